@@ -0,0 +1,477 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package uplink
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/zeebo/errs"
+	"golang.org/x/sync/errgroup"
+)
+
+// ArchiveFormat is the format of an archive of objects.
+type ArchiveFormat int
+
+const (
+	// ArchiveTar is a PAX tar archive. The custom metadata of an object is
+	// stored as PAX records of its entry.
+	ArchiveTar ArchiveFormat = iota
+	// ArchiveZip is a zip archive. The custom metadata of an object is
+	// stored as JSON in the comment of its entry.
+	ArchiveZip
+)
+
+const (
+	// defaultExportConcurrency is how many objects ExportArchive downloads
+	// at once by default.
+	defaultExportConcurrency = 4
+
+	// defaultExportPrefetchSize is the largest part of an object
+	// ExportArchive downloads ahead of writing it by default.
+	defaultExportPrefetchSize = 4 << 20
+
+	// paxCustomMetadataPrefix is the PAX record prefix of custom metadata
+	// entries in tar archives.
+	paxCustomMetadataPrefix = "STORX.custom."
+)
+
+// ExportArchiveOptions contains additional options for ExportArchive.
+type ExportArchiveOptions struct {
+	// Format is the format of the archive. It defaults to ArchiveTar.
+	Format ArchiveFormat
+
+	// Filter, if not nil, selects the objects to export. The objects
+	// include their system and custom metadata.
+	Filter func(object *Object) bool
+
+	// Concurrency is how many objects, or ranges of larger objects, are
+	// downloaded at once. When zero it is 4.
+	Concurrency int
+
+	// PrefetchSize is the largest part of an object that is downloaded
+	// into memory ahead of being written. Larger objects are downloaded in
+	// ranges of PrefetchSize, which count towards Concurrency like smaller
+	// objects. When zero it is 4 MiB.
+	PrefetchSize int64
+}
+
+// ExportArchiveReport is the result of ExportArchive.
+type ExportArchiveReport struct {
+	// Objects is the number of exported objects.
+	Objects int
+	// Bytes is the total size of the exported objects.
+	Bytes int64
+}
+
+// ExportArchive writes the objects in bucket whose keys start with prefix
+// to w as an archive.
+//
+// The entries are named by the object keys, relative to the prefix up to
+// its last slash, and are written in listing order. The modification time
+// of an entry is the creation time of its object. Objects are downloaded
+// several at once, large ones in several ranges, but written one after the
+// other.
+func (project *Project) ExportArchive(ctx context.Context, bucket, prefix string, w io.Writer, options *ExportArchiveOptions) (_ *ExportArchiveReport, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if bucket == "" {
+		return nil, errwrapf("%w (%q)", ErrBucketNameInvalid, bucket)
+	}
+
+	if options == nil {
+		options = &ExportArchiveOptions{}
+	}
+
+	var archive archiveWriter
+	switch options.Format {
+	case ArchiveTar:
+		archive = &tarArchiveWriter{w: tar.NewWriter(w)}
+	case ArchiveZip:
+		archive = &zipArchiveWriter{w: zip.NewWriter(w)}
+	default:
+		return nil, packageError.New("unknown archive format %d", options.Format)
+	}
+
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultExportConcurrency
+	}
+	prefetchSize := options.PrefetchSize
+	if prefetchSize <= 0 {
+		prefetchSize = defaultExportPrefetchSize
+	}
+
+	// listing needs a prefix that ends with a slash, so list everything
+	// under the last one and filter the rest.
+	listPrefix := ""
+	if i := strings.LastIndexByte(prefix, '/'); i >= 0 {
+		listPrefix = prefix[:i+1]
+	}
+
+	report := &ExportArchiveReport{}
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	queue := make(chan *exportEntry, concurrency)
+
+	// limiter bounds the parts that are downloaded or waiting to be written.
+	limiter := make(chan struct{}, concurrency)
+
+	group.Go(func() error {
+		defer close(queue)
+
+		var wg sync.WaitGroup
+		defer wg.Wait()
+
+		objects := project.ListObjects(groupCtx, bucket, &ListObjectsOptions{
+			Prefix:    listPrefix,
+			Recursive: true,
+			System:    true,
+			Custom:    true,
+		})
+		for objects.Next() {
+			object := objects.Item()
+			if !strings.HasPrefix(object.Key, prefix) || object.Key == listPrefix {
+				continue
+			}
+			if options.Filter != nil && !options.Filter(object) {
+				continue
+			}
+
+			entry := &exportEntry{
+				name:   strings.TrimPrefix(object.Key, listPrefix),
+				object: object,
+				ranged: object.System.ContentLength > prefetchSize,
+				// a part is only sent while holding the limiter, which the
+				// writer releases after receiving it, so this never blocks.
+				parts: make(chan *exportPart, concurrency),
+			}
+
+			select {
+			case queue <- entry:
+			case <-groupCtx.Done():
+				return groupCtx.Err()
+			}
+
+			for offset := int64(0); offset == 0 || offset < object.System.ContentLength; offset += prefetchSize {
+				length := int64(-1)
+				if entry.ranged {
+					length = object.System.ContentLength - offset
+					if length > prefetchSize {
+						length = prefetchSize
+					}
+				}
+
+				select {
+				case limiter <- struct{}{}:
+				case <-groupCtx.Done():
+					return groupCtx.Err()
+				}
+
+				part := &exportPart{done: make(chan struct{})}
+				entry.parts <- part
+
+				wg.Add(1)
+				go func(offset, length int64) {
+					defer wg.Done()
+					defer close(part.done)
+
+					part.object, part.data, part.err = project.downloadRange(groupCtx, bucket, object.Key, offset, length)
+				}(offset, length)
+			}
+			close(entry.parts)
+		}
+		return objects.Err()
+	})
+
+	group.Go(func() error {
+		for entry := range queue {
+			size, err := writeExportEntry(groupCtx, archive, entry, limiter)
+			if err != nil {
+				return err
+			}
+
+			report.Objects++
+			report.Bytes += size
+		}
+		return nil
+	})
+
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, packageError.Wrap(err)
+	}
+
+	mon.IntVal("archive_exported_objects").Observe(int64(report.Objects))
+
+	return report, nil
+}
+
+// exportEntry is an object queued to be written to an archive.
+type exportEntry struct {
+	name   string
+	object *Object
+
+	// ranged is whether the object is downloaded in several parts, instead
+	// of a single one.
+	ranged bool
+	parts  chan *exportPart
+}
+
+// exportPart is a part of an object that is downloaded into memory.
+type exportPart struct {
+	object *Object
+	data   []byte
+	err    error
+	done   chan struct{}
+}
+
+// writeExportEntry writes the entry to the archive as its parts are
+// downloaded, and returns its size.
+func writeExportEntry(ctx context.Context, archive archiveWriter, entry *exportEntry, limiter chan struct{}) (_ int64, err error) {
+	reader := &exportReader{ctx: ctx, entry: entry, limiter: limiter}
+	defer reader.release()
+
+	if err := reader.next(); err != nil {
+		return 0, err
+	}
+	object := reader.object
+	if entry.ranged && object.System.ContentLength != entry.object.System.ContentLength {
+		return 0, packageError.New("object %q changed during export", entry.object.Key)
+	}
+
+	if err := archive.Write(entry.name, object, reader); err != nil {
+		return 0, err
+	}
+	return object.System.ContentLength, nil
+}
+
+// exportReader reads the parts of an entry in order, releasing the limiter
+// of every part once it is read.
+type exportReader struct {
+	ctx     context.Context
+	entry   *exportEntry
+	limiter chan struct{}
+
+	// object is the object as the first part was downloaded.
+	object *Object
+	data   []byte
+	held   bool
+}
+
+func (reader *exportReader) Read(p []byte) (int, error) {
+	for len(reader.data) == 0 {
+		if err := reader.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, reader.data)
+	reader.data = reader.data[n:]
+	return n, nil
+}
+
+// next releases the current part and waits for the next one to be
+// downloaded. It returns io.EOF after the last part.
+func (reader *exportReader) next() error {
+	reader.release()
+
+	var part *exportPart
+	select {
+	case next, ok := <-reader.entry.parts:
+		if !ok {
+			return io.EOF
+		}
+		part = next
+	case <-reader.ctx.Done():
+		return reader.ctx.Err()
+	}
+	reader.held = true
+
+	select {
+	case <-part.done:
+	case <-reader.ctx.Done():
+		return reader.ctx.Err()
+	}
+	if part.err != nil {
+		return part.err
+	}
+
+	if reader.object == nil {
+		reader.object = part.object
+	} else if !part.object.System.Created.Equal(reader.object.System.Created) {
+		return packageError.New("object %q changed during export", reader.entry.object.Key)
+	}
+	reader.data = part.data
+	return nil
+}
+
+// release releases the limiter of the current part, if it holds it.
+func (reader *exportReader) release() {
+	if reader.held {
+		<-reader.limiter
+		reader.held = false
+	}
+	reader.data = nil
+}
+
+// downloadRange downloads length bytes of the object from offset into
+// memory, or everything from offset when length is negative.
+func (project *Project) downloadRange(ctx context.Context, bucket, key string, offset, length int64) (_ *Object, _ []byte, err error) {
+	download, err := project.DownloadObject(ctx, bucket, key, &DownloadOptions{
+		Offset: offset,
+		Length: length,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { err = errs.Combine(err, download.Close()) }()
+
+	object := download.Info()
+	if length < 0 {
+		length = object.System.ContentLength - offset
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(download, data); err != nil {
+		return nil, nil, packageError.Wrap(err)
+	}
+	return object, data, nil
+}
+
+// archiveWriter writes objects to an archive.
+type archiveWriter interface {
+	// Write writes object.System.ContentLength bytes of data as the entry
+	// with the name.
+	Write(name string, object *Object, data io.Reader) error
+	Close() error
+}
+
+// tarArchiveWriter writes a tar archive.
+type tarArchiveWriter struct {
+	w *tar.Writer
+}
+
+func (archive *tarArchiveWriter) Write(name string, object *Object, data io.Reader) error {
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     object.System.ContentLength,
+		Mode:     0o644,
+		ModTime:  object.System.Created,
+		Format:   tar.FormatPAX,
+	}
+	if strings.HasSuffix(name, "/") && header.Size == 0 {
+		header.Typeflag = tar.TypeDir
+		header.Mode = 0o755
+	}
+
+	if len(object.Custom) > 0 {
+		header.PAXRecords = make(map[string]string, len(object.Custom))
+		for key, value := range object.Custom {
+			header.PAXRecords[paxCustomMetadataPrefix+escapePAXKey(key)] = value
+		}
+	}
+
+	if err := archive.w.WriteHeader(header); err != nil {
+		return errwrapf("%w (%q)", err, name)
+	}
+	if _, err := io.CopyN(archive.w, data, header.Size); err != nil {
+		return errwrapf("%w (%q)", err, name)
+	}
+	return nil
+}
+
+func (archive *tarArchiveWriter) Close() error { return archive.w.Close() }
+
+// zipArchiveWriter writes a zip archive.
+type zipArchiveWriter struct {
+	w *zip.Writer
+}
+
+func (archive *zipArchiveWriter) Write(name string, object *Object, data io.Reader) error {
+	header := &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: object.System.Created,
+	}
+	if strings.HasSuffix(name, "/") && object.System.ContentLength == 0 {
+		header.Method = zip.Store
+	}
+
+	if len(object.Custom) > 0 {
+		comment, err := json.Marshal(object.Custom)
+		if err != nil {
+			return packageError.Wrap(err)
+		}
+		header.Comment = string(comment)
+	}
+
+	entry, err := archive.w.CreateHeader(header)
+	if err != nil {
+		return errwrapf("%w (%q)", err, name)
+	}
+	if _, err := io.CopyN(entry, data, object.System.ContentLength); err != nil {
+		return errwrapf("%w (%q)", err, name)
+	}
+	return nil
+}
+
+func (archive *zipArchiveWriter) Close() error { return archive.w.Close() }
+
+// escapePAXKey escapes the characters that are not allowed in PAX record
+// keys, and "%".
+func escapePAXKey(key string) string {
+	const hexDigits = "0123456789ABCDEF"
+
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		switch c := key[i]; c {
+		case '%', '=', 0:
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0xF])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// unescapePAXKey reverses escapePAXKey. Anything else, like a bare "%" in
+// the keys of archives written by other tools, is kept as it is.
+func unescapePAXKey(key string) string {
+	if !strings.Contains(key, "%") {
+		return key
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		if key[i] == '%' && i+3 <= len(key) {
+			switch key[i+1 : i+3] {
+			case "25":
+				b.WriteByte('%')
+				i += 2
+				continue
+			case "3D":
+				b.WriteByte('=')
+				i += 2
+				continue
+			case "00":
+				b.WriteByte(0)
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(key[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,442 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package uplink
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+
+	"github.com/zeebo/errs"
+)
+
+// ErrObjectExists is returned by ImportArchive for an entry whose object
+// already exists when the conflict policy is ImportConflictFail.
+var ErrObjectExists = errors.New("object already exists")
+
+// ImportConflict is what ImportArchive does with entries whose object
+// already exists.
+type ImportConflict int
+
+const (
+	// ImportConflictFail stops the import with ErrObjectExists.
+	ImportConflictFail ImportConflict = iota
+	// ImportConflictSkip keeps the existing object and skips the entry.
+	ImportConflictSkip
+	// ImportConflictOverwrite replaces the existing object.
+	ImportConflictOverwrite
+)
+
+const (
+	// defaultImportConcurrency is how many objects ImportArchive uploads at
+	// once by default.
+	defaultImportConcurrency = 4
+
+	// defaultImportBufferSize is the largest tar entry ImportArchive reads
+	// into memory to upload it concurrently by default.
+	defaultImportBufferSize = 4 << 20
+)
+
+// ImportArchiveOptions contains additional options for ImportArchive.
+type ImportArchiveOptions struct {
+	// Format is the format of the archive. It defaults to ArchiveTar.
+	Format ArchiveFormat
+
+	// Conflict is what to do with entries whose object already exists. It
+	// defaults to ImportConflictFail.
+	Conflict ImportConflict
+
+	// Concurrency is how many objects are uploaded at once. When zero it is
+	// 4.
+	Concurrency int
+
+	// BufferSize is the largest tar entry that is read into memory, so that
+	// it is uploaded while the following entries are read. Larger entries
+	// are uploaded one at a time, directly from the archive. When zero it is
+	// 4 MiB. Zip entries are never buffered.
+	BufferSize int64
+
+	// Upload is used to upload every object.
+	Upload *UploadOptions
+}
+
+// ImportedObject is an entry of the archive imported by ImportArchive.
+type ImportedObject struct {
+	Key  string
+	Size int64
+
+	// Skipped is whether the object already existed and was kept.
+	Skipped bool
+	// Err is why the object could not be uploaded.
+	Err error
+}
+
+// ImportArchiveReport is the result of ImportArchive.
+type ImportArchiveReport struct {
+	// Objects are the imported entries, in archive order.
+	Objects []ImportedObject
+
+	// Uploaded is the number of uploaded objects.
+	Uploaded int
+	// Skipped is the number of entries skipped because of conflicts.
+	Skipped int
+	// Failed is the number of entries that could not be uploaded.
+	Failed int
+	// Bytes is the total size of the uploaded objects.
+	Bytes int64
+}
+
+// ImportArchive uploads the regular files of the archive read from r as
+// objects in bucket, with their names appended to prefix.
+//
+// The custom metadata of the objects is restored from the archives written
+// by ExportArchive. Zip archives are read at random, so r must also be an
+// io.ReaderAt, and either have a Size method, like bytes.Reader, or be an
+// *os.File.
+//
+// Entries with the same name are imported one after the other in archive
+// order, so each one sees the object of the one before as existing.
+//
+// An error uploading a single object doesn't stop the others from being
+// uploaded and is reported for that object. The import stops with an error
+// for a conflict with ImportConflictFail, or failing to read the archive,
+// but the objects uploaded before are kept and included in the returned
+// report.
+func (project *Project) ImportArchive(ctx context.Context, bucket, prefix string, r io.Reader, options *ImportArchiveOptions) (_ *ImportArchiveReport, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if bucket == "" {
+		return nil, errwrapf("%w (%q)", ErrBucketNameInvalid, bucket)
+	}
+
+	if options == nil {
+		options = &ImportArchiveOptions{}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	importer := &archiveImporter{
+		project: project,
+		bucket:  bucket,
+		prefix:  prefix,
+		options: options,
+		cancel:  cancel,
+		pending: make(map[string]chan struct{}),
+	}
+
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultImportConcurrency
+	}
+	importer.limiter = make(chan struct{}, concurrency)
+
+	switch options.Format {
+	case ArchiveTar:
+		err = importer.readTar(ctx, tar.NewReader(r))
+	case ArchiveZip:
+		err = importer.readZip(ctx, r)
+	default:
+		return nil, packageError.New("unknown archive format %d", options.Format)
+	}
+	importer.wg.Wait()
+
+	report := &ImportArchiveReport{}
+	for _, object := range importer.objects {
+		report.Objects = append(report.Objects, *object)
+		switch {
+		case object.Err != nil:
+			report.Failed++
+		case object.Skipped:
+			report.Skipped++
+		default:
+			report.Uploaded++
+			report.Bytes += object.Size
+		}
+	}
+	mon.IntVal("archive_imported_objects").Observe(int64(report.Uploaded))
+
+	if importer.err != nil {
+		// the error that stopped the import, instead of the cancellation.
+		return report, importer.err
+	}
+	return report, err
+}
+
+// archiveImporter uploads the entries of an archive.
+type archiveImporter struct {
+	project *Project
+	bucket  string
+	prefix  string
+	options *ImportArchiveOptions
+	cancel  func()
+
+	limiter chan struct{}
+	wg      sync.WaitGroup
+
+	mu      sync.Mutex
+	objects []*ImportedObject
+	pending map[string]chan struct{} // the done channel of the last entry of every key
+	err     error
+}
+
+// readTar uploads the regular files of the tar archive.
+func (importer *archiveImporter) readTar(ctx context.Context, reader *tar.Reader) error {
+	bufferSize := importer.options.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultImportBufferSize
+	}
+
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return packageError.Wrap(err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		custom := CustomMetadata{}
+		for key, value := range header.PAXRecords {
+			if !strings.HasPrefix(key, paxCustomMetadataPrefix) {
+				continue
+			}
+			custom[unescapePAXKey(strings.TrimPrefix(key, paxCustomMetadataPrefix))] = value
+		}
+
+		entry, ok := importer.begin(ctx, header.Name, header.Size)
+		if !ok {
+			return ctx.Err()
+		}
+
+		if header.Size > bufferSize {
+			// upload it directly from the archive, before reading on.
+			importer.upload(ctx, entry, reader, custom)
+			<-importer.limiter
+			continue
+		}
+
+		data := make([]byte, header.Size)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			importer.finish(entry)
+			<-importer.limiter
+			return packageError.Wrap(err)
+		}
+
+		importer.wg.Add(1)
+		go func() {
+			defer importer.wg.Done()
+			defer func() { <-importer.limiter }()
+
+			importer.upload(ctx, entry, bytes.NewReader(data), custom)
+		}()
+	}
+}
+
+// readZip uploads the regular files of the zip archive.
+func (importer *archiveImporter) readZip(ctx context.Context, r io.Reader) error {
+	readerAt, ok := r.(io.ReaderAt)
+	if !ok {
+		return packageError.New("zip archives must be read from an io.ReaderAt")
+	}
+
+	var size int64
+	switch sized := r.(type) {
+	case interface{ Size() int64 }:
+		size = sized.Size()
+	case interface{ Stat() (fs.FileInfo, error) }:
+		info, err := sized.Stat()
+		if err != nil {
+			return packageError.Wrap(err)
+		}
+		size = info.Size()
+	default:
+		return packageError.New("the size of the zip archive is unknown")
+	}
+
+	reader, err := zip.NewReader(readerAt, size)
+	if err != nil {
+		return packageError.Wrap(err)
+	}
+
+	for _, file := range reader.File {
+		file := file
+		if !file.Mode().IsRegular() {
+			continue
+		}
+
+		custom := CustomMetadata{}
+		if file.Comment != "" {
+			// comments of zip archives that were not exported are ignored,
+			// including ones that only partly decode.
+			var decoded CustomMetadata
+			if err := json.Unmarshal([]byte(file.Comment), &decoded); err == nil && decoded != nil {
+				custom = decoded
+			}
+		}
+
+		entry, ok := importer.begin(ctx, file.Name, int64(file.UncompressedSize64))
+		if !ok {
+			return ctx.Err()
+		}
+
+		importer.wg.Add(1)
+		go func() {
+			defer importer.wg.Done()
+			defer func() { <-importer.limiter }()
+
+			data, err := file.Open()
+			if err != nil {
+				entry.object.Err = packageError.Wrap(err)
+				importer.finish(entry)
+				return
+			}
+			defer func() { _ = data.Close() }()
+
+			importer.upload(ctx, entry, data, custom)
+		}()
+	}
+	return nil
+}
+
+// importEntry is an entry of the archive that is being imported.
+type importEntry struct {
+	object *ImportedObject
+
+	// previous is closed once the previous entry with the same key is
+	// done, or nil when there is none.
+	previous chan struct{}
+	done     chan struct{}
+}
+
+// begin adds the entry to the report once an upload can start. It returns
+// false if the import was stopped. The entry must be finished, which
+// upload does.
+func (importer *archiveImporter) begin(ctx context.Context, name string, size int64) (*importEntry, bool) {
+	select {
+	case importer.limiter <- struct{}{}:
+	case <-ctx.Done():
+		return nil, false
+	}
+
+	entry := &importEntry{
+		object: &ImportedObject{
+			Key:  importer.prefix + strings.TrimPrefix(name, "./"),
+			Size: size,
+		},
+		done: make(chan struct{}),
+	}
+
+	importer.mu.Lock()
+	importer.objects = append(importer.objects, entry.object)
+	entry.previous = importer.pending[entry.object.Key]
+	importer.pending[entry.object.Key] = entry.done
+	importer.mu.Unlock()
+
+	return entry, true
+}
+
+// finish lets the next entry with the same key be uploaded.
+func (importer *archiveImporter) finish(entry *importEntry) {
+	importer.mu.Lock()
+	defer importer.mu.Unlock()
+
+	if importer.pending[entry.object.Key] == entry.done {
+		delete(importer.pending, entry.object.Key)
+	}
+	close(entry.done)
+}
+
+// upload uploads data as the object of the entry, following the conflict
+// policy. Entries with the same key are uploaded one after the other in
+// archive order, so the last one wins.
+func (importer *archiveImporter) upload(ctx context.Context, entry *importEntry, data io.Reader, custom CustomMetadata) {
+	defer importer.finish(entry)
+
+	object := entry.object
+	if err := custom.Verify(); err != nil {
+		object.Err = errwrapf("%w (%q)", err, object.Key)
+		return
+	}
+
+	if entry.previous != nil {
+		select {
+		case <-entry.previous:
+		case <-ctx.Done():
+			object.Err = ctx.Err()
+			return
+		}
+	}
+
+	if importer.options.Conflict != ImportConflictOverwrite {
+		_, err := importer.project.StatObject(ctx, importer.bucket, object.Key)
+		switch {
+		case err == nil && importer.options.Conflict == ImportConflictSkip:
+			object.Skipped = true
+			return
+		case err == nil:
+			object.Err = errwrapf("%w (%q)", ErrObjectExists, object.Key)
+			importer.stop(object.Err)
+			return
+		case !errors.Is(err, ErrObjectNotFound):
+			object.Err = err
+			return
+		}
+	}
+
+	var options UploadOptions
+	if importer.options.Upload != nil {
+		options = *importer.options.Upload
+	}
+	options.ExpectedSize = object.Size
+
+	object.Err = importer.project.uploadObject(ctx, importer.bucket, object.Key, data, custom, &options)
+}
+
+// stop stops the import with err.
+func (importer *archiveImporter) stop(err error) {
+	importer.mu.Lock()
+	defer importer.mu.Unlock()
+
+	if importer.err == nil {
+		importer.err = err
+		importer.cancel()
+	}
+}
+
+// uploadObject uploads data as the object with the custom metadata.
+func (project *Project) uploadObject(ctx context.Context, bucket, key string, data io.Reader, custom CustomMetadata, options *UploadOptions) (err error) {
+	upload, err := project.UploadObject(ctx, bucket, key, options)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errs.Combine(err, upload.Abort())
+		}
+	}()
+
+	if _, err := io.Copy(upload, data); err != nil {
+		return err
+	}
+
+	if len(custom) > 0 {
+		if err := upload.SetCustomMetadata(ctx, custom); err != nil {
+			return err
+		}
+	}
+
+	return upload.Commit()
+}
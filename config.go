@@ -17,6 +17,8 @@ import (
 
 const defaultDialTimeout = 10 * time.Second
 
+const defaultPieceUploadStarvationTimeout = 10 * time.Second
+
 // Config defines configuration for using uplink library.
 type Config struct {
 	// UserAgent defines a registered partner's Value Attribution Code, and is used by the satellite to associate
@@ -48,6 +50,69 @@ type Config struct {
 	// connections. This value is a hammer where we need a scalpel.
 	DialContext func(ctx context.Context, network, address string) (net.Conn, error)
 
+	// MaximumUploadBytesPerSecond limits the rate at which bytes are sent to
+	// storage nodes, summed across all uploads of a project opened with this
+	// config. The limit counts the bytes on the wire, which includes the
+	// erasure coding expansion, so it is usually a few times larger than the
+	// size of the uploaded data.
+	// No explicit value or 0 means there is no limit.
+	// It can be replaced for a single upload with UploadOptions.
+	MaximumUploadBytesPerSecond int64
+
+	// MaximumDownloadBytesPerSecond limits the rate at which bytes are received
+	// from storage nodes, summed across all downloads of a project opened with
+	// this config. The limit counts the bytes on the wire.
+	// No explicit value or 0 means there is no limit.
+	// It can be replaced for a single download with DownloadOptions.
+	MaximumDownloadBytesPerSecond int64
+
+	// DownloadHedgePolicy, if not nil, makes downloads start reading only
+	// some of the pieces of every segment, and read more of them when these
+	// are slow or fail. It can be replaced for a single download with
+	// DownloadOptions.
+	DownloadHedgePolicy *HedgePolicy
+
+	// MemoryLimit limits the number of bytes used to buffer segments while
+	// they are being uploaded or downloaded, summed across all transfers of a
+	// project opened with this config. When the limit is reached, writes to
+	// an upload and reads of a download block until enough of the other
+	// in-flight segments are done.
+	// No explicit value or 0 means there is no limit.
+	MemoryLimit int64
+
+	// BufferSpillThreshold is the number of bytes of a segment kept in memory
+	// while it is being uploaded. Past the threshold the segment is moved into
+	// a temporary file in BufferSpillDirectory.
+	// No explicit value or 0 means segments are always kept in memory.
+	// It can be overridden for a single upload with UploadOptions.
+	BufferSpillThreshold int64
+
+	// BufferSpillDirectory is the directory where segments past the
+	// BufferSpillThreshold are stored. If empty, the default directory for
+	// temporary files is used.
+	BufferSpillDirectory string
+
+	// MaximumConcurrentPieceUploads limits the number of pieces uploaded at
+	// once, summed across all uploads and part uploads of a project opened
+	// with this config. When they compete for it, uploads that are not
+	// UploadOptions.Background are served first, and uploads of the same
+	// kind get a fair share.
+	// No explicit value or 0 means there is no limit.
+	MaximumConcurrentPieceUploads int
+
+	// PieceUploadStarvationTimeout is how long a piece upload can wait for
+	// MaximumConcurrentPieceUploads before it is served ahead of all the
+	// others, so that background uploads keep making progress while
+	// interactive ones compete with them.
+	// No explicit value or 0 means default 10s will be used. Value lower than 0 means piece uploads are always served by kind.
+	PieceUploadStarvationTimeout time.Duration
+
+	// ComputePartETags makes every part upload use the MD5 of the data
+	// written to it as its ETag, unless PartUpload.SetETag is called, and
+	// makes CommitUpload store the S3-style ETag of the object, which is
+	// available as SystemMetadata.ETag.
+	ComputePartETags bool
+
 	// satellitePool is a connection pool dedicated for satellite connections.
 	// If not set, the normal pool / default will be used.
 	satellitePool *rpcpool.Pool
@@ -17,6 +17,8 @@ import (
 	"github.com/zeebo/errs"
 
 	"common/paths"
+	"uplink/private/ecclient"
+	"uplink/private/eestream"
 	"uplink/private/metaclient"
 	"uplink/private/storage/streams"
 	"uplink/private/stream"
@@ -29,6 +31,99 @@ type DownloadOptions struct {
 	Offset int64
 	// When Length is negative it will read until the end of the blob.
 	Length int64
+
+	// MaximumBytesPerSecond limits the rate at which this download receives
+	// bytes from storage nodes. When positive it replaces
+	// Config.MaximumDownloadBytesPerSecond for this download, so it can also
+	// be higher, and the download isn't counted against that limit.
+	MaximumBytesPerSecond int64
+
+	// VerifyErasureShares makes the download always read more than the
+	// minimum number of pieces and check them against each other. Corrupt
+	// pieces are corrected when enough pieces are available, otherwise the
+	// download fails. The corrupt pieces are reported by Download.Stats.
+	VerifyErasureShares bool
+
+	// Recovery makes the download continue past segments that cannot be
+	// read, instead of failing. Download.RecoveryManifest reports which
+	// ranges were recovered and which were lost.
+	Recovery RecoveryMode
+
+	// HedgePolicy, if not nil, replaces Config.DownloadHedgePolicy for this
+	// download.
+	HedgePolicy *HedgePolicy
+}
+
+// HedgePolicy controls hedged downloads. Only some of the pieces of a
+// segment are read at first, and more are started when the ones being read
+// are slower than usual to produce data or fail. Once enough pieces are
+// producing data, the slow ones are canceled.
+type HedgePolicy struct {
+	// Margin is the number of pieces beyond the required count that are
+	// read immediately.
+	Margin int
+
+	// Percentile of the observed time to first byte, across the downloads
+	// of the project, after which a piece that hasn't produced any data is
+	// considered slow. If zero, 0.9 is used.
+	Percentile float64
+
+	// MinimumDelay is the shortest time to wait before a piece is considered
+	// slow. It is also used until enough latencies are observed, falling back
+	// to 500ms if zero.
+	MinimumDelay time.Duration
+}
+
+// convertHedgePolicy converts the policy for downloads that observe the
+// latencies.
+func convertHedgePolicy(policy *HedgePolicy, latencies *eestream.LatencyTracker) *eestream.HedgePolicy {
+	return &eestream.HedgePolicy{
+		Margin:       policy.Margin,
+		Percentile:   policy.Percentile,
+		MinimumDelay: policy.MinimumDelay,
+		Latencies:    latencies,
+	}
+}
+
+// RecoveryMode controls what a download does with data that cannot be
+// recovered.
+type RecoveryMode int
+
+const (
+	// RecoveryDisabled fails the download when data cannot be recovered.
+	RecoveryDisabled RecoveryMode = iota
+	// RecoveryZeroFill replaces data that cannot be recovered with zeros,
+	// so that the download has the requested length.
+	RecoveryZeroFill
+	// RecoverySkip leaves data that cannot be recovered out of the download.
+	RecoverySkip
+)
+
+// RecoveryRange is a range of bytes of an object that was either recovered
+// or lost during a download in recovery mode.
+type RecoveryRange struct {
+	Offset    int64
+	Length    int64
+	Recovered bool
+	// Err is why the range was lost.
+	Err error
+}
+
+// DownloadStats contains statistics about a download.
+type DownloadStats struct {
+	// Bytes is the number of bytes read so far.
+	Bytes int64
+	// CorruptPieces are the pieces found to be corrupt so far, when
+	// DownloadOptions.VerifyErasureShares is set.
+	CorruptPieces []CorruptPiece
+}
+
+// CorruptPiece is a piece found to be corrupt while downloading.
+type CorruptPiece struct {
+	NodeID  string
+	PieceID string
+	// Stripes is the number of stripes in which the piece was corrupt.
+	Stripes int
 }
 
 // DownloadObject starts a download from the specific key.
@@ -54,6 +149,12 @@ func (project *Project) DownloadObject(ctx context.Context, bucket, key string,
 		return nil, errwrapf("%w (%q)", ErrObjectKeyInvalid, key)
 	}
 
+	var bytesPerSecond int64
+	if options != nil {
+		bytesPerSecond = options.MaximumBytesPerSecond
+	}
+	ctx = project.withDownloadLimiter(ctx, bytesPerSecond)
+
 	var opts metaclient.DownloadOptions
 	switch {
 	case options == nil:
@@ -114,6 +215,10 @@ func (project *Project) DownloadObject(ctx context.Context, bucket, key string,
 		return nil, convertKnownErrors(err, bucket, key)
 	}
 
+	if options != nil && options.Recovery != RecoveryDisabled {
+		download.recovery = new(streams.RecoveryManifest)
+	}
+
 	streams, err := project.getStreamsStore(ctx)
 	if err != nil {
 		return nil, convertKnownErrors(err, bucket, key)
@@ -121,6 +226,20 @@ func (project *Project) DownloadObject(ctx context.Context, bucket, key string,
 	download.streams = streams
 
 	download.object = convertObject(&objectDownload.Object)
+	if options != nil && options.VerifyErasureShares {
+		download.corrupt = new(ecclient.CorruptPieces)
+		streams.CorruptPieces = download.corrupt
+	}
+
+	if options != nil && options.HedgePolicy != nil {
+		streams.HedgePolicy = convertHedgePolicy(options.HedgePolicy, project.hedgeLatencies)
+	}
+
+	if download.recovery != nil {
+		streams.Recovery = convertRecoveryMode(options.Recovery)
+		streams.RecoveryManifest = download.recovery
+	}
+
 	download.download = stream.NewDownloadRange(ctx, objectDownload, streams, streamRange.Start, streamRange.Limit-streamRange.Start)
 	return download, nil
 }
@@ -132,6 +251,8 @@ type Download struct {
 	object   *Object
 	bucket   string
 	streams  *streams.Store
+	corrupt  *ecclient.CorruptPieces
+	recovery *streams.RecoveryManifest
 
 	sizes struct {
 		offset, length, total int64
@@ -164,6 +285,100 @@ func (download *Download) Read(p []byte) (n int, err error) {
 	return n, convertKnownErrors(err, download.bucket, download.object.Key)
 }
 
+// WriteTo downloads the rest of the object's data stream and writes it to w.
+// It is used by io.Copy, and reads the decoded data with a larger buffer
+// than io.Copy's, so that there are fewer calls into the decoding pipeline.
+func (download *Download) WriteTo(w io.Writer) (n int64, err error) {
+	track := download.stats.trackWorking()
+	dw := &downloadWriter{download: download, w: w}
+	n, err = download.download.WriteTo(dw)
+	download.mu.Lock()
+	if err != nil && dw.err == nil {
+		download.stats.flagFailure(err)
+	}
+	track()
+	download.mu.Unlock()
+	if dw.err != nil {
+		return n, err
+	}
+	return n, convertKnownErrors(err, download.bucket, download.object.Key)
+}
+
+// downloadWriter updates the download stats for the data written to w and
+// remembers whether w failed.
+type downloadWriter struct {
+	download *Download
+	w        io.Writer
+	err      error
+}
+
+func (dw *downloadWriter) Write(p []byte) (n int, err error) {
+	dw.download.mu.Lock()
+	if dw.download.ttfb == 0 && len(p) > 0 {
+		dw.download.ttfb = time.Since(dw.download.stats.start)
+	}
+	dw.download.mu.Unlock()
+
+	n, err = dw.w.Write(p)
+	dw.err = err
+
+	dw.download.mu.Lock()
+	dw.download.stats.bytes += int64(n)
+	dw.download.mu.Unlock()
+	return n, err
+}
+
+// Stats returns statistics about the download so far.
+func (download *Download) Stats() DownloadStats {
+	download.mu.Lock()
+	stats := DownloadStats{
+		Bytes: download.stats.bytes,
+	}
+	download.mu.Unlock()
+
+	if download.corrupt != nil {
+		for _, piece := range download.corrupt.Pieces() {
+			stats.CorruptPieces = append(stats.CorruptPieces, CorruptPiece{
+				NodeID:  piece.NodeID.String(),
+				PieceID: piece.PieceID.String(),
+				Stripes: piece.Stripes,
+			})
+		}
+	}
+	return stats
+}
+
+// RecoveryManifest returns, in order, the ranges of the object read so far
+// that were recovered and that were lost. It is empty unless
+// DownloadOptions.Recovery is set.
+func (download *Download) RecoveryManifest() []RecoveryRange {
+	if download.recovery == nil {
+		return nil
+	}
+
+	var ranges []RecoveryRange
+	for _, r := range download.recovery.Ranges() {
+		ranges = append(ranges, RecoveryRange{
+			Offset:    r.Offset,
+			Length:    r.Length,
+			Recovered: r.Recovered,
+			Err:       r.Err,
+		})
+	}
+	return ranges
+}
+
+func convertRecoveryMode(mode RecoveryMode) streams.RecoveryMode {
+	switch mode {
+	case RecoveryZeroFill:
+		return streams.RecoveryZeroFill
+	case RecoverySkip:
+		return streams.RecoverySkip
+	default:
+		return streams.RecoveryDisabled
+	}
+}
+
 // Close closes the reader of the download.
 func (download *Download) Close() error {
 	track := download.stats.trackWorking()
@@ -6,6 +6,7 @@ package edge
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/zeebo/errs"
 
@@ -37,16 +38,33 @@ type Credentials struct {
 }
 
 // RegisterAccessOptions contains optional parameters for RegisterAccess.
+//
+// Labels, allowed origins (CORS), and listing, inspecting or deleting
+// registered credentials are not supported yet. The EdgeAuth service only
+// has RegisterAccess, and its request only carries the access grant and the
+// public flag, so they are deferred until the auth service protocol has
+// them. Until then, limit the lifetime of credentials with Expires, and
+// invalidate them early by revoking their access grant with
+// uplink.Project.RevokeAccess.
 type RegisterAccessOptions struct {
+	// TODO: add Label and AllowedOrigins, and ListAccess, GetAccess and
+	// DeleteAccess to Config, when the EdgeAuth service supports them.
+
 	// Whether objects can be read without authentication.
 	Public bool
+
+	// Expires, if set, is when the credentials stop working. It restricts
+	// the registered access grant with Permission.NotAfter, so it is also
+	// enforced by the satellite.
+	Expires time.Time
 }
 
 // RegisterAccess gets credentials for the Storx-hosted Gateway and linkshare service.
 // All files accessible under the Access are then also accessible via those services.
 // If you call this function a lot, and the use case allows it,
 // please limit the lifetime of the credentials
-// by setting Permission.NotAfter when creating the Access.
+// by setting Permission.NotAfter when creating the Access, or with
+// RegisterAccessOptions.Expires.
 func (config *Config) RegisterAccess(
 	ctx context.Context,
 	access *uplink.Access,
@@ -60,6 +78,21 @@ func (config *Config) RegisterAccess(
 		options = &RegisterAccessOptions{}
 	}
 
+	if !options.Expires.IsZero() {
+		if !options.Expires.After(time.Now()) {
+			return nil, uplinkError.New("Expires is in the past: %v", options.Expires)
+		}
+
+		permission := uplink.FullPermission()
+		permission.NotAfter = options.Expires
+
+		var err error
+		access, err = access.Share(permission)
+		if err != nil {
+			return nil, uplinkError.Wrap(err)
+		}
+	}
+
 	var conn *rpc.Conn
 	var err error
 	if config.InsecureUnencryptedConnection || config.InsecureSkipVerify {
@@ -0,0 +1,282 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+// Package edgetest implements an in-process auth service for testing code
+// that uses edge.Config.RegisterAccess.
+package edgetest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base32"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zeebo/errs"
+
+	"common/pb"
+	"common/rpc/rpcstatus"
+	"drpc/drpcmux"
+	"drpc/drpcserver"
+	"uplink"
+	"uplink/edge"
+)
+
+// Error is the error class of the auth service.
+var Error = errs.Class("edgetest")
+
+// ErrRegisterFailed can be used as Options.FailRegister, or with
+// SetRegisterError, to fail registrations.
+var ErrRegisterFailed = errors.New("injected register failure")
+
+// Options contains optional parameters for NewServer.
+type Options struct {
+	// TLS serves with a self-signed certificate for "localhost", instead of
+	// unencrypted.
+	TLS bool
+
+	// Endpoint is the gateway endpoint of the issued credentials. It defaults
+	// to "https://gateway.example".
+	Endpoint string
+
+	// PublicOnly fails the registration of access grants that are not
+	// public, like an auth service that only serves linksharing.
+	PublicOnly bool
+
+	// FailDial gives the server an address that refuses every connection.
+	FailDial bool
+
+	// FailRegister fails every registration with it as error.
+	FailRegister error
+}
+
+// Registration is an access grant registered with the server.
+type Registration struct {
+	AccessGrant string
+	Public      bool
+	Credentials edge.Credentials
+}
+
+// Server is an in-process auth service.
+//
+// The credentials it issues are derived from the access grant and the
+// public flag, so registering the same access grant again returns the same
+// credentials.
+type Server struct {
+	options     Options
+	listener    net.Listener
+	addr        string
+	certificate []byte
+
+	mu            sync.Mutex
+	registerErr   error
+	registrations map[string]Registration
+}
+
+// NewServer creates an auth service listening on a local port. The
+// connections are served by Run, which also closes the listener.
+func NewServer(options Options) (*Server, error) {
+	if options.Endpoint == "" {
+		options.Endpoint = "https://gateway.example"
+	}
+
+	server := &Server{
+		options:       options,
+		registerErr:   options.FailRegister,
+		registrations: make(map[string]Registration),
+	}
+
+	if options.FailDial {
+		// nothing can listen on port 0, so connecting to it is always
+		// refused, unlike a freed port that another test may bind.
+		server.addr = "localhost:0"
+		return server, nil
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+	server.addr = "localhost:" + strings.TrimPrefix(listener.Addr().String(), "127.0.0.1:")
+
+	if options.TLS {
+		certificatePEM, privateKeyPEM, err := createCertificate("localhost")
+		if err != nil {
+			return nil, errs.Combine(err, listener.Close())
+		}
+
+		certificate, err := tls.X509KeyPair(certificatePEM, privateKeyPEM)
+		if err != nil {
+			return nil, errs.Combine(Error.Wrap(err), listener.Close())
+		}
+
+		server.certificate = certificatePEM
+		listener = tls.NewListener(listener, &tls.Config{
+			Certificates: []tls.Certificate{certificate},
+		})
+	}
+
+	server.listener = listener
+	return server, nil
+}
+
+// Addr returns the address of the server.
+func (server *Server) Addr() string { return server.addr }
+
+// CertificatePEM returns the certificate of the server, or nil when it is
+// unencrypted.
+func (server *Server) CertificatePEM() []byte { return server.certificate }
+
+// Config returns an edge.Config that connects to the server.
+func (server *Server) Config() edge.Config {
+	return edge.Config{
+		AuthServiceAddress:            server.addr,
+		CertificatePEM:                server.certificate,
+		InsecureUnencryptedConnection: !server.options.TLS,
+	}
+}
+
+// Run serves connections until ctx is canceled.
+func (server *Server) Run(ctx context.Context) error {
+	if server.listener == nil {
+		<-ctx.Done()
+		return nil
+	}
+
+	mux := drpcmux.New()
+	if err := pb.DRPCRegisterEdgeAuth(mux, &authEndpoint{server: server}); err != nil {
+		return Error.Wrap(err)
+	}
+
+	err := drpcserver.New(mux).Serve(ctx, server.listener)
+	if errs.Is(err, context.Canceled) {
+		return nil
+	}
+	return Error.Wrap(err)
+}
+
+// SetRegisterError fails the following registrations with err, or lets
+// them succeed when it is nil.
+func (server *Server) SetRegisterError(err error) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.registerErr = err
+}
+
+// Registrations returns the access grants registered with the server.
+func (server *Server) Registrations() []Registration {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	registrations := make([]Registration, 0, len(server.registrations))
+	for _, registration := range server.registrations {
+		registrations = append(registrations, registration)
+	}
+	return registrations
+}
+
+func (server *Server) register(request *pb.EdgeRegisterAccessRequest) (*pb.EdgeRegisterAccessResponse, error) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	if server.registerErr != nil {
+		return nil, rpcstatus.Error(rpcstatus.Internal, server.registerErr.Error())
+	}
+
+	if _, err := uplink.ParseAccess(request.AccessGrant); err != nil {
+		return nil, rpcstatus.Error(rpcstatus.InvalidArgument, err.Error())
+	}
+
+	if server.options.PublicOnly && !request.Public {
+		return nil, rpcstatus.Error(rpcstatus.PermissionDenied, "only public access grants can be registered")
+	}
+
+	credentials := edge.Credentials{
+		AccessKeyID: deriveKey("access key", request, 17),
+		SecretKey:   deriveKey("secret key", request, 32),
+		Endpoint:    server.options.Endpoint,
+	}
+
+	server.registrations[credentials.AccessKeyID] = Registration{
+		AccessGrant: request.AccessGrant,
+		Public:      request.Public,
+		Credentials: credentials,
+	}
+
+	return &pb.EdgeRegisterAccessResponse{
+		AccessKeyId: credentials.AccessKeyID,
+		SecretKey:   credentials.SecretKey,
+		Endpoint:    credentials.Endpoint,
+	}, nil
+}
+
+// authEndpoint implements the EdgeAuth DRPC service.
+type authEndpoint struct {
+	pb.DRPCEdgeAuthServer
+	server *Server
+}
+
+func (endpoint *authEndpoint) RegisterAccess(ctx context.Context, request *pb.EdgeRegisterAccessRequest) (*pb.EdgeRegisterAccessResponse, error) {
+	return endpoint.server.register(request)
+}
+
+// deriveKey derives size bytes from the registration, encoded as lowercase
+// base32 like the keys of the real auth service.
+func deriveKey(kind string, request *pb.EdgeRegisterAccessRequest, size int) string {
+	hash := sha256.New()
+	_, _ = hash.Write([]byte(kind))
+	if request.Public {
+		_, _ = hash.Write([]byte{1})
+	} else {
+		_, _ = hash.Write([]byte{0})
+	}
+	_, _ = hash.Write([]byte(request.AccessGrant))
+
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	return strings.ToLower(encoding.EncodeToString(hash.Sum(nil)[:size]))
+}
+
+// createCertificate creates a self-signed certificate for hostname.
+func createCertificate(hostname string) (certificatePEM, privateKeyPEM []byte, err error) {
+	template := x509.Certificate{
+		Subject: pkix.Name{
+			CommonName: hostname,
+		},
+		DNSNames:     []string{hostname},
+		SerialNumber: big.NewInt(1),
+		IsCA:         true,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, Error.Wrap(err)
+	}
+
+	certificateDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		return nil, nil, Error.Wrap(err)
+	}
+
+	privateKeyDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, Error.Wrap(err)
+	}
+
+	certificatePEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificateDER})
+	privateKeyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyDER})
+	return certificatePEM, privateKeyPEM, nil
+}
@@ -0,0 +1,120 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package edgetest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"common/testcontext"
+	"uplink"
+	"uplink/edge"
+	"uplink/edge/edgetest"
+)
+
+const minimalAccess = "13J4Upun87ATb3T5T5sDXVeQaCzWFZeF9Ly4ELfxS5hUwTL8APEkwahTEJ1wxZjyErimiDs3kgid33kDLuYPYtwaY7Toy32mCTapfrUB814X13RiA844HPWK3QLKZb9cAoVceTowmNZXWbcUMKNbkMHCURE4hn8ZrdHPE3S86yngjvDxwKmarfGx"
+
+func startServer(ctx *testcontext.Context, t *testing.T, options edgetest.Options) *edgetest.Server {
+	server, err := edgetest.NewServer(options)
+	require.NoError(t, err)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+	ctx.Go(func() error {
+		return server.Run(runCtx)
+	})
+
+	return server
+}
+
+func TestServer(t *testing.T) {
+	for _, useTLS := range []bool{false, true} {
+		useTLS := useTLS
+		name := "Unencrypted"
+		if useTLS {
+			name = "TLS"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			ctx := testcontext.NewWithTimeout(t, 30*time.Second)
+			defer ctx.Cleanup()
+
+			access, err := uplink.ParseAccess(minimalAccess)
+			require.NoError(t, err)
+
+			server := startServer(ctx, t, edgetest.Options{TLS: useTLS})
+			config := server.Config()
+			require.Equal(t, useTLS, len(server.CertificatePEM()) > 0)
+
+			private, err := config.RegisterAccess(ctx, access, nil)
+			require.NoError(t, err)
+			require.Len(t, private.AccessKeyID, 28)
+			require.Equal(t, "https://gateway.example", private.Endpoint)
+
+			again, err := config.RegisterAccess(ctx, access, nil)
+			require.NoError(t, err)
+			require.Equal(t, private, again)
+
+			public, err := config.RegisterAccess(ctx, access, &edge.RegisterAccessOptions{Public: true})
+			require.NoError(t, err)
+			require.NotEqual(t, private.AccessKeyID, public.AccessKeyID)
+
+			registrations := server.Registrations()
+			require.Len(t, registrations, 2)
+			for _, registration := range registrations {
+				require.Equal(t, minimalAccess, registration.AccessGrant)
+				require.Equal(t, registration.Public, registration.Credentials == *public)
+			}
+
+			server.SetRegisterError(edgetest.ErrRegisterFailed)
+			_, err = config.RegisterAccess(ctx, access, nil)
+			require.True(t, errors.Is(err, edge.ErrRegisterAccessFailed))
+
+			server.SetRegisterError(nil)
+			_, err = config.RegisterAccess(ctx, access, nil)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestServer_PublicOnly(t *testing.T) {
+	ctx := testcontext.NewWithTimeout(t, 30*time.Second)
+	defer ctx.Cleanup()
+
+	access, err := uplink.ParseAccess(minimalAccess)
+	require.NoError(t, err)
+
+	server := startServer(ctx, t, edgetest.Options{PublicOnly: true})
+	config := server.Config()
+
+	_, err = config.RegisterAccess(ctx, access, nil)
+	require.True(t, errors.Is(err, edge.ErrRegisterAccessFailed))
+
+	_, err = config.RegisterAccess(ctx, access, &edge.RegisterAccessOptions{Public: true})
+	require.NoError(t, err)
+}
+
+func TestServer_Failures(t *testing.T) {
+	ctx := testcontext.NewWithTimeout(t, 30*time.Second)
+	defer ctx.Cleanup()
+
+	access, err := uplink.ParseAccess(minimalAccess)
+	require.NoError(t, err)
+
+	for _, useTLS := range []bool{false, true} {
+		server := startServer(ctx, t, edgetest.Options{TLS: useTLS, FailDial: true})
+		config := server.Config()
+		_, err = config.RegisterAccess(ctx, access, nil)
+		require.True(t, errors.Is(err, edge.ErrAuthDialFailed), err)
+
+		server = startServer(ctx, t, edgetest.Options{TLS: useTLS, FailRegister: edgetest.ErrRegisterFailed})
+		config = server.Config()
+		_, err = config.RegisterAccess(ctx, access, nil)
+		require.True(t, errors.Is(err, edge.ErrRegisterAccessFailed), err)
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package edge
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	presignAlgorithm = "AWS4-HMAC-SHA256"
+	presignService   = "s3"
+
+	// presignMaxExpires is the longest expiration allowed by SigV4.
+	presignMaxExpires = 7 * 24 * time.Hour
+)
+
+// PresignOptions contains optional parameters for PresignURL.
+type PresignOptions struct {
+	// Region is the region the signature is scoped to. The gateway accepts
+	// any region; it defaults to "us-east-1".
+	Region string
+
+	// VirtualHostedStyle puts the bucket into the host name, such as
+	// https://mybucket.gateway.example.com/mykey, instead of into the path.
+	VirtualHostedStyle bool
+
+	// ContentType, if set, must be sent as the Content-Type header of the
+	// request. Useful to constrain the type of presigned uploads.
+	ContentType string
+
+	// Headers are additional headers that are signed and must be sent with
+	// the request, such as "x-amz-meta-*" headers of presigned uploads.
+	Headers map[string]string
+
+	// Query are additional query parameters to sign, such as
+	// "response-content-disposition".
+	Query url.Values
+
+	// Now is the signing time. When zero, the current time is used.
+	Now time.Time
+}
+
+// PresignURL creates an URL that performs the S3 request on the gateway with
+// the credentials, without any further authentication, until it expires.
+//
+// The URL is signed offline with AWS Signature Version 4 query parameters.
+// The method is the HTTP method of the request, e.g. GET or PUT. The key is
+// optional, leave it blank to sign a request on the bucket. The expires
+// duration must be between one second and seven days.
+func PresignURL(credentials *Credentials, method, bucket, key string, expires time.Duration, options *PresignOptions) (string, error) {
+	if credentials == nil || credentials.AccessKeyID == "" || credentials.SecretKey == "" {
+		return "", uplinkError.New("credentials are required")
+	}
+
+	if method == "" {
+		return "", uplinkError.New("method is required")
+	}
+
+	if bucket == "" {
+		return "", uplinkError.New("bucket is required")
+	}
+
+	if expires < time.Second || expires > presignMaxExpires {
+		return "", uplinkError.New("expires must be between 1s and %v: %v", presignMaxExpires, expires)
+	}
+
+	if options == nil {
+		options = &PresignOptions{}
+	}
+
+	endpoint, err := url.ParseRequestURI(credentials.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return "", uplinkError.New("invalid endpoint: %q", credentials.Endpoint)
+	}
+
+	region := options.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	now := options.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	now = now.UTC()
+	date := now.Format("20060102")
+	scope := date + "/" + region + "/" + presignService + "/aws4_request"
+
+	result := &url.URL{
+		Scheme: endpoint.Scheme,
+		Host:   endpoint.Host,
+		Path:   strings.TrimSuffix(endpoint.Path, "/"),
+	}
+	if options.VirtualHostedStyle {
+		result.Host = bucket + "." + endpoint.Host
+	} else {
+		result.Path += "/" + bucket
+	}
+	result.Path += "/" + key
+
+	headers := map[string]string{
+		"host": result.Host,
+	}
+	if options.ContentType != "" {
+		headers["content-type"] = options.ContentType
+	}
+	for name, value := range options.Headers {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || name == "host" {
+			return "", uplinkError.New("invalid signed header: %q", name)
+		}
+		headers[name] = value
+	}
+
+	headerNames := make([]string, 0, len(headers))
+	for name := range headers {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range headerNames {
+		canonicalHeaders.WriteString(name + ":" + strings.Join(strings.Fields(headers[name]), " ") + "\n")
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	query := url.Values{}
+	for name, values := range options.Query {
+		query[name] = append([]string(nil), values...)
+	}
+	query.Set("X-Amz-Algorithm", presignAlgorithm)
+	query.Set("X-Amz-Credential", credentials.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+	query.Set("X-Amz-SignedHeaders", signedHeaders)
+
+	canonicalPath := presignEscape(result.Path, false)
+	canonicalQuery := presignCanonicalQuery(query)
+
+	canonicalRequest := strings.Join([]string{
+		strings.ToUpper(method),
+		canonicalPath,
+		canonicalQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		presignAlgorithm,
+		now.Format("20060102T150405Z"),
+		scope,
+		presignHash(canonicalRequest),
+	}, "\n")
+
+	signingKey := []byte("AWS4" + credentials.SecretKey)
+	for _, part := range []string{date, region, presignService, "aws4_request"} {
+		signingKey = presignHMAC(signingKey, part)
+	}
+	signature := hex.EncodeToString(presignHMAC(signingKey, stringToSign))
+
+	result.RawPath = canonicalPath
+	result.RawQuery = canonicalQuery + "&X-Amz-Signature=" + signature
+
+	return result.String(), nil
+}
+
+// presignCanonicalQuery encodes the query sorted by name and value, as
+// required by SigV4.
+func presignCanonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pairs []string
+	for _, name := range names {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, presignEscape(name, true)+"="+presignEscape(value, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// presignEscape percent-encodes every byte of s except the unreserved
+// characters of RFC 3986 and, unless encodeSlash, "/".
+func presignEscape(s string, encodeSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~',
+			c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0xF])
+		}
+	}
+	return b.String()
+}
+
+func presignHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func presignHMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -72,3 +72,71 @@ func JoinShareURL(baseURL string, accessKeyID string, bucket string, key string,
 
 	return result.String(), nil
 }
+
+// ShareURL contains the parts of a linksharing URL.
+type ShareURL struct {
+	// BaseURL is the url of the linksharing service, e.g. https://link.storxshare.io.
+	BaseURL string
+	// AccessKeyID of the credentials the URL uses.
+	AccessKeyID string
+	// Bucket is empty if the URL shares the entire project.
+	Bucket string
+	// Key is the object key or prefix, it is empty if the URL shares the entire bucket.
+	Key string
+	// Raw is set for links directly to the data instead of to a landing page.
+	Raw bool
+}
+
+// ParseShareURL splits a linksharing URL, as created by JoinShareURL, into its parts.
+//
+// The path of the URL is split at its first "/s/" or "/raw/" segment. Anything before
+// it is part of the base URL.
+func ParseShareURL(shareURL string) (*ShareURL, error) {
+	parsed, err := url.ParseRequestURI(shareURL)
+	if err != nil || parsed.Host == "" {
+		return nil, uplinkError.New("invalid share url: %q", shareURL)
+	}
+
+	result := &ShareURL{}
+
+	var rest string
+	segments := strings.SplitAfter(parsed.Path, "/")
+	for i, segment := range segments {
+		if segment == "s/" || segment == "raw/" {
+			result.Raw = segment == "raw/"
+			parsed.Path = strings.TrimSuffix(strings.Join(segments[:i], ""), "/")
+			rest = strings.Join(segments[i+1:], "")
+			break
+		}
+	}
+
+	if rest == "" {
+		return nil, uplinkError.New("share url has no access key ID: %q", shareURL)
+	}
+
+	parts := strings.SplitN(rest, "/", 3)
+	result.AccessKeyID = parts[0]
+	if len(parts) > 1 {
+		result.Bucket = parts[1]
+	}
+	if len(parts) > 2 {
+		result.Key = parts[2]
+	}
+
+	if result.AccessKeyID == "" {
+		return nil, uplinkError.New("share url has no access key ID: %q", shareURL)
+	}
+
+	if result.Bucket == "" && result.Key != "" {
+		return nil, uplinkError.New("share url has a key but no bucket: %q", shareURL)
+	}
+
+	if result.Raw && (result.Key == "" || strings.HasSuffix(result.Key, "/")) {
+		return nil, uplinkError.New("raw share url must link to an object: %q", shareURL)
+	}
+
+	parsed.RawPath = ""
+	result.BaseURL = parsed.String()
+
+	return result, nil
+}
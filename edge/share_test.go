@@ -4,6 +4,8 @@
 package edge_test
 
 import (
+	"math/rand"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -165,3 +167,95 @@ func TestCreateURL(t *testing.T) {
 		"uplink: a raw download link can not be a prefix",
 	)
 }
+
+func TestParseShareURL(t *testing.T) {
+	for _, tc := range []struct {
+		url      string
+		expected edge.ShareURL
+	}{
+		{
+			url:      "https://linksharing.test/s/aaaa",
+			expected: edge.ShareURL{BaseURL: "https://linksharing.test", AccessKeyID: "aaaa"},
+		},
+		{
+			url:      "https://linksharing.test/s/aaaa/mybucket",
+			expected: edge.ShareURL{BaseURL: "https://linksharing.test", AccessKeyID: "aaaa", Bucket: "mybucket"},
+		},
+		{
+			url:      "https://linksharing.test/s/aaaa/mybucket/my/prefix/",
+			expected: edge.ShareURL{BaseURL: "https://linksharing.test", AccessKeyID: "aaaa", Bucket: "mybucket", Key: "my/prefix/"},
+		},
+		{
+			url:      "https://linksharing.test/raw/aaaa/mybucket/a%00a%20b%3F",
+			expected: edge.ShareURL{BaseURL: "https://linksharing.test", AccessKeyID: "aaaa", Bucket: "mybucket", Key: "a\x00a b?", Raw: true},
+		},
+		{
+			url:      "http://localhost:8080/linksharing/s/aaaa/mybucket/s/myobject",
+			expected: edge.ShareURL{BaseURL: "http://localhost:8080/linksharing", AccessKeyID: "aaaa", Bucket: "mybucket", Key: "s/myobject"},
+		},
+	} {
+		parsed, err := edge.ParseShareURL(tc.url)
+		require.NoError(t, err, tc.url)
+		require.Equal(t, tc.expected, *parsed, tc.url)
+	}
+
+	for _, invalid := range []string{
+		"",
+		"linksharing.test/s/aaaa",
+		"https://linksharing.test",
+		"https://linksharing.test/aaaa/mybucket",
+		"https://linksharing.test/s/",
+		"https://linksharing.test/s//mybucket",
+		"https://linksharing.test/s/aaaa//myobject",
+		"https://linksharing.test/raw/aaaa/mybucket",
+		"https://linksharing.test/raw/aaaa/mybucket/myprefix/",
+	} {
+		_, err := edge.ParseShareURL(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestParseShareURL_RoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	const alphabet = "abc/ %?#&+=:;@\x00\xff/é"
+	randomString := func(maxLength int) string {
+		runes := []rune(alphabet)
+		var b strings.Builder
+		for i := rng.Intn(maxLength + 1); i > 0; i-- {
+			b.WriteRune(runes[rng.Intn(len(runes))])
+		}
+		return b.String()
+	}
+
+	baseURLs := []string{
+		"https://linksharing.test",
+		"https://linksharing.test/",
+		"http://localhost:8080/some/path/",
+		"https://linksharing.test/a%20b",
+	}
+
+	for i := 0; i < 10000; i++ {
+		baseURL := baseURLs[rng.Intn(len(baseURLs))]
+		accessKeyID := "l5pucy3dmvzxgs3fpfewix27l5pq"
+		bucket := strings.ReplaceAll(randomString(8), "/", "")
+		key := randomString(16)
+		raw := rng.Intn(2) == 0
+
+		shareURL, err := edge.JoinShareURL(baseURL, accessKeyID, bucket, key, &edge.ShareURLOptions{Raw: raw})
+		if err != nil {
+			continue
+		}
+
+		parsed, err := edge.ParseShareURL(shareURL)
+		require.NoError(t, err, shareURL)
+		require.Equal(t, accessKeyID, parsed.AccessKeyID, shareURL)
+		require.Equal(t, bucket, parsed.Bucket, shareURL)
+		require.Equal(t, key, parsed.Key, shareURL)
+		require.Equal(t, raw, parsed.Raw, shareURL)
+
+		joined, err := edge.JoinShareURL(parsed.BaseURL, parsed.AccessKeyID, parsed.Bucket, parsed.Key, &edge.ShareURLOptions{Raw: parsed.Raw})
+		require.NoError(t, err, shareURL)
+		require.Equal(t, shareURL, joined)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package edge
+
+import (
+	"context"
+	"time"
+
+	"uplink"
+)
+
+// ShareLinkOptions contains options for CreateShareLink.
+type ShareLinkOptions struct {
+	// BaseURL is the url of the linksharing service, e.g. https://link.storxshare.io.
+	BaseURL string
+
+	// If set it creates a link directly to the object instead of an to intermediate landing page.
+	// It requires the key of an object, not a prefix.
+	Raw bool
+
+	// NotBefore and NotAfter restrict when the link works. See uplink.Permission.
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+// ShareLink is a public link created by CreateShareLink.
+type ShareLink struct {
+	// URL is the linksharing URL.
+	URL string
+	// Credentials are the public gateway credentials the URL uses.
+	Credentials *Credentials
+	// Access is the read-only access grant the credentials were registered
+	// with. It is needed to revoke the link.
+	Access *uplink.Access
+}
+
+// CreateShareLink creates a public linksharing URL for an object, a prefix, a
+// bucket or the entire project.
+//
+// It restricts the access to read-only permission on bucket and keyOrPrefix,
+// registers the result with public visibility, and joins the URL. The bucket
+// is optional, leave it blank to share the entire project. The keyOrPrefix is
+// also optional, if empty shares the entire bucket. A prefix must end with a
+// "/".
+//
+// The link can be revoked with RevokeShareLink.
+func (config *Config) CreateShareLink(ctx context.Context, access *uplink.Access, bucket, keyOrPrefix string, options *ShareLinkOptions) (*ShareLink, error) {
+	if options == nil || options.BaseURL == "" {
+		return nil, uplinkError.New("BaseURL is missing")
+	}
+
+	if bucket == "" && keyOrPrefix != "" {
+		return nil, uplinkError.New("bucket is required if key is specified")
+	}
+
+	if !options.NotAfter.IsZero() && !options.NotAfter.After(time.Now()) {
+		return nil, uplinkError.New("NotAfter is in the past: %v", options.NotAfter)
+	}
+
+	// Check the URL before registering credentials that would go unused.
+	if _, err := JoinShareURL(options.BaseURL, "-", bucket, keyOrPrefix, &ShareURLOptions{Raw: options.Raw}); err != nil {
+		return nil, err
+	}
+
+	permission := uplink.ReadOnlyPermission()
+	permission.NotBefore = options.NotBefore
+	permission.NotAfter = options.NotAfter
+
+	var prefixes []uplink.SharePrefix
+	if bucket != "" {
+		prefixes = append(prefixes, uplink.SharePrefix{Bucket: bucket, Prefix: keyOrPrefix})
+	}
+
+	shared, err := access.Share(permission, prefixes...)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := config.RegisterAccess(ctx, shared, &RegisterAccessOptions{Public: true})
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := JoinShareURL(options.BaseURL, credentials.AccessKeyID, bucket, keyOrPrefix, &ShareURLOptions{Raw: options.Raw})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ShareLink{
+		URL:         url,
+		Credentials: credentials,
+		Access:      shared,
+	}, nil
+}
+
+// RevokeShareLink revokes the access grant of a link created by
+// CreateShareLink. The project must be opened with the access grant the link
+// was created from, or one of its parents.
+//
+// There may be a delay until the link stops working, depending on the
+// caching policies of the satellite and the linksharing service.
+func RevokeShareLink(ctx context.Context, project *uplink.Project, link *ShareLink) error {
+	if link == nil || link.Access == nil {
+		return uplinkError.New("link access is missing")
+	}
+	return project.RevokeAccess(ctx, link.Access)
+}
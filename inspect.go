@@ -0,0 +1,275 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package uplink
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zeebo/errs"
+
+	"common/pb"
+	"common/storx"
+	"uplink/private/eestream"
+	"uplink/private/metaclient"
+	"uplink/private/piecestore"
+)
+
+// InspectObjectOptions contains additional options for inspecting an object.
+type InspectObjectOptions struct {
+	// DialNodes makes InspectObject dial every storage node the satellite
+	// reports a piece on, to confirm that it is reachable.
+	DialNodes bool
+	// DialTimeout limits how long a single node is dialed for. When zero,
+	// only the context limits it.
+	DialTimeout time.Duration
+}
+
+// ObjectInspection describes how an object is stored.
+type ObjectInspection struct {
+	Object   *Object
+	Segments []SegmentInspection
+}
+
+// SegmentInspection describes how a single segment of an object is stored.
+type SegmentInspection struct {
+	PartNumber uint32
+	Index      uint32
+
+	// PlainSize is the size of the segment before encryption.
+	PlainSize int64
+	// EncryptedSize is the size of the segment after encryption.
+	EncryptedSize int64
+
+	// Inline is true when the segment is stored with the object metadata
+	// on the satellite instead of on storage nodes.
+	Inline bool
+
+	// RedundancyScheme is the redundancy scheme of a remote segment.
+	RedundancyScheme RedundancyScheme
+
+	// Pieces are the pieces the satellite reports for a remote segment.
+	Pieces []PieceInspection
+	// Reachable is the number of pieces on nodes that could be dialed. It
+	// is -1 when the nodes were not dialed.
+	Reachable int
+}
+
+// RedundancyScheme describes how a segment is erasure coded.
+type RedundancyScheme struct {
+	// Required is the number of pieces needed to reconstruct the segment.
+	Required int
+	// Repair is the number of pieces below which the segment is repaired.
+	Repair int
+	// Optimal is the number of pieces an upload aims for.
+	Optimal int
+	// Total is the number of pieces the segment is encoded into.
+	Total int
+}
+
+// PieceInspection describes a single piece of a remote segment.
+type PieceInspection struct {
+	Number      int
+	NodeID      string
+	NodeAddress string
+
+	// DialError is the error dialing the node, if it was dialed and failed.
+	DialError error
+}
+
+// Margin returns how many more pieces the segment can lose before it can
+// no longer be reconstructed. When the nodes were dialed, only reachable
+// pieces are counted. Inline segments have no pieces to lose, so their
+// margin is -1.
+func (segment SegmentInspection) Margin() int {
+	if segment.Inline {
+		return -1
+	}
+	available := len(segment.Pieces)
+	if segment.Reachable >= 0 {
+		available = segment.Reachable
+	}
+	return available - segment.RedundancyScheme.Required
+}
+
+// InspectObject returns how each segment of the object at the specific key
+// is stored, so that it is possible to tell how close it is to being lost.
+func (project *Project) InspectObject(ctx context.Context, bucket, key string, options *InspectObjectOptions) (_ *ObjectInspection, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if options == nil {
+		options = &InspectObjectOptions{}
+	}
+
+	inspection := &ObjectInspection{}
+	inspection.Object, err = project.forEachSegment(ctx, bucket, key, func(ctx context.Context, response metaclient.DownloadSegmentWithRSResponse) error {
+		segment, err := project.inspectSegment(ctx, response, options)
+		if err != nil {
+			return err
+		}
+		inspection.Segments = append(inspection.Segments, segment)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return inspection, nil
+}
+
+// forEachSegment calls fn with the download information of every segment of
+// the object at the specific key, in order, and returns the object.
+func (project *Project) forEachSegment(ctx context.Context, bucket, key string, fn func(context.Context, metaclient.DownloadSegmentWithRSResponse) error) (_ *Object, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	metainfoClient, err := project.dialMetainfoClient(ctx)
+	if err != nil {
+		return nil, convertKnownErrors(err, bucket, key)
+	}
+	defer func() { err = errs.Combine(err, metainfoClient.Close()) }()
+
+	db := metaclient.New(metainfoClient, project.access.encAccess.Store)
+
+	object, err := db.GetObject(ctx, bucket, key)
+	if err != nil {
+		return nil, convertKnownErrors(err, bucket, key)
+	}
+
+	params := metaclient.ListSegmentsParams{
+		StreamID: object.ID,
+	}
+	for {
+		list, err := metainfoClient.ListSegments(ctx, params)
+		if err != nil {
+			return nil, convertKnownErrors(err, bucket, key)
+		}
+
+		for _, item := range list.Items {
+			response, err := metainfoClient.DownloadSegmentWithRS(ctx, metaclient.DownloadSegmentParams{
+				StreamID: object.ID,
+				Position: item.Position,
+			})
+			if err != nil {
+				return nil, convertKnownErrors(err, bucket, key)
+			}
+			if response.Info.Position == nil {
+				position := item.Position
+				response.Info.Position = &position
+			}
+
+			if err := fn(ctx, response); err != nil {
+				return nil, convertKnownErrors(err, bucket, key)
+			}
+		}
+
+		if !list.More || len(list.Items) == 0 {
+			break
+		}
+		params.Cursor = list.Items[len(list.Items)-1].Position
+	}
+
+	return convertObject(&object), nil
+}
+
+func (project *Project) inspectSegment(ctx context.Context, response metaclient.DownloadSegmentWithRSResponse, options *InspectObjectOptions) (_ SegmentInspection, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	info := response.Info
+	segment := SegmentInspection{
+		PartNumber:    uint32(info.Position.PartNumber),
+		Index:         uint32(info.Position.Index),
+		PlainSize:     info.PlainSize,
+		EncryptedSize: info.EncryptedSize,
+		Reachable:     -1,
+	}
+
+	if isInlineSegment(response) {
+		segment.Inline = true
+		if segment.EncryptedSize == 0 {
+			segment.EncryptedSize = int64(len(info.EncryptedInlineData))
+		}
+		return segment, nil
+	}
+
+	redundancy, err := eestream.NewRedundancyStrategyFromStorx(info.RedundancyScheme)
+	if err != nil {
+		return SegmentInspection{}, err
+	}
+	segment.RedundancyScheme = convertRedundancyStrategy(redundancy)
+
+	for num, limit := range response.Limits {
+		if limit == nil {
+			continue
+		}
+		segment.Pieces = append(segment.Pieces, PieceInspection{
+			Number:      num,
+			NodeID:      limit.GetLimit().StorageNodeId.String(),
+			NodeAddress: limit.GetStorageNodeAddress().GetAddress(),
+		})
+	}
+
+	if options.DialNodes {
+		segment.Reachable = project.dialPieces(ctx, response.Limits, segment.Pieces, options.DialTimeout)
+	}
+
+	return segment, nil
+}
+
+// isInlineSegment returns whether the segment is stored on the satellite.
+func isInlineSegment(response metaclient.DownloadSegmentWithRSResponse) bool {
+	// no order limits also means its inline segment
+	return len(response.Info.EncryptedInlineData) != 0 || len(response.Limits) == 0
+}
+
+func convertRedundancyStrategy(redundancy eestream.RedundancyStrategy) RedundancyScheme {
+	return RedundancyScheme{
+		Required: redundancy.RequiredCount(),
+		Repair:   redundancy.RepairThreshold(),
+		Optimal:  redundancy.OptimalThreshold(),
+		Total:    redundancy.TotalCount(),
+	}
+}
+
+// dialPieces dials the nodes of pieces concurrently, sets the dial error of
+// the pieces that could not be reached and returns how many could.
+func (project *Project) dialPieces(ctx context.Context, limits []*pb.AddressedOrderLimit, pieces []PieceInspection, timeout time.Duration) int {
+	defer mon.Task()(&ctx)(nil)
+
+	var wg sync.WaitGroup
+	for i := range pieces {
+		piece := &pieces[i]
+		limit := limits[piece.Number]
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx := ctx
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			client, err := piecestore.Dial(ctx, project.storagenodeDialer, storx.NodeURL{
+				ID:      limit.GetLimit().StorageNodeId,
+				Address: limit.GetStorageNodeAddress().GetAddress(),
+			}, piecestore.DefaultConfig)
+			if err == nil {
+				err = client.Close()
+			}
+			piece.DialError = err
+		}()
+	}
+	wg.Wait()
+
+	reachable := 0
+	for _, piece := range pieces {
+		if piece.DialError == nil {
+			reachable++
+		}
+	}
+	return reachable
+}
@@ -12,6 +12,7 @@ import (
 	"common/rpc"
 	"common/rpc/rpcpool"
 	"uplink"
+	"uplink/private/reputation"
 )
 
 // ConfigSetConnectionPool exposes Config.setConnectionPool.
@@ -55,3 +56,9 @@ func AccessGetEncAccess(*uplink.Access) *grant.EncryptionAccess
 //nolint:revive
 //go:linkname ConfigRequestAccessWithPassphraseAndConcurrency uplink.config_requestAccessWithPassphraseAndConcurrency
 func ConfigRequestAccessWithPassphraseAndConcurrency(config uplink.Config, ctx context.Context, satelliteAddress, apiKey, passphrase string, concurrency uint8) (*uplink.Access, error)
+
+// ProjectNodeReputation exposes the node reputation cache of a project, to
+// inspect what is known about the storage nodes for debugging.
+//
+//go:linkname ProjectNodeReputation uplink.project_nodeReputation
+func ProjectNodeReputation(*uplink.Project) *reputation.Cache
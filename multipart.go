@@ -5,10 +5,14 @@ package uplink
 
 import (
 	"context"
+	"crypto/md5"
 	"crypto/rand"
 	"errors"
+	"hash"
+	"io"
 	"math"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -21,6 +25,7 @@ import (
 	"common/pb"
 	"common/storx"
 	"uplink/private/eestream/scheduler"
+	"uplink/private/etag"
 	"uplink/private/metaclient"
 	"uplink/private/storage/streams"
 	"uplink/private/stream"
@@ -131,7 +136,15 @@ func (project *Project) CommitUpload(ctx context.Context, bucket, key, uploadID
 		opts = &CommitUploadOptions{}
 	}
 
-	commitObjParams, err := project.fillMetadata(bucket, key, id, opts.CustomMetadata)
+	var eTag string
+	if project.config.ComputePartETags {
+		eTag, err = project.compositeETag(ctx, bucket, key, uploadID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	commitObjParams, err := project.fillMetadata(bucket, key, id, opts.CustomMetadata, eTag)
 	if err != nil {
 		return nil, packageError.Wrap(err)
 	}
@@ -150,12 +163,47 @@ func (project *Project) CommitUpload(ctx context.Context, bucket, key, uploadID
 	// TODO return real object after committing
 	return &Object{
 		Key: key,
+		System: SystemMetadata{
+			ETag: eTag,
+		},
 	}, nil
 }
 
-func (project *Project) fillMetadata(bucket, key string, id storx.StreamID, metadata CustomMetadata) (metaclient.CommitObjectParams, error) {
+// compositeETag returns the S3-style ETag of a multipart upload from the
+// ETags of its parts. It returns an empty ETag if some part doesn't have an
+// MD5 for its ETag.
+func (project *Project) compositeETag(ctx context.Context, bucket, key, uploadID string) (_ string, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	var parts []*Part
+	iterator := project.ListUploadParts(ctx, bucket, key, uploadID, nil)
+	for iterator.Next() {
+		parts = append(parts, iterator.Item())
+	}
+	if err := iterator.Err(); err != nil {
+		return "", err
+	}
+
+	sort.Slice(parts, func(i, k int) bool {
+		return parts[i].PartNumber < parts[k].PartNumber
+	})
+
+	eTags := make([][]byte, 0, len(parts))
+	for _, part := range parts {
+		eTags = append(eTags, part.ETag)
+	}
+
+	eTag, err := etag.Composite(eTags)
+	if err != nil {
+		mon.Event("multipart_composite_etag_skipped")
+		return "", nil
+	}
+	return eTag, nil
+}
+
+func (project *Project) fillMetadata(bucket, key string, id storx.StreamID, metadata CustomMetadata, eTag string) (metaclient.CommitObjectParams, error) {
 	commitObjParams := metaclient.CommitObjectParams{StreamID: id}
-	if len(metadata) == 0 {
+	if len(metadata) == 0 && eTag == "" {
 		return commitObjParams, nil
 	}
 
@@ -172,6 +220,7 @@ func (project *Project) fillMetadata(bucket, key string, id storx.StreamID, meta
 	if err != nil {
 		return metaclient.CommitObjectParams{}, packageError.Wrap(err)
 	}
+	streamInfo = metaclient.AppendStreamInfoETag(streamInfo, eTag)
 
 	derivedKey, err := deriveContentKey(project, bucket, key)
 	if err != nil {
@@ -219,10 +268,28 @@ func (project *Project) fillMetadata(bucket, key string, id storx.StreamID, meta
 	return commitObjParams, nil
 }
 
+// UploadPartOptions contains additional options for uploading a part.
+type UploadPartOptions struct {
+	// MaximumBytesPerSecond limits the rate at which this part sends bytes
+	// to storage nodes, including the erasure coding expansion. When positive
+	// it replaces Config.MaximumUploadBytesPerSecond for this part, so it can
+	// also be higher, and the part isn't counted against that limit.
+	MaximumBytesPerSecond int64
+
+	// Background marks the part upload as background work, like
+	// UploadOptions.Background.
+	Background bool
+}
+
 // UploadPart uploads a part with partNumber to a multipart upload started with BeginUpload.
 //
 // uploadID is an upload identifier returned by BeginUpload.
 func (project *Project) UploadPart(ctx context.Context, bucket, key, uploadID string, partNumber uint32) (_ *PartUpload, err error) {
+	return project.UploadPartWithOptions(ctx, bucket, key, uploadID, partNumber, nil)
+}
+
+// UploadPartWithOptions is like UploadPart, with additional options.
+func (project *Project) UploadPartWithOptions(ctx context.Context, bucket, key, uploadID string, partNumber uint32, options *UploadPartOptions) (_ *PartUpload, err error) {
 	upload := &PartUpload{
 		bucket: bucket,
 		key:    key,
@@ -232,6 +299,9 @@ func (project *Project) UploadPart(ctx context.Context, bucket, key, uploadID st
 		stats:  newOperationStats(ctx, project.access.satelliteURL),
 		eTagCh: make(chan []byte, 1),
 	}
+	if project.config.ComputePartETags {
+		upload.hash = md5.New()
+	}
 	upload.task = mon.TaskNamed("PartUpload")(&ctx)
 	defer func() {
 		if err != nil {
@@ -262,6 +332,11 @@ func (project *Project) UploadPart(ctx context.Context, bucket, key, uploadID st
 		upload.stats.encPath = encPath
 	}
 
+	if options == nil {
+		options = &UploadPartOptions{}
+	}
+	ctx = project.withUploadLimiter(ctx, options.MaximumBytesPerSecond)
+
 	ctx, cancel := context.WithCancel(ctx)
 	upload.cancel = cancel
 
@@ -274,7 +349,11 @@ func (project *Project) UploadPart(ctx context.Context, bucket, key, uploadID st
 	if project.concurrentSegmentUploadConfig == nil {
 		upload.upload = stream.NewUploadPart(ctx, bucket, key, decodedStreamID, partNumber, upload.eTagCh, streams)
 	} else {
-		sched := scheduler.New(project.concurrentSegmentUploadConfig.SchedulerOptions)
+		prio := scheduler.PriorityInteractive
+		if options.Background {
+			prio = scheduler.PriorityBackground
+		}
+		sched := project.newScheduler(prio)
 		u, err := streams.UploadPart(ctx, bucket, key, decodedStreamID, int32(partNumber), upload.eTagCh, sched)
 		if err != nil {
 			return nil, convertKnownErrors(err, bucket, key)
@@ -442,6 +521,9 @@ type PartUpload struct {
 	streams *streams.Store
 	eTagCh  chan []byte
 
+	// hash, if not nil, computes the ETag from the data written.
+	hash hash.Hash
+
 	stats operationStats
 	task  func(*error)
 }
@@ -452,6 +534,9 @@ type PartUpload struct {
 func (upload *PartUpload) Write(p []byte) (int, error) {
 	track := upload.stats.trackWorking()
 	n, err := upload.upload.Write(p)
+	if upload.hash != nil {
+		_, _ = upload.hash.Write(p[:n])
+	}
 	upload.mu.Lock()
 	upload.stats.bytes += int64(n)
 	upload.stats.flagFailure(err)
@@ -460,6 +545,23 @@ func (upload *PartUpload) Write(p []byte) (int, error) {
 	return n, convertKnownErrors(err, upload.bucket, upload.key)
 }
 
+// ReadFrom uploads data read from r to the part's data stream until EOF.
+// It is used by io.Copy, and hands r to the upload to read from, instead of
+// copying the data through a buffer of its own.
+func (upload *PartUpload) ReadFrom(r io.Reader) (n int64, err error) {
+	track := upload.stats.trackWorking()
+	if upload.hash != nil {
+		r = etag.NewHashReader(r, upload.hash)
+	}
+	n, err = upload.upload.ReadFrom(r)
+	upload.mu.Lock()
+	upload.stats.bytes += n
+	upload.stats.flagFailure(err)
+	track()
+	upload.mu.Unlock()
+	return n, convertKnownErrors(err, upload.bucket, upload.key)
+}
+
 // SetETag sets ETag for a part.
 func (upload *PartUpload) SetETag(eTag []byte) error {
 	upload.mu.Lock()
@@ -499,6 +601,11 @@ func (upload *PartUpload) Commit() error {
 
 	upload.closed = true
 
+	if upload.hash != nil && upload.part.ETag == nil {
+		upload.part.ETag = upload.hash.Sum(nil)
+		upload.eTagCh <- upload.part.ETag
+	}
+
 	// ETag must not be sent after a call to commit. The upload code waits on
 	// the channel before committing the last segment. Closing the channel
 	// allows the upload code to unblock if no eTag has been set. Not all
@@ -0,0 +1,394 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package uplink
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"uplink/private/etag"
+)
+
+// ErrResumeStateInvalid is returned when a resume state cannot be used to
+// continue an upload.
+var ErrResumeStateInvalid = errors.New("resume state invalid")
+
+const (
+	// defaultParallelUploadConcurrency is how many parts UploadParallel
+	// uploads at once by default.
+	defaultParallelUploadConcurrency = 4
+
+	// maxParallelUploadParts is the most parts UploadParallel splits a
+	// source into when picking the part size itself.
+	maxParallelUploadParts = 10000
+
+	// parallelUploadStateVersion is the version of the resume state.
+	parallelUploadStateVersion = 1
+)
+
+// ParallelUploadOptions contains additional options for UploadParallel.
+type ParallelUploadOptions struct {
+	// PartSize is the size of every part but the last one. When zero it is
+	// the segment size of the project, or larger if needed to keep the
+	// number of parts at or below 10000.
+	PartSize int64
+
+	// Concurrency is how many parts are uploaded at once. When zero it is 4.
+	Concurrency int
+
+	// Upload is used to begin the upload. Its MaximumBytesPerSecond limits
+	// all the parts together, instead of Config.MaximumUploadBytesPerSecond,
+	// and its Background applies to every part.
+	Upload *UploadOptions
+
+	// Commit is used to commit the upload.
+	Commit *CommitUploadOptions
+
+	// Resume is a state returned by an earlier call for the same source.
+	// When not empty, the upload it describes is continued instead of
+	// beginning a new one.
+	Resume string
+
+	// Progress, if not nil, is called with the current state every time a
+	// part is uploaded, so that it can be persisted and used to resume the
+	// upload if the process stops. Calls are not concurrent.
+	Progress func(state string)
+}
+
+// UploadParallel uploads size bytes of src to bucket and key as a multipart
+// upload, uploading several parts at once.
+//
+// When it fails after the upload has begun, it returns the state of the
+// upload, which can be passed as ParallelUploadOptions.Resume to continue the
+// upload. The state is empty once the upload is committed. When resuming,
+// parts that were already uploaded are kept if their size and ETag match the
+// source, and uploaded again otherwise.
+//
+// The ETag of every part is the MD5 of its contents.
+func (project *Project) UploadParallel(ctx context.Context, bucket, key string, src io.ReaderAt, size int64, options *ParallelUploadOptions) (_ *Object, state string, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	switch {
+	case bucket == "":
+		return nil, "", errwrapf("%w (%q)", ErrBucketNameInvalid, bucket)
+	case key == "":
+		return nil, "", errwrapf("%w (%q)", ErrObjectKeyInvalid, key)
+	case size < 0:
+		return nil, "", packageError.New("negative size %d", size)
+	}
+
+	if options == nil {
+		options = &ParallelUploadOptions{}
+	}
+
+	upload := &parallelUpload{
+		project:  project,
+		bucket:   bucket,
+		key:      key,
+		src:      src,
+		progress: options.Progress,
+	}
+	if options.Upload != nil {
+		upload.background = options.Upload.Background
+
+		// the parts share the limiter, because UploadPart chains its own
+		// under it.
+		ctx = project.withUploadLimiter(ctx, options.Upload.MaximumBytesPerSecond)
+	}
+
+	if options.Resume != "" {
+		upload.state, err = decodeParallelUploadState(options.Resume)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := upload.state.check(bucket, key, size, options.PartSize); err != nil {
+			return nil, options.Resume, err
+		}
+	} else {
+		partSize := options.PartSize
+		if partSize <= 0 {
+			partSize = project.segmentSize
+			if least := (size + maxParallelUploadParts - 1) / maxParallelUploadParts; partSize < least {
+				// keep parts a multiple of the segment size.
+				partSize = (least + project.segmentSize - 1) / project.segmentSize * project.segmentSize
+			}
+		}
+
+		info, err := project.BeginUpload(ctx, bucket, key, options.Upload)
+		if err != nil {
+			return nil, "", err
+		}
+
+		upload.state = parallelUploadState{
+			Version:  parallelUploadStateVersion,
+			Bucket:   bucket,
+			Key:      key,
+			UploadID: info.UploadID,
+			Size:     size,
+			PartSize: partSize,
+		}
+		upload.report()
+	}
+
+	pending, err := upload.pendingParts(ctx)
+	if err != nil {
+		return nil, upload.encode(), err
+	}
+
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultParallelUploadConcurrency
+	}
+
+	group, gctx := errgroup.WithContext(ctx)
+	group.SetLimit(concurrency)
+	for _, number := range pending {
+		number := number
+		group.Go(func() error {
+			return upload.uploadPart(gctx, number)
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, upload.encode(), err
+	}
+
+	object, err := project.CommitUpload(ctx, bucket, key, upload.state.UploadID, options.Commit)
+	if err != nil {
+		return nil, upload.encode(), err
+	}
+	return object, "", nil
+}
+
+// parallelUploadState is what is needed to resume a parallel upload. It is
+// encoded as base64 JSON.
+type parallelUploadState struct {
+	Version  int                  `json:"v"`
+	Bucket   string               `json:"bucket"`
+	Key      string               `json:"key"`
+	UploadID string               `json:"upload_id"`
+	Size     int64                `json:"size"`
+	PartSize int64                `json:"part_size"`
+	Parts    []parallelUploadPart `json:"parts,omitempty"`
+}
+
+// parallelUploadPart is a part that was uploaded.
+type parallelUploadPart struct {
+	Number uint32 `json:"n"`
+	Size   int64  `json:"size"`
+	ETag   []byte `json:"etag"`
+}
+
+func decodeParallelUploadState(encoded string) (state parallelUploadState, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return state, errwrapf("%w: %v", ErrResumeStateInvalid, err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, errwrapf("%w: %v", ErrResumeStateInvalid, err)
+	}
+	if state.Version != parallelUploadStateVersion {
+		return state, errwrapf("%w: unsupported version %d", ErrResumeStateInvalid, state.Version)
+	}
+	if state.UploadID == "" || state.PartSize <= 0 {
+		return state, errwrapf("%w: missing upload", ErrResumeStateInvalid)
+	}
+	return state, nil
+}
+
+// check returns an error if the state is not for the given upload.
+func (state *parallelUploadState) check(bucket, key string, size, partSize int64) error {
+	switch {
+	case state.Bucket != bucket || state.Key != key:
+		return errwrapf("%w: state is for %q/%q", ErrResumeStateInvalid, state.Bucket, state.Key)
+	case state.Size != size:
+		return errwrapf("%w: state is for %d bytes, not %d", ErrResumeStateInvalid, state.Size, size)
+	case partSize > 0 && state.PartSize != partSize:
+		return errwrapf("%w: state has parts of %d bytes, not %d", ErrResumeStateInvalid, state.PartSize, partSize)
+	}
+	return nil
+}
+
+// partCount returns the number of parts of the upload. Empty sources are
+// uploaded as a single empty part.
+func (state *parallelUploadState) partCount() uint32 {
+	if state.Size == 0 {
+		return 1
+	}
+	return uint32((state.Size + state.PartSize - 1) / state.PartSize)
+}
+
+// partRange returns the offset and size of a part in the source. Parts are
+// numbered from 1.
+func (state *parallelUploadState) partRange(number uint32) (offset, size int64) {
+	offset = int64(number-1) * state.PartSize
+	size = state.PartSize
+	if offset+size > state.Size {
+		size = state.Size - offset
+	}
+	return offset, size
+}
+
+// parallelUpload is a single UploadParallel call.
+type parallelUpload struct {
+	project  *Project
+	bucket   string
+	key      string
+	src      io.ReaderAt
+	progress func(state string)
+
+	// background is whether the parts are uploaded as background work.
+	background bool
+
+	mu    sync.Mutex
+	state parallelUploadState
+}
+
+// pendingParts returns the numbers of the parts that still need to be
+// uploaded, after checking the parts that the upload already has.
+func (upload *parallelUpload) pendingParts(ctx context.Context) (pending []uint32, err error) {
+	recorded := make(map[uint32]parallelUploadPart, len(upload.state.Parts))
+	for _, part := range upload.state.Parts {
+		recorded[part.Number] = part
+	}
+
+	existing := make(map[uint32]*Part)
+	parts := upload.project.ListUploadParts(ctx, upload.bucket, upload.key, upload.state.UploadID, nil)
+	for parts.Next() {
+		part := parts.Item()
+		existing[part.PartNumber] = part
+	}
+	if err := parts.Err(); err != nil {
+		return nil, err
+	}
+
+	var kept []parallelUploadPart
+	for number := uint32(1); number <= upload.state.partCount(); number++ {
+		offset, size := upload.state.partRange(number)
+
+		part, ok := existing[number]
+		if !ok || part.Size != size {
+			pending = append(pending, number)
+			continue
+		}
+
+		eTag := recorded[number].ETag
+		if eTag == nil {
+			// the part was uploaded, but the process stopped before its
+			// state was reported, so compare with the source instead.
+			eTag, err = hashPart(ctx, io.NewSectionReader(upload.src, offset, size))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if !bytes.Equal(part.ETag, eTag) {
+			pending = append(pending, number)
+			continue
+		}
+		kept = append(kept, parallelUploadPart{Number: number, Size: size, ETag: eTag})
+	}
+
+	upload.mu.Lock()
+	upload.state.Parts = kept
+	upload.mu.Unlock()
+
+	return pending, nil
+}
+
+// uploadPart uploads a single part and records it in the state.
+func (upload *parallelUpload) uploadPart(ctx context.Context, number uint32) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	offset, size := upload.state.partRange(number)
+
+	part, err := upload.project.UploadPartWithOptions(ctx, upload.bucket, upload.key, upload.state.UploadID, number, &UploadPartOptions{
+		Background: upload.background,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = part.Abort()
+		}
+	}()
+
+	reader := etag.NewHashReader(io.NewSectionReader(upload.src, offset, size), md5.New())
+	if _, err := io.Copy(part, reader); err != nil {
+		return err
+	}
+
+	eTag := reader.CurrentETag()
+	if err := part.SetETag(eTag); err != nil {
+		return err
+	}
+	if err := part.Commit(); err != nil {
+		return err
+	}
+
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	upload.state.Parts = append(upload.state.Parts, parallelUploadPart{
+		Number: number,
+		Size:   size,
+		ETag:   eTag,
+	})
+	upload.reportLocked()
+	return nil
+}
+
+// report calls the progress callback with the current state.
+func (upload *parallelUpload) report() {
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	upload.reportLocked()
+}
+
+func (upload *parallelUpload) reportLocked() {
+	if upload.progress != nil {
+		upload.progress(upload.encodeLocked())
+	}
+}
+
+// encode returns the current state encoded.
+func (upload *parallelUpload) encode() string {
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	return upload.encodeLocked()
+}
+
+func (upload *parallelUpload) encodeLocked() string {
+	state := upload.state
+	state.Parts = append([]parallelUploadPart(nil), state.Parts...)
+	sort.Slice(state.Parts, func(i, k int) bool {
+		return state.Parts[i].Number < state.Parts[k].Number
+	})
+
+	// the state only has types that always marshal.
+	data, _ := json.Marshal(state)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// hashPart returns the MD5 of r.
+func hashPart(ctx context.Context, r io.Reader) (_ []byte, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	h := md5.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, packageError.Wrap(err)
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,162 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package uplink
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// defaultAbortStaleUploadsConcurrency is how many uploads AbortStaleUploads
+// aborts at once by default.
+const defaultAbortStaleUploadsConcurrency = 8
+
+// AbortStaleUploadsOptions contains additional options for AbortStaleUploads.
+type AbortStaleUploadsOptions struct {
+	// Prefix selects only uploads with keys that start with it. Unlike
+	// ListUploadsOptions.Prefix, it does not need to end with a slash.
+	Prefix string
+
+	// Custom selects only uploads that have all of its entries in their
+	// custom metadata.
+	Custom CustomMetadata
+
+	// Concurrency is how many uploads are aborted at once. When zero it is 8.
+	Concurrency int
+
+	// DryRun reports the uploads that would be aborted without aborting
+	// them.
+	DryRun bool
+}
+
+// StaleUpload is an upload selected by AbortStaleUploads.
+type StaleUpload struct {
+	Key      string
+	UploadID string
+	Created  time.Time
+
+	// Aborted is whether the upload was aborted. It is false for a dry run.
+	Aborted bool
+	// Err is why the upload could not be aborted.
+	Err error
+}
+
+// AbortStaleUploadsReport is the result of AbortStaleUploads.
+type AbortStaleUploadsReport struct {
+	// Uploads are the selected uploads, in key order.
+	Uploads []StaleUpload
+
+	// Aborted is the number of uploads that were aborted.
+	Aborted int
+	// Failed is the number of uploads that could not be aborted.
+	Failed int
+}
+
+// AbortStaleUploads aborts the uncommitted uploads in bucket that were
+// created more than olderThan ago and match options, and reports them.
+// olderThan must be positive, so that a zero duration can't abort every
+// upload in the bucket by mistake.
+//
+// An error aborting a single upload doesn't stop the others from being
+// aborted and is reported for that upload. The returned error is only for
+// failing to list the uploads.
+func (project *Project) AbortStaleUploads(ctx context.Context, bucket string, olderThan time.Duration, options *AbortStaleUploadsOptions) (_ *AbortStaleUploadsReport, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if bucket == "" {
+		return nil, errwrapf("%w (%q)", ErrBucketNameInvalid, bucket)
+	}
+	if olderThan <= 0 {
+		return nil, packageError.New("olderThan must be positive, got %v", olderThan)
+	}
+
+	if options == nil {
+		options = &AbortStaleUploadsOptions{}
+	}
+
+	report := &AbortStaleUploadsReport{}
+	cutoff := time.Now().Add(-olderThan)
+
+	// listing needs a prefix that ends with a slash, so list everything
+	// under the last one and filter the rest.
+	listPrefix := ""
+	if i := strings.LastIndexByte(options.Prefix, '/'); i >= 0 {
+		listPrefix = options.Prefix[:i+1]
+	}
+
+	uploads := project.ListUploads(ctx, bucket, &ListUploadsOptions{
+		Prefix:    listPrefix,
+		Recursive: true,
+		System:    true,
+		Custom:    len(options.Custom) > 0,
+	})
+	for uploads.Next() {
+		upload := uploads.Item()
+		if !upload.System.Created.Before(cutoff) ||
+			!strings.HasPrefix(upload.Key, options.Prefix) ||
+			!hasCustomMetadata(upload.Custom, options.Custom) {
+			continue
+		}
+
+		report.Uploads = append(report.Uploads, StaleUpload{
+			Key:      upload.Key,
+			UploadID: upload.UploadID,
+			Created:  upload.System.Created,
+		})
+	}
+	if err := uploads.Err(); err != nil {
+		return nil, err
+	}
+
+	// uploads are listed in the order of their encrypted keys.
+	sort.SliceStable(report.Uploads, func(i, k int) bool {
+		return report.Uploads[i].Key < report.Uploads[k].Key
+	})
+
+	if options.DryRun {
+		return report, nil
+	}
+
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultAbortStaleUploadsConcurrency
+	}
+
+	var group errgroup.Group
+	group.SetLimit(concurrency)
+	for i := range report.Uploads {
+		upload := &report.Uploads[i]
+		group.Go(func() error {
+			upload.Err = project.AbortUpload(ctx, bucket, upload.Key, upload.UploadID)
+			upload.Aborted = upload.Err == nil
+			return nil
+		})
+	}
+	_ = group.Wait()
+
+	for _, upload := range report.Uploads {
+		if upload.Aborted {
+			report.Aborted++
+		} else {
+			report.Failed++
+		}
+	}
+	mon.IntVal("stale_uploads_aborted").Observe(int64(report.Aborted))
+
+	return report, nil
+}
+
+// hasCustomMetadata returns whether metadata has all the entries of want.
+func hasCustomMetadata(metadata, want CustomMetadata) bool {
+	for key, value := range want {
+		if got, ok := metadata[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
@@ -37,6 +37,10 @@ type SystemMetadata struct {
 	Created       time.Time
 	Expires       time.Time
 	ContentLength int64
+
+	// ETag is the S3-style ETag of the object, when it is known. It is set
+	// for multipart uploads committed with Config.ComputePartETags.
+	ETag string
 }
 
 // CustomMetadata contains custom user metadata about the object.
@@ -151,6 +155,7 @@ func convertObject(obj *metaclient.Object) *Object {
 			Created:       obj.Created,
 			Expires:       obj.Expires,
 			ContentLength: obj.Size,
+			ETag:          obj.ETag,
 		},
 		Custom: obj.Metadata,
 	}
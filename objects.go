@@ -161,6 +161,8 @@ func (objects *ObjectIterator) Item() *Object {
 			Created:       item.Created,
 			Expires:       item.Expires,
 			ContentLength: item.Size,
+			// the ETag is only known when custom metadata is listed.
+			ETag: item.ETag,
 		}
 	}
 
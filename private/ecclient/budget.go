@@ -0,0 +1,83 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package ecclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"common/ranger"
+	"uplink/private/eestream"
+	"uplink/private/storage/streams/buffer"
+)
+
+// budgetedRanger acquires the memory used to decode a segment from a budget
+// for as long as a range of it is being read.
+type budgetedRanger struct {
+	ranger.Ranger
+	budget *buffer.Budget
+	size   int64
+}
+
+// Range implements ranger.Ranger.
+func (rr *budgetedRanger) Range(ctx context.Context, offset, length int64) (_ io.ReadCloser, err error) {
+	acquired, err := rr.budget.Acquire(ctx, rr.size)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	r, err := rr.Ranger.Range(ctx, offset, length)
+	if err != nil {
+		rr.budget.Release(acquired)
+		return nil, err
+	}
+
+	return &budgetedReadCloser{
+		ReadCloser: r,
+		release:    func() { rr.budget.Release(acquired) },
+	}, nil
+}
+
+// budgetedReadCloser gives the memory back to the budget when it is read to
+// the end or closed, whichever is first, so that a download reading one
+// segment after the other only holds the memory of one of them.
+type budgetedReadCloser struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+// Read implements io.Reader.
+func (r *budgetedReadCloser) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	if errors.Is(err, io.EOF) {
+		r.once.Do(r.release)
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (r *budgetedReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
+
+// decodeMemory returns the number of bytes allocated by the stripe reader to
+// decode from the readers with mbm maximum buffer memory.
+func decodeMemory(readers int, es eestream.ErasureScheme, mbm int) int64 {
+	shareSize := es.ErasureShareSize()
+
+	bufSize := mbm / readers
+	bufSize -= bufSize % shareSize
+	if bufSize < shareSize {
+		bufSize = shareSize
+	}
+
+	// every reader has a piece buffer and an input buffer of one share, and
+	// the decoded stripe is kept in an output buffer.
+	return int64(readers)*int64(bufSize+shareSize) + int64(es.StripeSize())
+}
@@ -0,0 +1,53 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package ecclient
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"common/ranger"
+	"uplink/private/storage/streams/buffer"
+)
+
+func TestBudgetedRanger(t *testing.T) {
+	ctx := context.Background()
+
+	budget := buffer.NewBudget(100)
+	rr := &budgetedRanger{
+		Ranger: ranger.ByteRanger([]byte("hello")),
+		budget: budget,
+		size:   100,
+	}
+
+	first, err := rr.Range(ctx, 0, 5)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), budget.Used())
+
+	// a second range waits for the first one to finish.
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = rr.Range(timeout, 0, 5)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// reading to the end gives the memory back before closing.
+	data, err := io.ReadAll(first)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(data))
+	require.Zero(t, budget.Used())
+
+	second, err := rr.Range(ctx, 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), budget.Used())
+
+	require.NoError(t, first.Close())
+	require.Equal(t, int64(100), budget.Used())
+	require.NoError(t, second.Close())
+	require.NoError(t, second.Close())
+	require.Zero(t, budget.Used())
+}
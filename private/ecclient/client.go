@@ -23,6 +23,9 @@ import (
 	"common/storx"
 	"uplink/private/eestream"
 	"uplink/private/piecestore"
+	"uplink/private/reputation"
+	"uplink/private/storage/streams/buffer"
+	"uplink/private/testuplink"
 )
 
 var mon = monkit.Package()
@@ -32,6 +35,21 @@ type Client interface {
 	PutSingleResult(ctx context.Context, limits []*pb.AddressedOrderLimit, privateKey storx.PiecePrivateKey, rs eestream.RedundancyStrategy, data io.Reader) (results []*pb.SegmentPieceUploadResult, err error)
 	Get(ctx context.Context, limits []*pb.AddressedOrderLimit, privateKey storx.PiecePrivateKey, es eestream.ErasureScheme, size int64) (ranger.Ranger, error)
 	WithForceErrorDetection(force bool) Client
+	// WithCorruptPieces returns a copy of the client that records the pieces
+	// found to be corrupt while downloading with forced error detection.
+	WithCorruptPieces(corrupt *CorruptPieces) Client
+	// WithReputation returns a copy of the client that records the outcomes
+	// of piece transfers with nodes in the cache.
+	WithReputation(cache *reputation.Cache) Client
+	// WithHedgePolicy returns a copy of the client that downloads pieces
+	// according to the hedging policy, or reads from all of them if nil.
+	WithHedgePolicy(policy *eestream.HedgePolicy) Client
+	// WithMemoryBudget returns a copy of the client that acquires the
+	// memory used to decode a segment from the budget while reading it.
+	WithMemoryBudget(budget *buffer.Budget) Client
+	// Verify downloads all available pieces of a segment and checks their
+	// hashes and erasure shares, without decoding the segment.
+	Verify(ctx context.Context, limits []*pb.AddressedOrderLimit, privateKey storx.PiecePrivateKey, es eestream.ErasureScheme, size int64) (SegmentVerification, error)
 	// PutPiece is not intended to be used by normal uplinks directly, but is exported to support storagenode graceful exit transfers.
 	PutPiece(ctx, parent context.Context, limit *pb.AddressedOrderLimit, privateKey storx.PiecePrivateKey, data io.ReadCloser) (hash *pb.PieceHash, id *struct{}, err error)
 }
@@ -42,6 +60,10 @@ type ecClient struct {
 	dialer              rpc.Dialer
 	memoryLimit         int
 	forceErrorDetection bool
+	hedge               *eestream.HedgePolicy
+	reputation          *reputation.Cache
+	corrupt             *CorruptPieces
+	budget              *buffer.Budget
 }
 
 // New creates a client from the given dialer and max buffer memory.
@@ -54,11 +76,40 @@ func New(dialer rpc.Dialer, memoryLimit int) Client {
 }
 
 func (ec *ecClient) WithForceErrorDetection(force bool) Client {
-	ec.forceErrorDetection = force
-	return ec
+	clone := *ec
+	clone.forceErrorDetection = force
+	return &clone
+}
+
+func (ec *ecClient) WithCorruptPieces(corrupt *CorruptPieces) Client {
+	clone := *ec
+	clone.corrupt = corrupt
+	return &clone
+}
+
+func (ec *ecClient) WithHedgePolicy(policy *eestream.HedgePolicy) Client {
+	clone := *ec
+	clone.hedge = policy
+	return &clone
+}
+
+func (ec *ecClient) WithReputation(cache *reputation.Cache) Client {
+	clone := *ec
+	clone.reputation = cache
+	return &clone
+}
+
+func (ec *ecClient) WithMemoryBudget(budget *buffer.Budget) Client {
+	clone := *ec
+	clone.budget = budget
+	return &clone
 }
 
 func (ec *ecClient) dialPiecestore(ctx context.Context, n storx.NodeURL) (*piecestore.Client, error) {
+	if err := testuplink.GetFaults(ctx).DialError(n.ID); err != nil {
+		return nil, err
+	}
+
 	hashAlgo := piecestore.GetPieceHashAlgo(ctx)
 	client, err := piecestore.DialReplaySafe(ctx, ec.dialer, n, piecestore.DefaultConfig)
 	if err != nil {
@@ -216,13 +267,18 @@ func (ec *ecClient) PutPiece(ctx, parent context.Context, limit *pb.AddressedOrd
 	}
 
 	storageNodeID := limit.GetLimit().StorageNodeId
+	start := time.Now()
 	ps, err := ec.dialPiecestore(ctx, limitToNodeURL(limit))
 	if err != nil {
+		if ctx.Err() == nil {
+			ec.reputation.Record(storageNodeID, reputation.DialFailure, 0, 0)
+		}
 		return nil, nil, Error.New("failed to dial (node:%v): %w", storageNodeID, err)
 	}
 	defer func() { err = errs.Combine(err, ps.Close()) }()
 
-	hash, err = ps.UploadReader(ctx, limit.GetLimit(), privateKey, data)
+	counted := &countingReader{r: testuplink.GetFaults(ctx).PieceReader(ctx, storageNodeID, data, false)}
+	hash, err = ps.UploadReader(ctx, limit.GetLimit(), privateKey, counted)
 	if err != nil {
 		if errors.Is(ctx.Err(), context.Canceled) {
 			// Canceled context means the piece upload was interrupted by user or due
@@ -230,6 +286,7 @@ func (ec *ecClient) PutPiece(ctx, parent context.Context, limit *pb.AddressedOrd
 			if errors.Is(parent.Err(), context.Canceled) {
 				err = Error.New("upload canceled by user: %w", err)
 			} else {
+				ec.reputation.Record(storageNodeID, reputation.Timeout, 0, 0)
 				err = Error.New("upload cut due to slow connection (node:%v): %w", storageNodeID, err)
 			}
 
@@ -241,15 +298,30 @@ func (ec *ecClient) PutPiece(ctx, parent context.Context, limit *pb.AddressedOrd
 			if limit.GetStorageNodeAddress() != nil {
 				nodeAddress = limit.GetStorageNodeAddress().GetAddress()
 			}
+			ec.reputation.Record(storageNodeID, reputation.Failure, 0, 0)
 			err = Error.New("upload failed (node:%v, address:%v): %w", storageNodeID, nodeAddress, err)
 		}
 
 		return nil, nil, err
 	}
 
+	ec.reputation.Record(storageNodeID, reputation.Success, counted.n, time.Since(start))
+
 	return hash, nil, nil
 }
 
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (n int, err error) {
+	n, err = c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
 func (ec *ecClient) Get(ctx context.Context, limits []*pb.AddressedOrderLimit, privateKey storx.PiecePrivateKey, es eestream.ErasureScheme, size int64) (rr ranger.Ranger, err error) {
 	defer mon.Task()(&ctx)(&err)
 
@@ -272,17 +344,35 @@ func (ec *ecClient) Get(ctx context.Context, limits []*pb.AddressedOrderLimit, p
 
 		rrs[i] = &lazyPieceRanger{
 			dialPiecestore: ec.dialPiecestore,
+			reputation:     ec.reputation,
 			limit:          addressedLimit,
 			privateKey:     privateKey,
 			size:           pieceSize,
 		}
 	}
 
-	rr, err = eestream.Decode(rrs, es, ec.memoryLimit, ec.forceErrorDetection)
+	rr, err = eestream.DecodeHedged(rrs, es, ec.memoryLimit, ec.forceErrorDetection, ec.hedge)
 	if err != nil {
 		return nil, Error.Wrap(err)
 	}
 
+	if ec.budget != nil {
+		rr = &budgetedRanger{
+			Ranger: rr,
+			budget: ec.budget,
+			size:   decodeMemory(len(rrs), es, ec.memoryLimit),
+		}
+	}
+
+	if ec.corrupt != nil {
+		rr = &corruptReportingRanger{
+			Ranger:     rr,
+			corrupt:    ec.corrupt,
+			reputation: ec.reputation,
+			limits:     limits,
+		}
+	}
+
 	ranger, err := encryption.Unpad(rr, int(paddedSize-size))
 	return ranger, Error.Wrap(err)
 }
@@ -320,6 +410,7 @@ func calcPadded(size int64, blockSize int) int64 {
 
 type lazyPieceRanger struct {
 	dialPiecestore dialPiecestoreFunc
+	reputation     *reputation.Cache
 	limit          *pb.AddressedOrderLimit
 	privateKey     storx.PiecePrivateKey
 	size           int64
@@ -342,6 +433,7 @@ func (lr *lazyPieceRanger) Range(ctx context.Context, offset, length int64) (_ i
 		cancel: cancel,
 		offset: offset,
 		length: length,
+		start:  time.Now(),
 	}, nil
 }
 
@@ -351,18 +443,48 @@ type lazyPieceReader struct {
 	cancel func()
 	offset int64
 	length int64
+	start  time.Time
 
 	mu       sync.Mutex
 	isClosed bool
+	recorded bool
+	read     int64
 	download *piecestore.Download
 	client   *piecestore.Client
+	// reader reads from download, with the injected test faults.
+	reader io.Reader
 }
 
 func (lr *lazyPieceReader) Read(data []byte) (_ int, err error) {
 	if err := lr.dial(); err != nil {
 		return 0, err
 	}
-	return lr.download.Read(data)
+	n, err := lr.reader.Read(data)
+
+	lr.mu.Lock()
+	lr.read += int64(n)
+	lr.mu.Unlock()
+
+	switch {
+	case errors.Is(err, io.EOF):
+		lr.record(reputation.Success)
+	case err != nil && lr.ctx.Err() == nil:
+		lr.record(reputation.Failure)
+	}
+	return n, err
+}
+
+// record records the outcome of the download in the reputation cache, once.
+func (lr *lazyPieceReader) record(outcome reputation.Outcome) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	if lr.recorded || lr.ranger.reputation == nil {
+		return
+	}
+	lr.recorded = true
+
+	lr.ranger.reputation.Record(lr.ranger.limit.GetLimit().StorageNodeId, outcome, lr.read, time.Since(lr.start))
 }
 
 func (lr *lazyPieceReader) dial() error {
@@ -379,6 +501,9 @@ func (lr *lazyPieceReader) dial() error {
 
 	client, downloader, err := lr.ranger.dial(lr.ctx, lr.offset, lr.length)
 	if err != nil {
+		if lr.ctx.Err() == nil {
+			lr.record(reputation.DialFailure)
+		}
 		return Error.Wrap(err)
 	}
 
@@ -395,6 +520,7 @@ func (lr *lazyPieceReader) dial() error {
 
 	lr.download = downloader
 	lr.client = client
+	lr.reader = testuplink.GetFaults(lr.ctx).PieceReader(lr.ctx, lr.ranger.limit.GetLimit().StorageNodeId, downloader, true)
 
 	return nil
 }
@@ -430,6 +556,14 @@ func (lr *lazyPieceReader) GetHashAndLimit() (*pb.PieceHash, *pb.OrderLimit) {
 }
 
 func (lr *lazyPieceReader) Close() (err error) {
+	// a download closed early still tells how fast the node was.
+	lr.mu.Lock()
+	read := lr.read
+	lr.mu.Unlock()
+	if read > 0 {
+		lr.record(reputation.Success)
+	}
+
 	lr.mu.Lock()
 	defer lr.mu.Unlock()
 	if lr.isClosed {
@@ -0,0 +1,342 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package ecclient
+
+import (
+	"bytes"
+	"context"
+	"hash"
+	"io"
+	"sort"
+	"sync"
+
+	"common/pb"
+	"common/ranger"
+	"common/signing"
+	"common/storx"
+	"uplink/private/eestream"
+	"uplink/private/piecestore"
+	"uplink/private/reputation"
+)
+
+// verifyBufferedStripes is how many stripes each piece may be read ahead of
+// the slowest piece while verifying.
+const verifyBufferedStripes = 16
+
+// SegmentVerification is the result of verifying every piece of a segment.
+type SegmentVerification struct {
+	// Pieces are the results for the pieces that had an order limit.
+	Pieces []PieceVerification
+
+	// Stripes is the number of stripes in the segment.
+	Stripes int
+	// UncheckedStripes is the number of stripes for which exactly the
+	// required number of shares was available, so errors could not be
+	// detected.
+	UncheckedStripes int
+	// UncorrectableStripes is the number of stripes in which corrupt shares
+	// were detected, but there were too few spare shares to find them.
+	UncorrectableStripes int
+	// UnrecoverableStripes is the number of stripes which could not be
+	// reconstructed, because of missing shares.
+	UnrecoverableStripes int
+}
+
+// PieceVerification is the result of verifying a single piece.
+type PieceVerification struct {
+	Num     int
+	NodeID  storx.NodeID
+	Address string
+
+	// Downloaded is the number of bytes of the piece that were downloaded.
+	Downloaded int64
+	// Err is set when the piece could not be downloaded completely.
+	Err error
+
+	// HashSent is true when the node sent the signed hash of the piece, so
+	// it was checked against the downloaded data. Nodes only send it for
+	// repair order limits, not for ordinary downloads.
+	HashSent bool
+	// HashErr is set when the hash did not match the data or it was not
+	// signed by the uplink that uploaded the piece.
+	HashErr error
+
+	// CorruptStripes is the number of stripes in which the erasure share of
+	// the piece disagreed with the shares of the other pieces.
+	CorruptStripes int
+}
+
+// Verify downloads every piece that has an order limit and checks them
+// against each other, stripe by stripe, and against their signed hashes if
+// the nodes send them. The downloaded data is discarded.
+func (ec *ecClient) Verify(ctx context.Context, limits []*pb.AddressedOrderLimit, privateKey storx.PiecePrivateKey, es eestream.ErasureScheme, size int64) (_ SegmentVerification, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if len(limits) != es.TotalCount() {
+		return SegmentVerification{}, Error.New("size of limits slice (%d) does not match total count (%d) of erasure scheme", len(limits), es.TotalCount())
+	}
+
+	paddedSize := calcPadded(size, es.StripeSize())
+	pieceSize := paddedSize / int64(es.RequiredCount())
+	shareSize := es.ErasureShareSize()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var verifiers []*pieceVerifier
+	for i, limit := range limits {
+		if limit == nil {
+			continue
+		}
+		verifiers = append(verifiers, &pieceVerifier{
+			result: PieceVerification{
+				Num:     i,
+				NodeID:  limit.GetLimit().StorageNodeId,
+				Address: limit.GetStorageNodeAddress().GetAddress(),
+			},
+			limit:  limit,
+			shares: make(chan []byte, verifyBufferedStripes),
+		})
+	}
+
+	var wg sync.WaitGroup
+	for _, v := range verifiers {
+		v := v
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v.run(ctx, ec.dialPiecestore, privateKey, pieceSize, shareSize)
+		}()
+	}
+
+	result := SegmentVerification{
+		Stripes: int(pieceSize / int64(shareSize)),
+	}
+
+	open := make(map[int]*pieceVerifier, len(verifiers))
+	for _, v := range verifiers {
+		open[v.result.Num] = v
+	}
+
+	stripe := make(map[int][]byte, len(verifiers))
+	for s := 0; s < result.Stripes; s++ {
+		for num := range stripe {
+			delete(stripe, num)
+		}
+		for num, v := range open {
+			share, ok := <-v.shares
+			if !ok {
+				delete(open, num)
+				continue
+			}
+			stripe[num] = share
+		}
+
+		for _, num := range result.checkStripe(es, stripe) {
+			open[num].result.CorruptStripes++
+		}
+	}
+
+	wg.Wait()
+
+	for _, v := range verifiers {
+		result.Pieces = append(result.Pieces, v.result)
+	}
+	return result, nil
+}
+
+// checkStripe counts the stripe in the result and returns the numbers of its
+// shares that were found to be corrupt.
+func (result *SegmentVerification) checkStripe(es eestream.ErasureScheme, stripe map[int][]byte) []int {
+	switch {
+	case len(stripe) < es.RequiredCount():
+		result.UnrecoverableStripes++
+	case len(stripe) == es.RequiredCount():
+		result.UncheckedStripes++
+	default:
+		corrupt, err := eestream.FindCorruptShares(es, stripe)
+		switch {
+		case eestream.ErrUncorrectable.Has(err):
+			result.UncorrectableStripes++
+		case err != nil:
+			result.UnrecoverableStripes++
+		default:
+			return corrupt
+		}
+	}
+	return nil
+}
+
+// pieceVerifier downloads a single piece, one erasure share at a time.
+type pieceVerifier struct {
+	result PieceVerification
+	limit  *pb.AddressedOrderLimit
+	shares chan []byte
+	hasher hash.Hash
+}
+
+func (v *pieceVerifier) run(ctx context.Context, dial dialPiecestoreFunc, privateKey storx.PiecePrivateKey, pieceSize int64, shareSize int) {
+	defer close(v.shares)
+
+	client, err := dial(ctx, limitToNodeURL(v.limit))
+	if err != nil {
+		v.result.Err = Error.Wrap(err)
+		return
+	}
+	defer func() { _ = client.Close() }()
+
+	download, err := client.Download(ctx, v.limit.GetLimit(), privateKey, 0, pieceSize)
+	if err != nil {
+		v.result.Err = Error.Wrap(err)
+		return
+	}
+	defer func() { _ = download.Close() }()
+
+	// the data is always hashed, so that the hash can be checked whenever
+	// the node sends it. Nodes that send it do so with the first chunk.
+	algorithm := piecestore.GetPieceHashAlgo(ctx)
+	for v.result.Downloaded < pieceSize {
+		share := make([]byte, shareSize)
+		if _, err := io.ReadFull(download, share); err != nil {
+			v.result.Err = Error.Wrap(err)
+			return
+		}
+
+		if v.hasher == nil {
+			if pieceHash, _ := download.GetHashAndLimit(); pieceHash != nil {
+				algorithm = pieceHash.HashAlgorithm
+			}
+			v.hasher = pb.NewHashFromAlgorithm(algorithm)
+		}
+		_, _ = v.hasher.Write(share)
+		v.result.Downloaded += int64(shareSize)
+
+		select {
+		case v.shares <- share:
+		case <-ctx.Done():
+			v.result.Err = Error.Wrap(ctx.Err())
+			return
+		}
+	}
+
+	// closing waits for the rest of the responses, so a hash sent after
+	// the data is seen as well.
+	if err := download.Close(); err != nil {
+		v.result.Err = Error.Wrap(err)
+		return
+	}
+
+	pieceHash, originLimit := download.GetHashAndLimit()
+	if pieceHash == nil {
+		return
+	}
+	if v.hasher == nil {
+		// the piece is empty.
+		v.hasher = pb.NewHashFromAlgorithm(algorithm)
+	}
+	v.result.HashSent = true
+	if pieceHash.HashAlgorithm != algorithm {
+		v.result.HashErr = Error.New("hash algorithm changed from %s to %s", algorithm, pieceHash.HashAlgorithm)
+		return
+	}
+	v.result.HashErr = verifyPieceHash(ctx, v.limit.GetLimit(), pieceHash, originLimit, v.hasher.Sum(nil))
+}
+
+// verifyPieceHash checks that the piece hash sent by a node matches the
+// downloaded data and is signed by the uplink that uploaded the piece.
+func verifyPieceHash(ctx context.Context, limit *pb.OrderLimit, pieceHash *pb.PieceHash, originLimit *pb.OrderLimit, actual []byte) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if originLimit == nil {
+		return Error.New("missing original order limit")
+	}
+	if pieceHash.PieceId != limit.PieceId {
+		return Error.New("piece id changed")
+	}
+	if !bytes.Equal(pieceHash.Hash, actual) {
+		return Error.New("hashes don't match")
+	}
+	if err := signing.VerifyUplinkPieceHashSignature(ctx, originLimit.UplinkPublicKey, pieceHash); err != nil {
+		return Error.New("invalid hash signature: %v", err)
+	}
+	return nil
+}
+
+// CorruptPiece is a piece that was found to be corrupt while downloading.
+type CorruptPiece struct {
+	Num     int
+	NodeID  storx.NodeID
+	PieceID storx.PieceID
+	// Stripes is the number of stripes in which the piece was corrupt.
+	Stripes int
+}
+
+// CorruptPieces collects the pieces found to be corrupt, and corrected,
+// while downloading with forced error detection. It is safe for concurrent
+// use.
+type CorruptPieces struct {
+	mu     sync.Mutex
+	pieces map[storx.PieceID]*CorruptPiece
+}
+
+// Pieces returns the corrupt pieces found so far.
+func (c *CorruptPieces) Pieces() []CorruptPiece {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pieces := make([]CorruptPiece, 0, len(c.pieces))
+	for _, piece := range c.pieces {
+		pieces = append(pieces, *piece)
+	}
+	sort.Slice(pieces, func(i, k int) bool {
+		return pieces[i].NodeID.Less(pieces[k].NodeID)
+	})
+	return pieces
+}
+
+// add records that the piece of the limit was corrupt in one stripe and
+// returns whether it is the first time it was found.
+func (c *CorruptPieces) add(num int, limit *pb.AddressedOrderLimit) (first bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pieceID := limit.GetLimit().PieceId
+	piece, ok := c.pieces[pieceID]
+	if !ok {
+		if c.pieces == nil {
+			c.pieces = make(map[storx.PieceID]*CorruptPiece)
+		}
+		piece = &CorruptPiece{
+			Num:     num,
+			NodeID:  limit.GetLimit().StorageNodeId,
+			PieceID: pieceID,
+		}
+		c.pieces[pieceID] = piece
+	}
+	piece.Stripes++
+	return !ok
+}
+
+// corruptReportingRanger records the pieces found to be corrupt while
+// decoding ranges of a segment.
+type corruptReportingRanger struct {
+	ranger.Ranger
+	corrupt    *CorruptPieces
+	reputation *reputation.Cache
+	limits     []*pb.AddressedOrderLimit
+}
+
+// Range implements ranger.Ranger.
+func (rr *corruptReportingRanger) Range(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	ctx = eestream.WithCorruptShareHandler(ctx, func(num int) {
+		if num < 0 || num >= len(rr.limits) || rr.limits[num] == nil {
+			return
+		}
+		if rr.corrupt.add(num, rr.limits[num]) {
+			mon.Counter("download_corrupt_pieces").Inc(1)
+			rr.reputation.Record(rr.limits[num].GetLimit().StorageNodeId, reputation.Failure, 0, 0)
+		}
+	})
+	return rr.Ranger.Range(ctx, offset, length)
+}
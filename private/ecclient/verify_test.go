@@ -0,0 +1,100 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package ecclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vivint/infectious"
+
+	"common/pb"
+	"common/pkcrypto"
+	"common/signing"
+	"common/storx"
+	"common/testcontext"
+	"common/testrand"
+	"uplink/private/eestream"
+)
+
+func TestVerifyPieceHash(t *testing.T) {
+	ctx := testcontext.New(t)
+
+	publicKey, privateKey, err := storx.NewPieceKey()
+	require.NoError(t, err)
+	_, otherKey, err := storx.NewPieceKey()
+	require.NoError(t, err)
+
+	pieceID := testrand.PieceID()
+	data := testrand.BytesInt(1024)
+	originLimit := &pb.OrderLimit{PieceId: pieceID, UplinkPublicKey: publicKey}
+	limit := &pb.OrderLimit{PieceId: pieceID, Action: pb.PieceAction_GET_REPAIR}
+
+	sign := func(key storx.PiecePrivateKey, pieceID storx.PieceID) *pb.PieceHash {
+		hash, err := signing.SignUplinkPieceHash(ctx, key, &pb.PieceHash{
+			PieceId:   pieceID,
+			Hash:      pkcrypto.SHA256Hash(data),
+			PieceSize: int64(len(data)),
+			Timestamp: time.Now(),
+		})
+		require.NoError(t, err)
+		return hash
+	}
+
+	actual := pkcrypto.SHA256Hash(data)
+	require.NoError(t, verifyPieceHash(ctx, limit, sign(privateKey, pieceID), originLimit, actual))
+
+	require.Error(t, verifyPieceHash(ctx, limit, sign(privateKey, pieceID), nil, actual))
+	require.Error(t, verifyPieceHash(ctx, limit, sign(privateKey, testrand.PieceID()), originLimit, actual))
+	require.Error(t, verifyPieceHash(ctx, limit, sign(otherKey, pieceID), originLimit, actual))
+	require.Error(t, verifyPieceHash(ctx, limit, sign(privateKey, pieceID), originLimit, pkcrypto.SHA256Hash(data[1:])))
+}
+
+func TestCheckStripe(t *testing.T) {
+	fc, err := infectious.NewFEC(2, 4)
+	require.NoError(t, err)
+	es := eestream.NewRSScheme(fc, 16)
+
+	data := testrand.BytesInt(es.StripeSize())
+	stripe := func(nums ...int) map[int][]byte {
+		stripe := make(map[int][]byte)
+		require.NoError(t, es.Encode(data, func(num int, share []byte) {
+			stripe[num] = append([]byte(nil), share...)
+		}))
+		for num := range stripe {
+			keep := false
+			for _, n := range nums {
+				keep = keep || n == num
+			}
+			if !keep {
+				delete(stripe, num)
+			}
+		}
+		return stripe
+	}
+
+	var result SegmentVerification
+
+	require.Empty(t, result.checkStripe(es, stripe(0, 1, 2, 3)))
+	require.Equal(t, SegmentVerification{}, result)
+
+	// two spare shares find the corrupt one.
+	corrupt := stripe(0, 1, 2, 3)
+	corrupt[1][0] ^= 0xFF
+	require.Equal(t, []int{1}, result.checkStripe(es, corrupt))
+	require.Equal(t, SegmentVerification{}, result)
+
+	// a single spare share only detects it.
+	corrupt = stripe(0, 1, 2)
+	corrupt[1][0] ^= 0xFF
+	require.Empty(t, result.checkStripe(es, corrupt))
+	require.Equal(t, SegmentVerification{UncorrectableStripes: 1}, result)
+
+	require.Empty(t, result.checkStripe(es, stripe(0, 3)))
+	require.Equal(t, SegmentVerification{UncorrectableStripes: 1, UncheckedStripes: 1}, result)
+
+	require.Empty(t, result.checkStripe(es, stripe(2)))
+	require.Equal(t, SegmentVerification{UncorrectableStripes: 1, UncheckedStripes: 1, UnrecoverableStripes: 1}, result)
+}
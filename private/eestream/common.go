@@ -9,3 +9,7 @@ import (
 
 // Error is the default eestream errs class.
 var Error = errs.Class("eestream")
+
+// ErrUncorrectable is the errs class of the error FindCorruptShares returns
+// when a stripe has corrupt shares, but too few spare shares to find them.
+var ErrUncorrectable = errs.Class("uncorrectable erasure shares")
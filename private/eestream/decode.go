@@ -41,6 +41,12 @@ type decodedReader struct {
 // if forceErrorDetection is set to true then k+1 pieces will be always
 // required for decoding, so corrupted pieces can be detected.
 func DecodeReaders2(ctx context.Context, cancel func(), rs map[int]io.ReadCloser, es ErasureScheme, expectedSize int64, mbm int, forceErrorDetection bool) io.ReadCloser {
+	return DecodeHedgedReaders(ctx, cancel, rs, es, expectedSize, mbm, forceErrorDetection, nil)
+}
+
+// DecodeHedgedReaders is like DecodeReaders2 but, if policy is not nil, reads
+// from the readers according to the hedging policy.
+func DecodeHedgedReaders(ctx context.Context, cancel func(), rs map[int]io.ReadCloser, es ErasureScheme, expectedSize int64, mbm int, forceErrorDetection bool, policy *HedgePolicy) io.ReadCloser {
 	defer mon.Task()(&ctx)(nil)
 	if expectedSize < 0 {
 		return readcloser.FatalReadCloser(Error.New("negative expected size"))
@@ -56,7 +62,7 @@ func DecodeReaders2(ctx context.Context, cancel func(), rs map[int]io.ReadCloser
 	dr := &decodedReader{
 		readers:         rs,
 		scheme:          es,
-		stripeReader:    NewStripeReader(rs, es, mbm, forceErrorDetection),
+		stripeReader:    NewHedgedStripeReader(rs, es, mbm, forceErrorDetection, policy),
 		outbuf:          make([]byte, 0, es.StripeSize()),
 		expectedStripes: expectedSize / int64(es.StripeSize()),
 	}
@@ -150,6 +156,7 @@ type decodedRanger struct {
 	inSize              int64
 	mbm                 int // max buffer memory
 	forceErrorDetection bool
+	hedge               *HedgePolicy
 }
 
 // Decode takes a map of Rangers and an ErasureScheme and returns a combined
@@ -161,6 +168,12 @@ type decodedRanger struct {
 // if forceErrorDetection is set to true then k+1 pieces will be always
 // required for decoding, so corrupted pieces can be detected.
 func Decode(rrs map[int]ranger.Ranger, es ErasureScheme, mbm int, forceErrorDetection bool) (ranger.Ranger, error) {
+	return DecodeHedged(rrs, es, mbm, forceErrorDetection, nil)
+}
+
+// DecodeHedged is like Decode but, if policy is not nil, the returned Ranger
+// reads from the rangers according to the hedging policy.
+func DecodeHedged(rrs map[int]ranger.Ranger, es ErasureScheme, mbm int, forceErrorDetection bool, policy *HedgePolicy) (ranger.Ranger, error) {
 	if err := checkMBM(mbm); err != nil {
 		return nil, err
 	}
@@ -189,6 +202,7 @@ func Decode(rrs map[int]ranger.Ranger, es ErasureScheme, mbm int, forceErrorDete
 		inSize:              size,
 		mbm:                 mbm,
 		forceErrorDetection: forceErrorDetection,
+		hedge:               policy,
 	}, nil
 }
 
@@ -217,7 +231,7 @@ func (dr *decodedRanger) Range(ctx context.Context, offset, length int64) (_ io.
 	}
 
 	// decode from all those ranges
-	r := DecodeReaders2(ctx, cancel, readers, dr.es, blockCount*int64(dr.es.StripeSize()), dr.mbm, dr.forceErrorDetection)
+	r := DecodeHedgedReaders(ctx, cancel, readers, dr.es, blockCount*int64(dr.es.StripeSize()), dr.mbm, dr.forceErrorDetection, dr.hedge)
 	// offset might start a few bytes in, potentially discard the initial bytes
 	_, err = io.CopyN(io.Discard, r, offset-firstBlock*int64(dr.es.StripeSize()))
 	if err != nil {
@@ -0,0 +1,330 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package eestream
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// errHedgeCanceled is the error of readers that were not needed.
+var errHedgeCanceled = Error.New("piece not needed")
+
+const (
+	// defaultHedgePercentile is used when HedgePolicy.Percentile is not set.
+	defaultHedgePercentile = 0.9
+	// defaultHedgeDelay is used when there are not enough observed latencies
+	// and HedgePolicy.MinimumDelay is not set.
+	defaultHedgeDelay = 500 * time.Millisecond
+	// defaultLatencySamples is the number of samples kept by a
+	// LatencyTracker when no size is given.
+	defaultLatencySamples = 1000
+	// minLatencySamples is the number of samples needed before a
+	// LatencyTracker reports percentiles.
+	minLatencySamples = 10
+)
+
+// HedgePolicy controls hedged reads while decoding. Only some of the pieces
+// are read at first, and more are started when the ones being read are slower
+// than usual to produce data or fail. Once enough pieces are producing data,
+// the started pieces that are not are canceled. The pieces that were never
+// started are kept in reserve until the segment is read, and are started if
+// one of the pieces producing data fails.
+type HedgePolicy struct {
+	// Margin is the number of pieces beyond the required count that are
+	// read immediately.
+	Margin int
+
+	// Percentile of the observed time to first byte after which a piece
+	// that hasn't produced any data is considered slow and an extra piece is
+	// started in its place. If zero, 0.9 is used.
+	Percentile float64
+
+	// MinimumDelay is the shortest time to wait before a piece is considered
+	// slow. It is also used until enough latencies are observed, falling back
+	// to 500ms if zero.
+	MinimumDelay time.Duration
+
+	// Latencies records the time to first byte of pieces. It can be shared
+	// across downloads so that new downloads benefit from earlier
+	// observations. If nil, each download only observes its own pieces.
+	Latencies *LatencyTracker
+}
+
+// LatencyTracker keeps a window of recently observed latencies.
+type LatencyTracker struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+// NewLatencyTracker returns a LatencyTracker keeping the last size
+// observations. If size is not positive, a default size is used.
+func NewLatencyTracker(size int) *LatencyTracker {
+	if size <= 0 {
+		size = defaultLatencySamples
+	}
+	return &LatencyTracker{
+		samples: make([]time.Duration, 0, size),
+	}
+}
+
+// Observe records a latency.
+func (t *LatencyTracker) Observe(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.samples) < cap(t.samples) {
+		t.samples = append(t.samples, d)
+		return
+	}
+	t.samples[t.next] = d
+	t.next = (t.next + 1) % len(t.samples)
+}
+
+// Percentile returns the p-th percentile, between 0 and 1, of the observed
+// latencies. It returns false if there are not enough observations.
+func (t *LatencyTracker) Percentile(p float64) (time.Duration, bool) {
+	t.mu.Lock()
+	if len(t.samples) < minLatencySamples {
+		t.mu.Unlock()
+		return 0, false
+	}
+	samples := append([]time.Duration(nil), t.samples...)
+	t.mu.Unlock()
+
+	sort.Slice(samples, func(i, k int) bool { return samples[i] < samples[k] })
+
+	idx := int(p * float64(len(samples)))
+	switch {
+	case idx < 0:
+		idx = 0
+	case idx >= len(samples):
+		idx = len(samples) - 1
+	}
+	return samples[idx], true
+}
+
+// hedger decides when the readers of a StripeReader are started and
+// canceled.
+type hedger struct {
+	policy    HedgePolicy
+	latencies *LatencyTracker
+	need      int
+	start     func(i int, r io.Reader)
+	cancel    func(i int, r io.Closer)
+
+	mu       sync.Mutex
+	readers  map[int]io.ReadCloser
+	reserve  []int // readers not started yet, in the order they'll start
+	started  map[int]time.Time
+	flowing  map[int]bool
+	failed   map[int]bool
+	canceled map[int]bool
+
+	wake chan struct{}
+	done chan struct{}
+}
+
+func newHedger(policy HedgePolicy, rs map[int]io.ReadCloser, need int, start func(i int, r io.Reader), cancel func(i int, r io.Closer)) *hedger {
+	if policy.Percentile <= 0 {
+		policy.Percentile = defaultHedgePercentile
+	}
+	latencies := policy.Latencies
+	if latencies == nil {
+		latencies = NewLatencyTracker(0)
+	}
+
+	order := make([]int, 0, len(rs))
+	for i := range rs {
+		order = append(order, i)
+	}
+	sort.Ints(order)
+	rand.Shuffle(len(order), func(i, k int) { order[i], order[k] = order[k], order[i] })
+
+	return &hedger{
+		policy:    policy,
+		latencies: latencies,
+		need:      need,
+		start:     start,
+		cancel:    cancel,
+
+		readers:  rs,
+		reserve:  order,
+		started:  make(map[int]time.Time, len(rs)),
+		flowing:  make(map[int]bool, len(rs)),
+		failed:   make(map[int]bool, len(rs)),
+		canceled: make(map[int]bool, len(rs)),
+
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+}
+
+// run starts and cancels readers until stop is called.
+func (h *hedger) run() {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		next := h.step(time.Now())
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		if next > 0 {
+			timer.Reset(next)
+		}
+
+		select {
+		case <-h.wake:
+		case <-timer.C:
+		case <-h.done:
+			return
+		}
+	}
+}
+
+func (h *hedger) stop() { close(h.done) }
+
+// step starts and cancels readers as needed and returns how long until the
+// next started reader becomes slow, or 0 if none will.
+func (h *hedger) step(now time.Time) (next time.Duration) {
+	delay := h.delay()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	flowing := len(h.flowing)
+
+	// once enough readers are producing data, the rest are only using
+	// resources, so cancel them. readers that were never started stay in
+	// reserve, parked, in case one of the flowing readers fails.
+	if flowing >= h.need {
+		for i := range h.started {
+			if !h.flowing[i] && !h.failed[i] && !h.canceled[i] {
+				h.canceled[i] = true
+				mon.Counter("download_hedge_canceled").Inc(1)
+				go h.cancel(i, h.readers[i])
+			}
+		}
+		return 0
+	}
+
+	healthy := flowing
+	for i, started := range h.started {
+		if h.flowing[i] || h.failed[i] || h.canceled[i] {
+			continue
+		}
+		if wait := delay - now.Sub(started); wait > 0 {
+			healthy++
+			if next == 0 || wait < next {
+				next = wait
+			}
+		}
+	}
+
+	for healthy < h.need+h.policy.Margin && len(h.reserve) > 0 {
+		i := h.reserve[0]
+		h.reserve = h.reserve[1:]
+
+		if len(h.started) >= h.need+h.policy.Margin {
+			mon.Counter("download_hedge_started").Inc(1)
+		}
+
+		h.started[i] = now
+		healthy++
+		if next == 0 || delay < next {
+			next = delay
+		}
+
+		h.start(i, &hedgedReader{hedger: h, index: i, started: now, reader: h.readers[i]})
+	}
+
+	return next
+}
+
+// parked returns the number of readers in reserve that won't be started
+// unless one of the flowing readers fails, so that the StripeReader doesn't
+// wait on them.
+func (h *hedger) parked() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.flowing) < h.need {
+		return 0
+	}
+	return len(h.reserve)
+}
+
+// delay returns how long a started reader can go without producing data
+// before it is considered slow.
+func (h *hedger) delay() time.Duration {
+	delay, ok := h.latencies.Percentile(h.policy.Percentile)
+	if !ok {
+		delay = h.policy.MinimumDelay
+		if delay <= 0 {
+			delay = defaultHedgeDelay
+		}
+	}
+	if delay < h.policy.MinimumDelay {
+		delay = h.policy.MinimumDelay
+	}
+	return delay
+}
+
+func (h *hedger) signal() {
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (h *hedger) markFlowing(i int, latency time.Duration) {
+	h.latencies.Observe(latency)
+
+	h.mu.Lock()
+	h.flowing[i] = true
+	h.mu.Unlock()
+
+	h.signal()
+}
+
+func (h *hedger) markFailed(i int) {
+	h.mu.Lock()
+	delete(h.flowing, i)
+	h.failed[i] = true
+	h.mu.Unlock()
+
+	h.signal()
+}
+
+// hedgedReader reports to the hedger when the wrapped reader produces its
+// first bytes and when it fails.
+type hedgedReader struct {
+	hedger  *hedger
+	index   int
+	started time.Time
+	reader  io.Reader
+	first   bool
+}
+
+func (r *hedgedReader) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	if n > 0 && !r.first {
+		r.first = true
+		r.hedger.markFlowing(r.index, time.Since(r.started))
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		r.hedger.markFailed(r.index)
+	}
+	return n, err
+}
@@ -0,0 +1,197 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package eestream_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vivint/infectious"
+
+	"common/testrand"
+	"uplink/private/eestream"
+)
+
+func TestLatencyTracker(t *testing.T) {
+	tracker := eestream.NewLatencyTracker(20)
+
+	_, ok := tracker.Percentile(0.5)
+	require.False(t, ok)
+
+	for i := 1; i <= 30; i++ {
+		tracker.Observe(time.Duration(i) * time.Millisecond)
+	}
+
+	// only the last 20 observations are kept.
+	p, ok := tracker.Percentile(0)
+	require.True(t, ok)
+	require.Equal(t, 11*time.Millisecond, p)
+
+	p, ok = tracker.Percentile(0.5)
+	require.True(t, ok)
+	require.Equal(t, 21*time.Millisecond, p)
+
+	p, ok = tracker.Percentile(1)
+	require.True(t, ok)
+	require.Equal(t, 30*time.Millisecond, p)
+}
+
+func TestDecodeHedgedSlowReaders(t *testing.T) {
+	ctx := context.Background()
+	data := testrand.Bytes(10 * 1024)
+
+	rs, pieces := encodeForHedging(t, data, 10, 20)
+
+	readerMap := make(map[int]io.ReadCloser, len(pieces))
+	for i, piece := range pieces {
+		readerMap[i] = io.NopCloser(bytes.NewReader(piece))
+	}
+	// a third of the readers never produce data in time.
+	for i := 0; i < len(pieces); i += 3 {
+		readerMap[i] = io.NopCloser(SlowReader(bytes.NewReader(pieces[i]), 5*time.Second))
+	}
+
+	policy := &eestream.HedgePolicy{
+		Margin:       2,
+		MinimumDelay: 10 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	decoder := eestream.DecodeHedgedReaders(ctx, cancel, readerMap, rs, int64(len(data)), 0, false, policy)
+	defer func() { require.NoError(t, decoder.Close()) }()
+
+	start := time.Now()
+	got, err := io.ReadAll(decoder)
+	require.NoError(t, err)
+	require.Equal(t, data, got)
+	require.Less(t, time.Since(start), 5*time.Second, "waited for slow reader")
+}
+
+func TestDecodeHedgedFailingReaders(t *testing.T) {
+	ctx := context.Background()
+	data := testrand.Bytes(10 * 1024)
+
+	rs, pieces := encodeForHedging(t, data, 10, 20)
+
+	readerMap := make(map[int]io.ReadCloser, len(pieces))
+	for i, piece := range pieces {
+		readerMap[i] = io.NopCloser(bytes.NewReader(piece))
+	}
+	// most of the readers fail, so the reserves must be started.
+	for i := 0; i < 9; i++ {
+		readerMap[i] = io.NopCloser(&failingReader{})
+	}
+
+	policy := &eestream.HedgePolicy{
+		MinimumDelay: time.Minute,
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	decoder := eestream.DecodeHedgedReaders(ctx, cancel, readerMap, rs, int64(len(data)), 0, false, policy)
+	defer func() { require.NoError(t, decoder.Close()) }()
+
+	got, err := io.ReadAll(decoder)
+	require.NoError(t, err)
+	require.Equal(t, data, got)
+}
+
+func TestDecodeHedgedFlowingReadersFail(t *testing.T) {
+	ctx := context.Background()
+	data := testrand.Bytes(100 * 1024)
+
+	rs, pieces := encodeForHedging(t, data, 10, 20)
+
+	// half of the readers fail in the middle of their piece, after the
+	// pieces in reserve were parked, so the reserves must be restarted.
+	readerMap := make(map[int]io.ReadCloser, len(pieces))
+	for i, piece := range pieces {
+		var r io.Reader = bytes.NewReader(piece)
+		if i%2 == 0 {
+			r = io.MultiReader(io.LimitReader(r, int64(len(piece)/2)), &failingReader{})
+		}
+		readerMap[i] = io.NopCloser(r)
+	}
+
+	policy := &eestream.HedgePolicy{
+		MinimumDelay: time.Minute,
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	decoder := eestream.DecodeHedgedReaders(ctx, cancel, readerMap, rs, int64(len(data)), 0, false, policy)
+	defer func() { require.NoError(t, decoder.Close()) }()
+
+	got, err := io.ReadAll(decoder)
+	require.NoError(t, err)
+	require.Equal(t, data, got)
+}
+
+func BenchmarkDecodeSlowReaders(b *testing.B) {
+	data := testrand.Bytes(64 * 1024)
+	rs, pieces := encodeForHedging(b, data, 20, 40)
+
+	policies := []struct {
+		name   string
+		policy *eestream.HedgePolicy
+	}{
+		{"no-hedging", nil},
+		{"hedging", &eestream.HedgePolicy{
+			Margin:       2,
+			MinimumDelay: 5 * time.Millisecond,
+			Latencies:    eestream.NewLatencyTracker(0),
+		}},
+	}
+
+	for _, slow := range []int{0, 5, 15} {
+		for _, p := range policies {
+			b.Run(fmt.Sprintf("slow=%d/%s", slow, p.name), func(b *testing.B) {
+				ctx := context.Background()
+				b.SetBytes(int64(len(data)))
+				b.ReportAllocs()
+
+				for n := 0; n < b.N; n++ {
+					readerMap := make(map[int]io.ReadCloser, len(pieces))
+					for i, piece := range pieces {
+						var r io.Reader = bytes.NewReader(piece)
+						if i < slow {
+							r = SlowReader(r, 50*time.Millisecond)
+						}
+						readerMap[i] = io.NopCloser(r)
+					}
+
+					ctx, cancel := context.WithCancel(ctx)
+					decoder := eestream.DecodeHedgedReaders(ctx, cancel, readerMap, rs, int64(len(data)), 0, false, p.policy)
+					if _, err := io.Copy(io.Discard, decoder); err != nil {
+						b.Fatal(err)
+					}
+					_ = decoder.Close()
+				}
+			})
+		}
+	}
+}
+
+func encodeForHedging(t testing.TB, data []byte, required, total int) (eestream.RedundancyStrategy, [][]byte) {
+	fc, err := infectious.NewFEC(required, total)
+	require.NoError(t, err)
+
+	rs, err := eestream.NewRedundancyStrategy(eestream.NewRSScheme(fc, 1024), 0, 0)
+	require.NoError(t, err)
+
+	readers, err := eestream.EncodeReader2(context.Background(), bytes.NewReader(data), rs)
+	require.NoError(t, err)
+
+	pieces, err := readAll(readers)
+	require.NoError(t, err)
+
+	return rs, pieces
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, io.ErrUnexpectedEOF }
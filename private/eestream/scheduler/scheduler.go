@@ -12,8 +12,9 @@ import (
 // with the property that earlier acquired handles get preference for
 // new resources over later acquired handles.
 type Scheduler struct {
-	opts Options
-	sema chan struct{}
+	opts   Options
+	sema   chan struct{}
+	shared *sharedMember
 
 	mu      sync.Mutex
 	prio    int
@@ -23,17 +24,50 @@ type Scheduler struct {
 // Options controls the parameters of the Scheduler.
 type Options struct {
 	MaximumConcurrent int // number of maximum concurrent resources
+
+	// Shared, if not nil, is a limit that resources are also acquired from,
+	// shared with other Schedulers.
+	Shared *Shared
+
+	// Priority is the class of work used when acquiring resources from
+	// Shared.
+	Priority Priority
 }
 
 // New constructs a new Scheduler.
 func New(opts Options) *Scheduler {
-	return &Scheduler{
+	s := &Scheduler{
 		opts: opts,
 		sema: make(chan struct{}, opts.MaximumConcurrent),
 	}
+	if opts.Shared != nil {
+		s.shared = opts.Shared.join(opts.Priority)
+	}
+	return s
 }
 
 func (s *Scheduler) resourceGet(ctx context.Context, h *handle) bool {
+	if !s.localGet(ctx, h) {
+		return false
+	}
+	if s.shared == nil {
+		return true
+	}
+	if !s.opts.Shared.acquire(ctx, s.shared) {
+		<-s.sema
+		return false
+	}
+	return true
+}
+
+func (s *Scheduler) resourceDone() {
+	if s.shared != nil {
+		s.opts.Shared.release(s.shared)
+	}
+	<-s.sema
+}
+
+func (s *Scheduler) localGet(ctx context.Context, h *handle) bool {
 	// ensure that we don't return new resources if the context is
 	// already canceled.
 	if ctx.Err() != nil {
@@ -175,7 +209,7 @@ type Resource interface {
 type resource handle
 
 func (r *resource) Done() {
-	<-(*handle)(r).sched.sema
+	(*handle)(r).sched.resourceDone()
 	(*handle)(r).wg.Done()
 }
 
@@ -0,0 +1,214 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/spacemonkeygo/monkit/v3"
+)
+
+var mon = monkit.Package()
+
+// Priority is the class of work a Scheduler does when it acquires resources
+// from a Shared limit. Lower values are served first.
+type Priority int
+
+const (
+	// PriorityInteractive is for work that someone is waiting on.
+	PriorityInteractive Priority = iota
+	// PriorityBackground is for work that can make way for interactive work.
+	PriorityBackground
+
+	numPriorities
+)
+
+// SharedOptions controls the parameters of a Shared limit.
+type SharedOptions struct {
+	// MaximumConcurrent is the number of resources that can be held at once
+	// across all of the Schedulers using the Shared limit.
+	MaximumConcurrent int
+
+	// StarvationTimeout is how long a waiter can wait before it is served
+	// ahead of every other waiter, regardless of its priority. Zero means
+	// waiters are always served by priority.
+	StarvationTimeout time.Duration
+}
+
+// Shared limits the number of resources held across many Schedulers. When
+// resources are scarce they are handed out by priority first and then to the
+// Scheduler holding the fewest resources, so that concurrent Schedulers get a
+// fair share of the limit.
+type Shared struct {
+	opts SharedOptions
+
+	mu      sync.Mutex
+	used    int
+	waiters []*sharedWaiter
+}
+
+// SharedStats describes the state of a Shared limit.
+type SharedStats struct {
+	// Active is the number of resources currently held.
+	Active int
+	// Waiting is the number of waiters by priority.
+	Waiting [numPriorities]int
+}
+
+type sharedMember struct {
+	prio Priority
+	held int // protected by Shared.mu
+}
+
+type sharedWaiter struct {
+	member *sharedMember
+	since  time.Time
+	sig    chan struct{}
+}
+
+// NewShared constructs a new Shared limit. It returns nil, which does not
+// limit, if the maximum concurrency is not positive.
+func NewShared(opts SharedOptions) *Shared {
+	if opts.MaximumConcurrent <= 0 {
+		return nil
+	}
+	return &Shared{opts: opts}
+}
+
+// Stats returns the current number of held resources and waiters.
+func (s *Shared) Stats() (stats SharedStats) {
+	if s == nil {
+		return stats
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats.Active = s.used
+	for _, w := range s.waiters {
+		stats.Waiting[w.member.prio]++
+	}
+	return stats
+}
+
+func (s *Shared) join(prio Priority) *sharedMember {
+	if prio < 0 || prio >= numPriorities {
+		prio = PriorityBackground
+	}
+	return &sharedMember{prio: prio}
+}
+
+func (s *Shared) acquire(ctx context.Context, m *sharedMember) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	s.mu.Lock()
+	if s.used < s.opts.MaximumConcurrent && len(s.waiters) == 0 {
+		s.used++
+		m.held++
+		s.mu.Unlock()
+		return true
+	}
+
+	w := &sharedWaiter{
+		member: m,
+		since:  time.Now(),
+		sig:    make(chan struct{}, 1),
+	}
+	s.waiters = append(s.waiters, w)
+	mon.IntVal("shared_scheduler_queue_depth").Observe(int64(len(s.waiters)))
+	s.mu.Unlock()
+
+	select {
+	case <-w.sig:
+		mon.DurationVal("shared_scheduler_wait").Observe(time.Since(w.since))
+		return true
+
+	case <-ctx.Done():
+		// if we couldn't be found, then someone has already handed us the
+		// resource, so we must accept it.
+		s.mu.Lock()
+		removed := s.removeWaiter(w)
+		s.mu.Unlock()
+
+		if removed {
+			return false
+		}
+
+		<-w.sig
+		return true
+	}
+}
+
+func (s *Shared) release(m *sharedMember) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m.held--
+
+	w := s.bestWaiter(time.Now())
+	if w == nil {
+		s.used--
+		return
+	}
+
+	// hand the resource directly to the waiter without releasing it so that
+	// new arrivals can't take it first.
+	_ = s.removeWaiter(w)
+	w.member.held++
+	w.sig <- struct{}{}
+}
+
+// bestWaiter returns the waiter that should be given the next resource. It
+// must be called with the mutex held.
+func (s *Shared) bestWaiter(now time.Time) (best *sharedWaiter) {
+	for _, w := range s.waiters {
+		if s.better(w, best, now) {
+			best = w
+		}
+	}
+	return best
+}
+
+// better returns true if a should be served before b.
+func (s *Shared) better(a, b *sharedWaiter, now time.Time) bool {
+	if b == nil {
+		return true
+	}
+
+	if timeout := s.opts.StarvationTimeout; timeout > 0 {
+		aStarved := now.Sub(a.since) >= timeout
+		bStarved := now.Sub(b.since) >= timeout
+		if aStarved != bStarved {
+			return aStarved
+		}
+		if aStarved {
+			return a.since.Before(b.since)
+		}
+	}
+
+	switch {
+	case a.member.prio != b.member.prio:
+		return a.member.prio < b.member.prio
+	case a.member.held != b.member.held:
+		return a.member.held < b.member.held
+	default:
+		return a.since.Before(b.since)
+	}
+}
+
+// removeWaiter removes w from the waiters and reports if it was found. It
+// must be called with the mutex held.
+func (s *Shared) removeWaiter(w *sharedWaiter) bool {
+	for i, x := range s.waiters {
+		if x == w {
+			s.waiters = append(s.waiters[:i], s.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
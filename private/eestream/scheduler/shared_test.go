@@ -0,0 +1,129 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package scheduler
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShared_Nil(t *testing.T) {
+	require.Nil(t, NewShared(SharedOptions{}))
+
+	var s *Shared
+	require.Zero(t, s.Stats())
+}
+
+// TestShared_Limits checks that the shared limit is respected across schedulers.
+func TestShared_Limits(t *testing.T) {
+	ctx := context.Background()
+
+	shared := NewShared(SharedOptions{MaximumConcurrent: 2})
+	s1 := New(Options{MaximumConcurrent: 10, Shared: shared})
+	s2 := New(Options{MaximumConcurrent: 10, Shared: shared})
+
+	r1, ok := s1.Join().Get(ctx)
+	require.True(t, ok)
+	r2, ok := s2.Join().Get(ctx)
+	require.True(t, ok)
+	require.Equal(t, 2, shared.Stats().Active)
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	_, ok = s1.Join().Get(ctx)
+	require.False(t, ok)
+
+	// the local resource must have been given back.
+	require.Len(t, s1.sema, 1)
+
+	r1.Done()
+	r2.Done()
+	require.Zero(t, shared.Stats().Active)
+	require.Empty(t, s1.sema)
+}
+
+// TestShared_Order checks that waiters are served by priority, then by
+// fewest held resources, unless they are starved.
+func TestShared_Order(t *testing.T) {
+	ctx := context.Background()
+
+	shared := NewShared(SharedOptions{MaximumConcurrent: 2})
+	greedy := New(Options{MaximumConcurrent: 10, Shared: shared})
+	fair := New(Options{MaximumConcurrent: 10, Shared: shared})
+	background := New(Options{MaximumConcurrent: 10, Shared: shared, Priority: PriorityBackground})
+
+	held := []Resource{mustGet(ctx, t, greedy), mustGet(ctx, t, greedy)}
+
+	got := make(chan string, 3)
+	wait := func(name string, s *Scheduler) {
+		r := mustGet(ctx, t, s)
+		got <- name
+		r.Done()
+	}
+
+	go wait("background", background)
+	waitForWaiters(shared, 1)
+	go wait("greedy", greedy)
+	waitForWaiters(shared, 2)
+	go wait("fair", fair)
+	waitForWaiters(shared, 3)
+
+	require.Equal(t, SharedStats{Active: 2, Waiting: [numPriorities]int{2, 1}}, shared.Stats())
+
+	held[0].Done()
+	require.Equal(t, "fair", <-got)
+	require.Equal(t, "greedy", <-got)
+	require.Equal(t, "background", <-got)
+	held[1].Done()
+
+	require.Zero(t, shared.Stats().Active)
+}
+
+func TestShared_Starvation(t *testing.T) {
+	ctx := context.Background()
+
+	shared := NewShared(SharedOptions{MaximumConcurrent: 1, StarvationTimeout: time.Millisecond})
+	interactive := New(Options{MaximumConcurrent: 10, Shared: shared})
+	background := New(Options{MaximumConcurrent: 10, Shared: shared, Priority: PriorityBackground})
+
+	held := mustGet(ctx, t, interactive)
+
+	got := make(chan string, 2)
+	wait := func(name string, s *Scheduler) {
+		r := mustGet(ctx, t, s)
+		got <- name
+		r.Done()
+	}
+
+	go wait("background", background)
+	waitForWaiters(shared, 1)
+	time.Sleep(5 * time.Millisecond)
+	go wait("interactive", interactive)
+	waitForWaiters(shared, 2)
+
+	held.Done()
+	require.Equal(t, "background", <-got)
+	require.Equal(t, "interactive", <-got)
+}
+
+func mustGet(ctx context.Context, t *testing.T, s *Scheduler) Resource {
+	r, ok := s.Join().Get(ctx)
+	require.True(t, ok)
+	return r
+}
+
+func waitForWaiters(s *Shared, n int) {
+	for {
+		stats := s.Stats()
+		if stats.Waiting[PriorityInteractive]+stats.Waiting[PriorityBackground] == n {
+			return
+		}
+		runtime.Gosched()
+	}
+}
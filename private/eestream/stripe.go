@@ -32,11 +32,20 @@ type StripeReader struct {
 	inmap               map[int][]byte
 	errmap              map[int]error
 	forceErrorDetection bool
+	hedger              *hedger
+	snapshot            map[int][]byte
 }
 
 // NewStripeReader creates a new StripeReader from the given readers, erasure
 // scheme and max buffer memory.
 func NewStripeReader(rs map[int]io.ReadCloser, es ErasureScheme, mbm int, forceErrorDetection bool) *StripeReader {
+	return NewHedgedStripeReader(rs, es, mbm, forceErrorDetection, nil)
+}
+
+// NewHedgedStripeReader is like NewStripeReader but, if policy is not nil,
+// only starts reading from as many readers as the policy allows, starting
+// more of them when the ones being read are slow or fail.
+func NewHedgedStripeReader(rs map[int]io.ReadCloser, es ErasureScheme, mbm int, forceErrorDetection bool, policy *HedgePolicy) *StripeReader {
 	readerCount := len(rs)
 
 	r := &StripeReader{
@@ -59,22 +68,50 @@ func NewStripeReader(rs map[int]io.ReadCloser, es ErasureScheme, mbm int, forceE
 	for i := range rs {
 		r.inbufs[i] = make([]byte, es.ErasureShareSize())
 		r.bufs[i] = NewPieceBuffer(make([]byte, bufSize), es.ErasureShareSize(), r.cond)
-		// Kick off a goroutine each reader to be copied into a PieceBuffer.
-		go func(r io.Reader, buf *PieceBuffer) {
-			_, err := io.Copy(buf, r)
-			if err != nil {
-				buf.SetError(err)
-				return
-			}
-			buf.SetError(io.EOF)
-		}(rs[i], r.bufs[i])
 	}
 
+	if policy == nil {
+		for i := range rs {
+			r.startReader(i, rs[i])
+		}
+		return r
+	}
+
+	need := es.RequiredCount()
+	if forceErrorDetection {
+		need++
+	}
+	r.hedger = newHedger(*policy, rs, need, r.startReader, r.cancelReader)
+	go r.hedger.run()
+
 	return r
 }
 
+// startReader kicks off a goroutine copying the reader into its PieceBuffer.
+func (r *StripeReader) startReader(i int, rd io.Reader) {
+	go func(rd io.Reader, buf *PieceBuffer) {
+		_, err := io.Copy(buf, rd)
+		if err != nil {
+			buf.SetError(err)
+			return
+		}
+		buf.SetError(io.EOF)
+	}(rd, r.bufs[i])
+}
+
+// cancelReader closes a reader that is not needed and fails its PieceBuffer,
+// so that ReadStripe doesn't wait on it even if closing doesn't stop it.
+func (r *StripeReader) cancelReader(i int, rd io.Closer) {
+	_ = rd.Close()
+	r.bufs[i].SetError(errHedgeCanceled)
+}
+
 // Close closes the StripeReader and all PieceBuffers.
 func (r *StripeReader) Close() error {
+	if r.hedger != nil {
+		r.hedger.stop()
+	}
+
 	errs := make(chan error, len(r.bufs))
 	for _, buf := range r.bufs {
 		go func(c io.Closer) {
@@ -111,6 +148,7 @@ func (r *StripeReader) ReadStripe(ctx context.Context, num int64, p []byte) (_ [
 			r.cond.Wait()
 		}
 		if r.hasEnoughShares() {
+			report := r.snapshotShares(ctx)
 			out, err := r.scheme.Decode(p, r.inmap)
 			if err != nil {
 				if r.shouldWaitForMore(err) {
@@ -118,9 +156,13 @@ func (r *StripeReader) ReadStripe(ctx context.Context, num int64, p []byte) (_ [
 				}
 				return nil, err
 			}
+			report()
 			return out, nil
 		}
 	}
+	if r.forceErrorDetection && len(r.errmap) == 0 {
+		return nil, Error.New("not enough pieces to detect errors in stripe %d", num)
+	}
 	// could not read enough shares to attempt a decode
 	backcompatMon.Meter("download_stripe_failed_not_enough_pieces_uplink").Mark(1) //mon:locked
 	return nil, r.combineErrs(num)
@@ -154,8 +196,12 @@ func (r *StripeReader) readAvailableShares(ctx context.Context, num int64) (n in
 }
 
 // pendingReaders checks if there are any pending readers to get a share from.
+// Readers parked by the hedger are not pending until they are needed.
 func (r *StripeReader) pendingReaders() bool {
 	goodReaders := r.readerCount - len(r.errmap)
+	if r.hedger != nil {
+		goodReaders -= r.hedger.parked()
+	}
 	return goodReaders >= r.scheme.RequiredCount() && goodReaders > len(r.inmap)
 }
 
@@ -192,3 +238,28 @@ func (r *StripeReader) combineErrs(num int64) error {
 	sort.Strings(errstrings)
 	return Error.New("failed to download stripe %d: %s", num, strings.Join(errstrings, ""))
 }
+
+// snapshotShares copies the shares about to be decoded, when error detection
+// is forced and ctx has a corrupt share handler. The returned func calls the
+// handler for the shares that decoding corrected.
+func (r *StripeReader) snapshotShares(ctx context.Context) (report func()) {
+	handler := getCorruptShareHandler(ctx)
+	if !r.forceErrorDetection || handler == nil {
+		return func() {}
+	}
+
+	if r.snapshot == nil {
+		r.snapshot = make(map[int][]byte, r.readerCount)
+	}
+	for i, share := range r.inmap {
+		r.snapshot[i] = append(r.snapshot[i][:0], share...)
+	}
+
+	return func() {
+		for i, share := range r.inmap {
+			if string(share) != string(r.snapshot[i]) {
+				handler(i)
+			}
+		}
+	}
+}
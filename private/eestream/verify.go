@@ -0,0 +1,95 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package eestream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/vivint/infectious"
+)
+
+type corruptShareHandlerKey struct{}
+
+// WithCorruptShareHandler returns a context that makes decoding call fn with
+// the number of every piece whose erasure share was found to be corrupt and
+// was corrected. It only has an effect when error detection is forced.
+func WithCorruptShareHandler(ctx context.Context, fn func(piece int)) context.Context {
+	return context.WithValue(ctx, corruptShareHandlerKey{}, fn)
+}
+
+func getCorruptShareHandler(ctx context.Context) func(piece int) {
+	fn, _ := ctx.Value(corruptShareHandlerKey{}).(func(piece int))
+	return fn
+}
+
+// FindCorruptShares returns the numbers of the erasure shares of a single
+// stripe that disagree with the others, in increasing order. Detecting
+// corruption needs more than the required number of shares, and correcting
+// it needs two more shares for every corrupt one. When the corruption is
+// detected but can't be corrected, the error is of the ErrUncorrectable class.
+func FindCorruptShares(es ErasureScheme, stripe map[int][]byte) ([]int, error) {
+	rs, ok := es.(*rsScheme)
+	if !ok {
+		return nil, Error.New("erasure scheme does not support error detection")
+	}
+	if len(stripe) <= rs.RequiredCount() {
+		return nil, Error.New("need more than %d shares to detect errors, got %d", rs.RequiredCount(), len(stripe))
+	}
+
+	shares := make([]infectious.Share, 0, len(stripe))
+	for num, data := range stripe {
+		shares = append(shares, infectious.Share{
+			Number: num,
+			Data:   append([]byte(nil), data...),
+		})
+	}
+
+	if len(shares) == rs.RequiredCount()+1 {
+		// correcting needs at least two spare shares, but one is enough to
+		// detect a corrupt share.
+		return nil, rs.detectCorruptShares(shares)
+	}
+
+	if err := rs.fc.Correct(shares); err != nil {
+		if errors.Is(err, infectious.TooManyErrors) {
+			return nil, ErrUncorrectable.Wrap(err)
+		}
+		return nil, Error.Wrap(err)
+	}
+
+	var corrupt []int
+	for _, share := range shares {
+		if string(share.Data) != string(stripe[share.Number]) {
+			corrupt = append(corrupt, share.Number)
+		}
+	}
+	sort.Ints(corrupt)
+	return corrupt, nil
+}
+
+// detectCorruptShares returns an error of the ErrUncorrectable class if the
+// shares of a stripe don't all agree with each other.
+func (rs *rsScheme) detectCorruptShares(shares []infectious.Share) error {
+	data := make([]byte, rs.StripeSize())
+	err := rs.fc.Rebuild(shares, func(share infectious.Share) {
+		copy(data[share.Number*rs.erasureShareSize:], share.Data)
+	})
+	if err != nil {
+		return Error.Wrap(err)
+	}
+
+	expected := make([]byte, rs.erasureShareSize)
+	for _, share := range shares {
+		if err := rs.fc.EncodeSingle(data, expected, share.Number); err != nil {
+			return Error.Wrap(err)
+		}
+		if !bytes.Equal(expected, share.Data) {
+			return ErrUncorrectable.New("share %d disagrees with the others", share.Number)
+		}
+	}
+	return nil
+}
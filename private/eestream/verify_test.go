@@ -0,0 +1,157 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package eestream_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vivint/infectious"
+
+	"common/testrand"
+	"uplink/private/eestream"
+)
+
+func TestFindCorruptShares(t *testing.T) {
+	fc, err := infectious.NewFEC(4, 8)
+	require.NoError(t, err)
+	es := eestream.NewRSScheme(fc, 16)
+
+	data := make([]byte, es.StripeSize())
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	encode := func() map[int][]byte {
+		stripe := make(map[int][]byte)
+		require.NoError(t, es.Encode(data, func(num int, share []byte) {
+			stripe[num] = append([]byte(nil), share...)
+		}))
+		return stripe
+	}
+
+	// intact shares.
+	corrupt, err := eestream.FindCorruptShares(es, encode())
+	require.NoError(t, err)
+	require.Empty(t, corrupt)
+
+	// corrupt shares are found, even with some missing.
+	stripe := encode()
+	stripe[1][3] ^= 0xFF
+	stripe[6][0] ^= 0xFF
+	delete(stripe, 4)
+	corrupt, err = eestream.FindCorruptShares(es, stripe)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 6}, corrupt)
+
+	// the input is not modified.
+	require.NotEqual(t, encode()[1], stripe[1])
+
+	// too many corrupt shares.
+	stripe = encode()
+	for num := 0; num < 3; num++ {
+		stripe[num][0] ^= 0xFF
+	}
+	_, err = eestream.FindCorruptShares(es, stripe)
+	require.True(t, eestream.ErrUncorrectable.Has(err))
+
+	// a single spare share detects a corrupt share, but can't find it.
+	stripe = encode()
+	for num := 5; num < 8; num++ {
+		delete(stripe, num)
+	}
+	corrupt, err = eestream.FindCorruptShares(es, stripe)
+	require.NoError(t, err)
+	require.Empty(t, corrupt)
+
+	for _, num := range []int{2, 4} {
+		stripe = encode()
+		for num := 5; num < 8; num++ {
+			delete(stripe, num)
+		}
+		stripe[num][0] ^= 0xFF
+		_, err = eestream.FindCorruptShares(es, stripe)
+		require.True(t, eestream.ErrUncorrectable.Has(err))
+	}
+
+	// not enough shares to detect anything.
+	stripe = encode()
+	for num := 4; num < 8; num++ {
+		delete(stripe, num)
+	}
+	_, err = eestream.FindCorruptShares(es, stripe)
+	require.Error(t, err)
+}
+
+func TestDecodeReportsCorruptShares(t *testing.T) {
+	const required, total = 4, 6
+
+	data := testrand.BytesInt(32 * 1024)
+	rs, pieces := encodeForHedging(t, data, required, total)
+
+	// corrupt every share of piece 2.
+	for i := 0; i < len(pieces[2]); i += rs.ErasureShareSize() {
+		pieces[2][i] ^= 0xFF
+	}
+
+	// make sure piece 2 is always among the shares that are decoded by
+	// letting the other pieces through only after it is read completely.
+	gate := make(chan struct{})
+	readers := make(map[int]io.ReadCloser, total)
+	for i, piece := range pieces {
+		if i == 2 {
+			readers[i] = io.NopCloser(&closingReader{r: bytes.NewReader(piece), gate: gate})
+		} else {
+			readers[i] = io.NopCloser(&gatedReader{r: bytes.NewReader(piece), gate: gate})
+		}
+	}
+
+	var mu sync.Mutex
+	corrupt := map[int]int{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = eestream.WithCorruptShareHandler(ctx, func(piece int) {
+		mu.Lock()
+		corrupt[piece]++
+		mu.Unlock()
+	})
+
+	// the buffers must fit piece 2 completely for the gate to open.
+	mbm := 2 * total * len(pieces[2])
+	decoder := eestream.DecodeReaders2(ctx, cancel, readers, rs, int64(len(data)), mbm, true)
+	decoded, err := io.ReadAll(decoder)
+	require.NoError(t, err)
+	require.NoError(t, decoder.Close())
+
+	require.Equal(t, data, decoded)
+	require.Equal(t, map[int]int{2: len(data) / rs.StripeSize()}, corrupt)
+}
+
+type gatedReader struct {
+	r    io.Reader
+	gate chan struct{}
+}
+
+func (g *gatedReader) Read(p []byte) (int, error) {
+	<-g.gate
+	return g.r.Read(p)
+}
+
+type closingReader struct {
+	r    io.Reader
+	gate chan struct{}
+	once sync.Once
+}
+
+func (c *closingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if err != nil {
+		c.once.Do(func() { close(c.gate) })
+	}
+	return n, err
+}
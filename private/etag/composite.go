@@ -0,0 +1,52 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package etag
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/zeebo/errs"
+)
+
+// Error is the error class for this package.
+var Error = errs.Class("etag")
+
+// Composite returns the ETag that S3 gives an object uploaded in parts,
+// which is the hex encoded MD5 of the MD5s of the parts, followed by a dash
+// and the number of parts. The part ETags must be in part order and be MD5s,
+// either raw or hex encoded.
+func Composite(partETags [][]byte) (string, error) {
+	if len(partETags) == 0 {
+		return "", Error.New("no parts")
+	}
+
+	h := md5.New()
+	for i, eTag := range partETags {
+		sum, err := md5Sum(eTag)
+		if err != nil {
+			return "", Error.New("part %d: %v", i, err)
+		}
+		_, _ = h.Write(sum)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)) + "-" + strconv.Itoa(len(partETags)), nil
+}
+
+// md5Sum returns the raw MD5 in eTag.
+func md5Sum(eTag []byte) ([]byte, error) {
+	switch len(eTag) {
+	case md5.Size:
+		return eTag, nil
+	case hex.EncodedLen(md5.Size):
+		sum, err := hex.DecodeString(string(eTag))
+		if err != nil {
+			return nil, err
+		}
+		return sum, nil
+	default:
+		return nil, errs.New("%d byte ETag is not an MD5", len(eTag))
+	}
+}
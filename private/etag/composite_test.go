@@ -0,0 +1,38 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package etag_test
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"uplink/private/etag"
+)
+
+func TestComposite(t *testing.T) {
+	hello := md5.Sum([]byte("hello"))
+	world := md5.Sum([]byte("world"))
+
+	// raw and hex encoded MD5s give the same result.
+	composite, err := etag.Composite([][]byte{hello[:], []byte("7d793037a0760186574b0282f2f435e7")})
+	require.NoError(t, err)
+	require.Equal(t, "065947336a2f2a95ba8899f3675c3be6-2", composite)
+
+	composite, err = etag.Composite([][]byte{hello[:], world[:]})
+	require.NoError(t, err)
+	require.Equal(t, "065947336a2f2a95ba8899f3675c3be6-2", composite)
+
+	_, err = etag.Composite(nil)
+	require.Error(t, err)
+
+	notMD5 := sha256.Sum256([]byte("hello"))
+	_, err = etag.Composite([][]byte{hello[:], notMD5[:]})
+	require.Error(t, err)
+
+	_, err = etag.Composite([][]byte{[]byte("zz793037a0760186574b0282f2f435e7")})
+	require.Error(t, err)
+}
@@ -18,6 +18,7 @@ import (
 	"common/rpc"
 	"common/rpc/rpcstatus"
 	"common/storx"
+	"drpc"
 	"uplink/private/eestream"
 )
 
@@ -59,14 +60,23 @@ func DialNodeURL(ctx context.Context, dialer rpc.Dialer, nodeURL string, apiKey
 		return nil, Error.New("node ID is required in node URL %q", nodeURL)
 	}
 
+	if replayer, ok := ctx.Value(replayerKey{}).(*Replayer); ok {
+		return NewClient(pb.NewDRPCMetainfoClient(faultyConn{replayer.Conn()}), apiKey, userAgent), nil
+	}
+
 	conn, err := dialer.DialNodeURL(ctx, url)
 	if err != nil {
 		return nil, Error.Wrap(err)
 	}
 
+	var drpcConn drpc.Conn = conn
+	if recorder, ok := ctx.Value(recorderKey{}).(*Recorder); ok {
+		drpcConn = recorder.Conn(conn)
+	}
+
 	return &Client{
 		conn:      conn,
-		client:    pb.NewDRPCMetainfoClient(conn),
+		client:    pb.NewDRPCMetainfoClient(faultyConn{drpcConn}),
 		apiKeyRaw: apiKey.SerializeRaw(),
 		userAgent: userAgent,
 	}, nil
@@ -0,0 +1,76 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package metaclient
+
+import (
+	"encoding/binary"
+
+	"github.com/zeebo/errs"
+)
+
+// streamInfoETagField is the field number that the S3-style ETag of an
+// object is stored under in its encrypted pb.StreamInfo. pb.StreamInfo
+// doesn't define the field, so clients that don't know about it skip it.
+const streamInfoETagField = 1000
+
+// AppendStreamInfoETag appends the ETag to a marshaled pb.StreamInfo.
+func AppendStreamInfoETag(streamInfo []byte, eTag string) []byte {
+	if eTag == "" {
+		return streamInfo
+	}
+	// the field is length delimited, which is wire type 2.
+	streamInfo = appendUvarint(streamInfo, streamInfoETagField<<3|2)
+	streamInfo = appendUvarint(streamInfo, uint64(len(eTag)))
+	return append(streamInfo, eTag...)
+}
+
+// StreamInfoETag returns the ETag appended to a marshaled pb.StreamInfo by
+// AppendStreamInfoETag, or "" when there is none.
+func StreamInfoETag(streamInfo []byte) (eTag string, err error) {
+	for len(streamInfo) > 0 {
+		tag, n := binary.Uvarint(streamInfo)
+		if n <= 0 {
+			return "", errs.New("invalid stream info field")
+		}
+		streamInfo = streamInfo[n:]
+
+		var size uint64
+		switch tag & 7 {
+		case 0: // varint
+			_, n = binary.Uvarint(streamInfo)
+			if n <= 0 {
+				return "", errs.New("invalid stream info varint")
+			}
+			size = uint64(n)
+		case 1: // fixed64
+			size = 8
+		case 2: // length delimited
+			size, n = binary.Uvarint(streamInfo)
+			if n <= 0 {
+				return "", errs.New("invalid stream info length")
+			}
+			streamInfo = streamInfo[n:]
+		case 5: // fixed32
+			size = 4
+		default:
+			return "", errs.New("unknown stream info wire type %d", tag&7)
+		}
+		if size > uint64(len(streamInfo)) {
+			return "", errs.New("truncated stream info")
+		}
+
+		if tag == streamInfoETagField<<3|2 {
+			eTag = string(streamInfo[:size])
+		}
+		streamInfo = streamInfo[size:]
+	}
+	return eTag, nil
+}
+
+// appendUvarint appends x to b as a varint.
+func appendUvarint(b []byte, x uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], x)
+	return append(b, buf[:n]...)
+}
@@ -0,0 +1,43 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package metaclient_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"common/pb"
+	"uplink/private/metaclient"
+)
+
+func TestStreamInfoETag(t *testing.T) {
+	streamInfo, err := pb.Marshal(&pb.StreamInfo{
+		SegmentsSize:    64 << 20,
+		LastSegmentSize: 1234,
+		Metadata:        []byte("metadata"),
+	})
+	require.NoError(t, err)
+
+	eTag, err := metaclient.StreamInfoETag(streamInfo)
+	require.NoError(t, err)
+	require.Empty(t, eTag)
+
+	require.Equal(t, streamInfo, metaclient.AppendStreamInfoETag(streamInfo, ""))
+
+	withETag := metaclient.AppendStreamInfoETag(append([]byte(nil), streamInfo...), "0123456789abcdef0123456789abcdef-2")
+	eTag, err = metaclient.StreamInfoETag(withETag)
+	require.NoError(t, err)
+	require.Equal(t, "0123456789abcdef0123456789abcdef-2", eTag)
+
+	// clients that don't know about the ETag still read the stream info.
+	var decoded pb.StreamInfo
+	require.NoError(t, pb.Unmarshal(withETag, &decoded))
+	require.Equal(t, int64(64<<20), decoded.SegmentsSize)
+	require.Equal(t, int64(1234), decoded.LastSegmentSize)
+	require.Equal(t, []byte("metadata"), decoded.Metadata)
+
+	_, err = metaclient.StreamInfoETag(withETag[:len(withETag)-1])
+	require.Error(t, err)
+}
@@ -0,0 +1,84 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package metaclient
+
+import (
+	"context"
+
+	"common/pb"
+	"drpc"
+	"uplink/private/testuplink"
+)
+
+// faultyConn fails the metainfo requests selected by the testuplink.Faults
+// of their context.
+type faultyConn struct {
+	drpc.Conn
+}
+
+// Invoke fails the request if it, or a request in its batch, is selected.
+func (conn faultyConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	if faults := testuplink.GetFaults(ctx); faults != nil {
+		if err := faults.RPCError(rpc); err != nil {
+			return err
+		}
+		if batch, ok := in.(*pb.BatchRequest); ok {
+			for _, item := range batch.Requests {
+				if err := faults.RPCError(batchItemMethod(item)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return conn.Conn.Invoke(ctx, rpc, enc, in, out)
+}
+
+// batchItemMethod returns the name of the method that handles the request
+// when it is not batched.
+func batchItemMethod(item *pb.BatchRequestItem) string {
+	switch item.Request.(type) {
+	case *pb.BatchRequestItem_BucketCreate:
+		return "CreateBucket"
+	case *pb.BatchRequestItem_BucketGet:
+		return "GetBucket"
+	case *pb.BatchRequestItem_BucketDelete:
+		return "DeleteBucket"
+	case *pb.BatchRequestItem_BucketList:
+		return "ListBuckets"
+	case *pb.BatchRequestItem_ObjectBegin:
+		return "BeginObject"
+	case *pb.BatchRequestItem_ObjectCommit:
+		return "CommitObject"
+	case *pb.BatchRequestItem_ObjectGet:
+		return "GetObject"
+	case *pb.BatchRequestItem_ObjectList:
+		return "ListObjects"
+	case *pb.BatchRequestItem_ObjectListPendingStreams:
+		return "ListPendingObjectStreams"
+	case *pb.BatchRequestItem_ObjectBeginDelete:
+		return "BeginDeleteObject"
+	case *pb.BatchRequestItem_ObjectDownload:
+		return "DownloadObject"
+	case *pb.BatchRequestItem_ObjectBeginMove:
+		return "BeginMoveObject"
+	case *pb.BatchRequestItem_ObjectFinishMove:
+		return "FinishMoveObject"
+	case *pb.BatchRequestItem_ObjectBeginCopy:
+		return "BeginCopyObject"
+	case *pb.BatchRequestItem_ObjectFinishCopy:
+		return "FinishCopyObject"
+	case *pb.BatchRequestItem_SegmentBegin:
+		return "BeginSegment"
+	case *pb.BatchRequestItem_SegmentCommit:
+		return "CommitSegment"
+	case *pb.BatchRequestItem_SegmentMakeInline:
+		return "MakeInlineSegment"
+	case *pb.BatchRequestItem_SegmentList:
+		return "ListSegments"
+	case *pb.BatchRequestItem_SegmentDownload:
+		return "DownloadSegment"
+	default:
+		return ""
+	}
+}
@@ -107,12 +107,13 @@ func (db *DB) UpdateObjectMetadata(ctx context.Context, bucket, key string, newM
 	}
 
 	// TODO: check if we could avoid this round-trip to satellite
-	// At the moment, we need to get the object for two reason:
+	// At the moment, we need to get the object for three reasons:
 	//   1. Retrieve the backward-compatibility metadata
 	//      (max segment size and last segment size)
 	//      and copy it to the new metadata.
 	//   2. Retrieve the object's encryption parameters
 	//      and use them for encrypting the new metadata.
+	//   3. Retrieve the object's ETag and keep it.
 	objectInfo, err := db.metainfo.GetObject(ctx, GetObjectParams{
 		Bucket:                     []byte(bucket),
 		EncryptedObjectKey:         []byte(encPath.Raw()),
@@ -142,6 +143,8 @@ func (db *DB) UpdateObjectMetadata(ctx context.Context, bucket, key string, newM
 	if err != nil {
 		return err
 	}
+	// the ETag is system metadata, so it is kept.
+	streamInfo = AppendStreamInfoETag(streamInfo, object.ETag)
 
 	derivedKey, err := encryption.DeriveContentKey(bucket, paths.NewUnencrypted(key), db.encStore)
 	if err != nil {
@@ -286,7 +289,7 @@ func (db *DB) pendingObjectsFromRawObjectList(ctx context.Context, items []RawOb
 	objectList = make([]Object, 0, len(items))
 
 	for _, item := range items {
-		stream, streamMeta, err := db.typedDecryptStreamInfo(ctx, pi.Bucket, pi.PathUnenc,
+		stream, eTag, streamMeta, err := db.typedDecryptStreamInfo(ctx, pi.Bucket, pi.PathUnenc,
 			item.EncryptedMetadata,
 			item.EncryptedMetadataEncryptedKey,
 			item.EncryptedMetadataNonce,
@@ -299,7 +302,7 @@ func (db *DB) pendingObjectsFromRawObjectList(ctx context.Context, items []RawOb
 			return nil, errClass.Wrap(err)
 		}
 
-		object, err := db.objectFromRawObjectListItem(pi.Bucket, pi.PathUnenc.Raw(), item, stream, streamMeta)
+		object, err := db.objectFromRawObjectListItem(pi.Bucket, pi.PathUnenc.Raw(), item, stream, eTag, streamMeta)
 		if err != nil {
 			return nil, errClass.Wrap(err)
 		}
@@ -425,7 +428,7 @@ func (db *DB) objectsFromRawObjectList(ctx context.Context, items []RawObjectLis
 			unencKey = paths.NewUnencrypted(unencItem)
 		}
 
-		stream, streamMeta, err := db.typedDecryptStreamInfo(ctx, pi.Bucket, unencKey,
+		stream, eTag, streamMeta, err := db.typedDecryptStreamInfo(ctx, pi.Bucket, unencKey,
 			item.EncryptedMetadata,
 			item.EncryptedMetadataEncryptedKey,
 			item.EncryptedMetadataNonce,
@@ -438,7 +441,7 @@ func (db *DB) objectsFromRawObjectList(ctx context.Context, items []RawObjectLis
 			return nil, errClass.Wrap(err)
 		}
 
-		object, err := db.objectFromRawObjectListItem(pi.Bucket, unencItem, item, stream, streamMeta)
+		object, err := db.objectFromRawObjectListItem(pi.Bucket, unencItem, item, stream, eTag, streamMeta)
 		if err != nil {
 			return nil, errClass.Wrap(err)
 		}
@@ -570,7 +573,7 @@ func (db *DB) ObjectFromRawObjectItem(ctx context.Context, bucket, key string, o
 		},
 	}
 
-	streamInfo, streamMeta, err := db.typedDecryptStreamInfo(ctx, bucket, paths.NewUnencrypted(key),
+	streamInfo, eTag, streamMeta, err := db.typedDecryptStreamInfo(ctx, bucket, paths.NewUnencrypted(key),
 		objectInfo.EncryptedMetadata,
 		objectInfo.EncryptedMetadataEncryptedKey,
 		objectInfo.EncryptedMetadataNonce,
@@ -595,7 +598,7 @@ func (db *DB) ObjectFromRawObjectItem(ctx context.Context, bucket, key string, o
 		}
 	}
 
-	err = updateObjectWithStream(&object, streamInfo, streamMeta)
+	err = updateObjectWithStream(&object, streamInfo, eTag, streamMeta)
 	if err != nil {
 		return Object{}, err
 	}
@@ -603,7 +606,7 @@ func (db *DB) ObjectFromRawObjectItem(ctx context.Context, bucket, key string, o
 	return object, nil
 }
 
-func (db *DB) objectFromRawObjectListItem(bucket string, path storx.Path, listItem RawObjectListItem, stream *pb.StreamInfo, streamMeta pb.StreamMeta) (Object, error) {
+func (db *DB) objectFromRawObjectListItem(bucket string, path storx.Path, listItem RawObjectListItem, stream *pb.StreamInfo, eTag string, streamMeta pb.StreamMeta) (Object, error) {
 	object := Object{
 		Version:  uint32(listItem.Version),
 		Bucket:   Bucket{Name: bucket},
@@ -621,7 +624,7 @@ func (db *DB) objectFromRawObjectListItem(bucket string, path storx.Path, listIt
 
 	object.Stream.ID = listItem.StreamID
 
-	err := updateObjectWithStream(&object, stream, streamMeta)
+	err := updateObjectWithStream(&object, stream, eTag, streamMeta)
 	if err != nil {
 		return Object{}, err
 	}
@@ -629,7 +632,7 @@ func (db *DB) objectFromRawObjectListItem(bucket string, path storx.Path, listIt
 	return object, nil
 }
 
-func updateObjectWithStream(object *Object, stream *pb.StreamInfo, streamMeta pb.StreamMeta) error {
+func updateObjectWithStream(object *Object, stream *pb.StreamInfo, eTag string, streamMeta pb.StreamMeta) error {
 	if stream == nil {
 		return nil
 	}
@@ -652,6 +655,7 @@ func updateObjectWithStream(object *Object, stream *pb.StreamInfo, streamMeta pb
 
 	segmentCount := streamMeta.NumberOfSegments
 	object.Metadata = serializableMeta.UserDefined
+	object.ETag = eTag
 
 	if object.Stream.Size == 0 {
 		object.Stream.Size = ((segmentCount - 1) * stream.SegmentsSize) + stream.LastSegmentSize
@@ -691,45 +695,50 @@ func (object *MutableObject) CreateDynamicStream(ctx context.Context, metadata S
 	}, nil
 }
 
-// typedDecryptStreamInfo decrypts stream info.
+// typedDecryptStreamInfo decrypts stream info, and the ETag stored with it.
 func (db *DB) typedDecryptStreamInfo(ctx context.Context, bucket string, unencryptedKey paths.Unencrypted,
-	streamMetaBytes, metadataKey []byte, metadataNonce storx.Nonce) (_ *pb.StreamInfo, _ pb.StreamMeta, err error) {
+	streamMetaBytes, metadataKey []byte, metadataNonce storx.Nonce) (_ *pb.StreamInfo, eTag string, _ pb.StreamMeta, err error) {
 	defer mon.Task()(&ctx)(&err)
 
 	streamMeta := pb.StreamMeta{}
 	err = pb.Unmarshal(streamMetaBytes, &streamMeta)
 	if err != nil {
-		return nil, pb.StreamMeta{}, err
+		return nil, "", pb.StreamMeta{}, err
 	}
 
 	if db.encStore.EncryptionBypass {
-		return nil, streamMeta, nil
+		return nil, "", streamMeta, nil
 	}
 
 	derivedKey, err := encryption.DeriveContentKey(bucket, unencryptedKey, db.encStore)
 	if err != nil {
-		return nil, pb.StreamMeta{}, err
+		return nil, "", pb.StreamMeta{}, err
 	}
 
 	cipher := storx.CipherSuite(streamMeta.EncryptionType)
 	encryptedKey, keyNonce := getEncryptedKeyAndNonce(metadataKey, metadataNonce, streamMeta.LastSegmentMeta)
 	contentKey, err := encryption.DecryptKey(encryptedKey, cipher, derivedKey, keyNonce)
 	if err != nil {
-		return nil, pb.StreamMeta{}, err
+		return nil, "", pb.StreamMeta{}, err
 	}
 
 	// decrypt metadata with the content encryption key and zero nonce
 	streamInfo, err := encryption.Decrypt(streamMeta.EncryptedStreamInfo, cipher, contentKey, &storx.Nonce{})
 	if err != nil {
-		return nil, pb.StreamMeta{}, err
+		return nil, "", pb.StreamMeta{}, err
 	}
 
 	var stream pb.StreamInfo
 	if err := pb.Unmarshal(streamInfo, &stream); err != nil {
-		return nil, pb.StreamMeta{}, err
+		return nil, "", pb.StreamMeta{}, err
 	}
 
-	return &stream, streamMeta, nil
+	eTag, err = StreamInfoETag(streamInfo)
+	if err != nil {
+		return nil, "", pb.StreamMeta{}, err
+	}
+
+	return &stream, eTag, streamMeta, nil
 }
 
 // getEncryptedKeyAndNonce returns key and nonce directly if exists, otherwise try to get them from SegmentMeta.
@@ -0,0 +1,478 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package metaclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zeebo/errs"
+
+	"common/pb"
+	"common/rpc/rpcstatus"
+	"drpc"
+)
+
+// ErrUnexpectedCall is returned by a Replayer for requests that were not
+// recorded.
+var ErrUnexpectedCall = errs.Class("unexpected metainfo call")
+
+type recorderKey struct{}
+
+type replayerKey struct{}
+
+// RecordedCall is a metainfo request and its response, as recorded by a
+// Recorder. The request and response are protobuf encoded.
+type RecordedCall struct {
+	// Time is when the request was made. It is only used to describe the
+	// call, requests are matched regardless of it.
+	Time time.Time `json:"time"`
+	RPC  string    `json:"rpc"`
+	// Methods are the methods of the requests of a batch.
+	Methods  []string `json:"methods,omitempty"`
+	Request  []byte   `json:"request"`
+	Response []byte   `json:"response,omitempty"`
+
+	// Error and Code are the message and rpcstatus code of the error the
+	// request failed with.
+	Error string               `json:"error,omitempty"`
+	Code  rpcstatus.StatusCode `json:"code,omitempty"`
+}
+
+// String describes the call.
+func (call *RecordedCall) String() string {
+	if len(call.Methods) == 0 {
+		return call.RPC
+	}
+	return fmt.Sprintf("%s [%s]", call.RPC, strings.Join(call.Methods, ", "))
+}
+
+// Recorder writes the metainfo requests made by clients, and their
+// responses, as JSON lines of RecordedCall. The API keys of the requests are
+// not recorded.
+type Recorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder returns a Recorder that writes to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+// WithRecorder creates a context that records the metainfo requests of the
+// clients dialed with it.
+func WithRecorder(ctx context.Context, recorder *Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, recorder)
+}
+
+// Conn returns conn with its requests recorded.
+func (recorder *Recorder) Conn(conn drpc.Conn) drpc.Conn {
+	return &recordingConn{Conn: conn, recorder: recorder}
+}
+
+// Err returns the first error writing the recording.
+func (recorder *Recorder) Err() error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	return recorder.err
+}
+
+func (recorder *Recorder) record(call *RecordedCall) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	if recorder.err == nil {
+		recorder.err = Error.Wrap(recorder.enc.Encode(call))
+	}
+}
+
+// recordingConn records the requests sent through it.
+type recordingConn struct {
+	drpc.Conn
+	recorder *Recorder
+}
+
+// Invoke sends the request and records it with its response.
+func (conn *recordingConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	call, err := newRecordedCall(rpc, enc, in)
+	if err != nil {
+		return err
+	}
+
+	invokeErr := conn.Conn.Invoke(ctx, rpc, enc, in, out)
+	if invokeErr != nil {
+		call.Error = invokeErr.Error()
+		call.Code = rpcstatus.Code(invokeErr)
+	} else if call.Response, err = enc.Marshal(out); err != nil {
+		return Error.Wrap(err)
+	}
+
+	conn.recorder.record(call)
+	return invokeErr
+}
+
+func newRecordedCall(rpc string, enc drpc.Encoding, in drpc.Message) (*RecordedCall, error) {
+	request, err := marshalWithoutAPIKeys(enc, in)
+	if err != nil {
+		return nil, err
+	}
+
+	call := &RecordedCall{
+		Time:    time.Now(),
+		RPC:     rpc,
+		Request: request,
+	}
+	if batch, ok := in.(*pb.BatchRequest); ok {
+		for _, item := range batch.Requests {
+			call.Methods = append(call.Methods, batchItemMethod(item))
+		}
+	}
+	return call, nil
+}
+
+// marshalWithoutAPIKeys encodes the request with the API keys of its headers
+// removed, so that recordings don't contain them and can be replayed with
+// other API keys.
+func marshalWithoutAPIKeys(enc drpc.Encoding, in drpc.Message) ([]byte, error) {
+	request, err := enc.Marshal(in)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	// clear the keys of a copy, because the request is still being sent.
+	typ := reflect.TypeOf(in)
+	if typ.Kind() != reflect.Ptr {
+		return request, nil
+	}
+	stripped := reflect.New(typ.Elem()).Interface()
+	if err := enc.Unmarshal(request, stripped); err != nil {
+		return nil, Error.Wrap(err)
+	}
+	if !clearAPIKeys(reflect.ValueOf(stripped)) {
+		return request, nil
+	}
+
+	request, err = enc.Marshal(stripped)
+	return request, Error.Wrap(err)
+}
+
+// clearAPIKeys clears the API key of every request header in v and returns
+// whether there were any.
+func clearAPIKeys(v reflect.Value) (cleared bool) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return false
+		}
+		if header, ok := v.Interface().(*pb.RequestHeader); ok {
+			cleared = len(header.ApiKey) > 0
+			header.ApiKey = nil
+			return cleared
+		}
+		return clearAPIKeys(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				cleared = clearAPIKeys(v.Field(i)) || cleared
+			}
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return false
+		}
+		for i := 0; i < v.Len(); i++ {
+			cleared = clearAPIKeys(v.Index(i)) || cleared
+		}
+	}
+	return cleared
+}
+
+// ReplayMatcher reports whether a request matches a recorded request of the
+// same method. The requests of a batch are matched item by item, so it is
+// given the request of a single method, like *pb.ObjectBeginRequest, whether
+// it was sent in a batch or not. Both requests are decoded for every call,
+// without API keys, so the matcher may modify them.
+type ReplayMatcher func(recorded, request drpc.Message) bool
+
+// nondeterministicFields are the fields of requests that differ every time
+// the same request is made, because they are encrypted with random keys and
+// nonces, or signed at the time of the request.
+var nondeterministicFields = map[string]bool{
+	"EncryptedKey":                  true,
+	"EncryptedKeyNonce":             true,
+	"EncryptedMetadata":             true,
+	"EncryptedMetadataNonce":        true,
+	"EncryptedMetadataEncryptedKey": true,
+	"EncryptedInlineData":           true,
+	"EncryptedETag":                 true,
+	"Hash":                          true, // the signed piece hashes of a segment commit
+}
+
+// MatchNormalized is the default ReplayMatcher. It matches requests that
+// are equal, except for the fields that differ every time the same request
+// is made: encrypted keys, metadata, inline data and ETags with their
+// nonces, and the signed piece hashes of committed segments. This lets
+// uploads be replayed, which always encrypt with new random keys.
+func MatchNormalized(recorded, request drpc.Message) bool {
+	clearFields(reflect.ValueOf(recorded), nondeterministicFields)
+	clearFields(reflect.ValueOf(request), nondeterministicFields)
+	return reflect.DeepEqual(recorded, request)
+}
+
+// clearFields sets the struct fields in v with the names to their zero
+// value.
+func clearFields(v reflect.Value, names map[string]bool) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			clearFields(v.Elem(), names)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			switch {
+			case !field.IsExported():
+			case names[field.Name]:
+				v.Field(i).Set(reflect.Zero(field.Type))
+			default:
+				clearFields(v.Field(i), names)
+			}
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			clearFields(v.Index(i), names)
+		}
+	}
+}
+
+// Replayer answers metainfo requests with the responses of a recording,
+// without a satellite.
+//
+// A request is answered by the first call of the recording, not yet
+// replayed, with the same RPC and a matching request. The requests are
+// matched with MatchNormalized, unless another ReplayMatcher is set. Other
+// requests fail with ErrUnexpectedCall.
+type Replayer struct {
+	mu         sync.Mutex
+	match      ReplayMatcher
+	calls      []RecordedCall
+	replayed   []bool
+	unexpected []string
+}
+
+// NewReplayer returns a Replayer of the recording read from r.
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	replayer := &Replayer{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var call RecordedCall
+		if err := json.Unmarshal(scanner.Bytes(), &call); err != nil {
+			return nil, Error.New("invalid recording line %d: %v", line, err)
+		}
+		replayer.calls = append(replayer.calls, call)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	replayer.replayed = make([]bool, len(replayer.calls))
+	replayer.match = MatchNormalized
+	return replayer, nil
+}
+
+// SetMatcher replaces how requests are matched with recorded requests.
+func (replayer *Replayer) SetMatcher(match ReplayMatcher) {
+	replayer.mu.Lock()
+	defer replayer.mu.Unlock()
+
+	replayer.match = match
+}
+
+// WithReplayer creates a context that answers the metainfo requests of the
+// clients dialed with it from the recording, instead of dialing the
+// satellite.
+func WithReplayer(ctx context.Context, replayer *Replayer) context.Context {
+	return context.WithValue(ctx, replayerKey{}, replayer)
+}
+
+// Conn returns a connection that answers requests from the recording.
+func (replayer *Replayer) Conn() drpc.Conn {
+	return &replayConn{replayer: replayer, closed: make(chan struct{})}
+}
+
+// Unexpected returns the requests that were not in the recording.
+func (replayer *Replayer) Unexpected() []string {
+	replayer.mu.Lock()
+	defer replayer.mu.Unlock()
+
+	return append([]string(nil), replayer.unexpected...)
+}
+
+// Remaining returns the calls of the recording that were not replayed.
+func (replayer *Replayer) Remaining() []RecordedCall {
+	replayer.mu.Lock()
+	defer replayer.mu.Unlock()
+
+	var remaining []RecordedCall
+	for i, call := range replayer.calls {
+		if !replayer.replayed[i] {
+			remaining = append(remaining, call)
+		}
+	}
+	return remaining
+}
+
+// Check returns an error if there were unexpected requests or calls of the
+// recording that were not replayed.
+func (replayer *Replayer) Check() error {
+	var group errs.Group
+	for _, call := range replayer.Unexpected() {
+		group.Add(ErrUnexpectedCall.New("%s", call))
+	}
+	for _, call := range replayer.Remaining() {
+		group.Add(Error.New("call not replayed: %s, recorded at %s", call.String(), call.Time.Format(time.RFC3339Nano)))
+	}
+	return group.Err()
+}
+
+// replay returns the recorded call that answers the request.
+func (replayer *Replayer) replay(rpc string, enc drpc.Encoding, in drpc.Message) (*RecordedCall, error) {
+	call, err := newRecordedCall(rpc, enc, in)
+	if err != nil {
+		return nil, err
+	}
+
+	replayer.mu.Lock()
+	defer replayer.mu.Unlock()
+
+	for i := range replayer.calls {
+		recorded := &replayer.calls[i]
+		if replayer.replayed[i] || recorded.RPC != rpc {
+			continue
+		}
+
+		ok, err := replayer.matches(enc, in, recorded.Request, call.Request)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			replayer.replayed[i] = true
+			return recorded, nil
+		}
+	}
+
+	replayer.unexpected = append(replayer.unexpected, call.String())
+	return nil, ErrUnexpectedCall.New("%s", call.String())
+}
+
+// matches returns whether the encoded requests, of the type of in, match.
+// Batches match when their items are of the same methods and match.
+func (replayer *Replayer) matches(enc drpc.Encoding, in drpc.Message, recordedRequest, request []byte) (bool, error) {
+	typ := reflect.TypeOf(in)
+	if typ.Kind() != reflect.Ptr {
+		return bytes.Equal(recordedRequest, request), nil
+	}
+
+	decode := func(data []byte) (drpc.Message, error) {
+		msg := reflect.New(typ.Elem()).Interface()
+		return msg, Error.Wrap(enc.Unmarshal(data, msg))
+	}
+	recorded, err := decode(recordedRequest)
+	if err != nil {
+		return false, err
+	}
+	current, err := decode(request)
+	if err != nil {
+		return false, err
+	}
+
+	recordedBatch, ok := recorded.(*pb.BatchRequest)
+	if !ok {
+		return replayer.match(recorded, current), nil
+	}
+	batch := current.(*pb.BatchRequest)
+
+	if len(recordedBatch.Requests) != len(batch.Requests) {
+		return false, nil
+	}
+	for i, item := range batch.Requests {
+		recordedItem := recordedBatch.Requests[i]
+		if reflect.TypeOf(recordedItem.Request) != reflect.TypeOf(item.Request) {
+			return false, nil
+		}
+		if !replayer.match(batchItemRequest(recordedItem), batchItemRequest(item)) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// batchItemRequest returns the request of the single method in a batch
+// item, or the item itself if it has none.
+func batchItemRequest(item *pb.BatchRequestItem) drpc.Message {
+	v := reflect.ValueOf(item.Request)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct || v.Elem().NumField() == 0 {
+		return item
+	}
+	return v.Elem().Field(0).Interface()
+}
+
+// replayConn answers requests from a Replayer.
+type replayConn struct {
+	// Conn is nil. It is embedded to implement drpc.Conn, of which the
+	// metainfo client only uses the methods below.
+	drpc.Conn
+
+	replayer  *Replayer
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// Invoke answers the request with the recorded response or error.
+func (conn *replayConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	call, err := conn.replayer.replay(rpc, enc, in)
+	if err != nil {
+		return err
+	}
+	if call.Error != "" {
+		return rpcstatus.Error(call.Code, call.Error)
+	}
+	return Error.Wrap(enc.Unmarshal(call.Response, out))
+}
+
+// NewStream fails, because streams are not recorded.
+func (conn *replayConn) NewStream(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+	return nil, ErrUnexpectedCall.New("stream %s", rpc)
+}
+
+// Close closes the connection.
+func (conn *replayConn) Close() error {
+	conn.closeOnce.Do(func() { close(conn.closed) })
+	return nil
+}
+
+// Closed returns a channel that is closed when the connection is closed.
+func (conn *replayConn) Closed() <-chan struct{} {
+	return conn.closed
+}
@@ -0,0 +1,230 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package metaclient_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"common/errs2"
+	"common/macaroon"
+	"common/pb"
+	"common/rpc/rpcstatus"
+	"common/storx"
+	"common/testcontext"
+	"common/testrand"
+	"drpc"
+	"uplink/private/metaclient"
+)
+
+// bucketConn answers GetBucket requests for a single bucket.
+type bucketConn struct {
+	drpc.Conn
+	bucket  string
+	created time.Time
+	invoked int
+}
+
+func (conn *bucketConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	conn.invoked++
+
+	request, ok := in.(*pb.BucketGetRequest)
+	if !ok {
+		return rpcstatus.Error(rpcstatus.Unimplemented, rpc)
+	}
+	if string(request.Name) != conn.bucket {
+		return rpcstatus.Error(rpcstatus.NotFound, "bucket not found")
+	}
+	out.(*pb.BucketGetResponse).Bucket = &pb.Bucket{
+		Name:      request.Name,
+		CreatedAt: conn.created,
+	}
+	return nil
+}
+
+// emptyConn answers every request with an empty response.
+type emptyConn struct {
+	drpc.Conn
+}
+
+func (conn emptyConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	return nil
+}
+
+func TestRecordReplay(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	apiKey, err := macaroon.NewAPIKey([]byte("secret"))
+	require.NoError(t, err)
+
+	conn := &bucketConn{bucket: "bucket", created: time.Now().UTC().Truncate(time.Second)}
+
+	var recording bytes.Buffer
+	recorder := metaclient.NewRecorder(&recording)
+	client := metaclient.NewClient(pb.NewDRPCMetainfoClient(recorder.Conn(conn)), apiKey, "")
+
+	bucket, err := client.GetBucket(ctx, metaclient.GetBucketParams{Name: []byte("bucket")})
+	require.NoError(t, err)
+	require.Equal(t, "bucket", bucket.Name)
+
+	_, err = client.GetBucket(ctx, metaclient.GetBucketParams{Name: []byte("missing")})
+	require.True(t, metaclient.ErrBucketNotFound.Has(err))
+
+	require.NoError(t, recorder.Err())
+	require.Equal(t, 2, conn.invoked)
+
+	t.Run("Replay", func(t *testing.T) {
+		replayer, err := metaclient.NewReplayer(bytes.NewReader(recording.Bytes()))
+		require.NoError(t, err)
+		require.Len(t, replayer.Remaining(), 2)
+
+		// the API key is not recorded.
+		for _, call := range replayer.Remaining() {
+			require.False(t, bytes.Contains(call.Request, apiKey.SerializeRaw()))
+		}
+
+		// so the recording can be replayed with another one.
+		otherKey, err := macaroon.NewAPIKey([]byte("other secret"))
+		require.NoError(t, err)
+		client := metaclient.NewClient(pb.NewDRPCMetainfoClient(replayer.Conn()), otherKey, "")
+
+		// the requests are answered in any order.
+		_, err = client.GetBucket(ctx, metaclient.GetBucketParams{Name: []byte("missing")})
+		require.True(t, metaclient.ErrBucketNotFound.Has(err))
+		require.True(t, errs2.IsRPC(err, rpcstatus.NotFound))
+
+		replayed, err := client.GetBucket(ctx, metaclient.GetBucketParams{Name: []byte("bucket")})
+		require.NoError(t, err)
+		require.Equal(t, bucket, replayed)
+
+		require.Equal(t, 2, conn.invoked)
+		require.Empty(t, replayer.Unexpected())
+		require.NoError(t, replayer.Check())
+	})
+
+	t.Run("Unexpected", func(t *testing.T) {
+		replayer, err := metaclient.NewReplayer(bytes.NewReader(recording.Bytes()))
+		require.NoError(t, err)
+
+		client := metaclient.NewClient(pb.NewDRPCMetainfoClient(replayer.Conn()), apiKey, "")
+
+		_, err = client.GetBucket(ctx, metaclient.GetBucketParams{Name: []byte("other")})
+		require.True(t, metaclient.ErrUnexpectedCall.Has(err))
+		require.Equal(t, []string{"/metainfo.Metainfo/GetBucket"}, replayer.Unexpected())
+
+		err = replayer.Check()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "call not replayed")
+	})
+
+	t.Run("InvalidRecording", func(t *testing.T) {
+		_, err := metaclient.NewReplayer(bytes.NewReader([]byte("{}\nnot json\n")))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "line 2")
+	})
+}
+
+func TestRecordReplay_Encrypted(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	apiKey, err := macaroon.NewAPIKey([]byte("secret"))
+	require.NoError(t, err)
+
+	streamID := storx.StreamID(testrand.Bytes(32))
+
+	// segment returns the params of an inline segment encrypted with new
+	// random keys, as every upload does.
+	segment := func(streamID storx.StreamID) metaclient.MakeInlineSegmentParams {
+		var nonce storx.Nonce
+		copy(nonce[:], testrand.BytesInt(len(nonce)))
+		return metaclient.MakeInlineSegmentParams{
+			StreamID: streamID,
+			Encryption: metaclient.SegmentEncryption{
+				EncryptedKeyNonce: nonce,
+				EncryptedKey:      testrand.BytesInt(48),
+			},
+			EncryptedInlineData: testrand.BytesInt(100),
+			PlainSize:           84,
+		}
+	}
+	commit := func(streamID storx.StreamID) metaclient.CommitObjectParams {
+		var nonce storx.Nonce
+		copy(nonce[:], testrand.BytesInt(len(nonce)))
+		return metaclient.CommitObjectParams{
+			StreamID:                      streamID,
+			EncryptedMetadataNonce:        nonce,
+			EncryptedMetadata:             testrand.BytesInt(32),
+			EncryptedMetadataEncryptedKey: testrand.BytesInt(48),
+		}
+	}
+	upload := func(client *metaclient.Client, streamID storx.StreamID) error {
+		if err := client.MakeInlineSegment(ctx, segment(streamID)); err != nil {
+			return err
+		}
+		inline, commit := segment(streamID), commit(streamID)
+		_, err := client.Batch(ctx, &inline, &commit)
+		return err
+	}
+
+	var recording bytes.Buffer
+	recorder := metaclient.NewRecorder(&recording)
+	client := metaclient.NewClient(pb.NewDRPCMetainfoClient(recorder.Conn(emptyConn{})), apiKey, "")
+	require.NoError(t, upload(client, streamID))
+	require.NoError(t, recorder.Err())
+
+	t.Run("Replay", func(t *testing.T) {
+		replayer, err := metaclient.NewReplayer(bytes.NewReader(recording.Bytes()))
+		require.NoError(t, err)
+
+		// the encrypted fields differ on every upload.
+		client := metaclient.NewClient(pb.NewDRPCMetainfoClient(replayer.Conn()), apiKey, "")
+		require.NoError(t, upload(client, streamID))
+
+		require.Empty(t, replayer.Unexpected())
+		require.NoError(t, replayer.Check())
+	})
+
+	t.Run("OtherStream", func(t *testing.T) {
+		replayer, err := metaclient.NewReplayer(bytes.NewReader(recording.Bytes()))
+		require.NoError(t, err)
+
+		client := metaclient.NewClient(pb.NewDRPCMetainfoClient(replayer.Conn()), apiKey, "")
+		err = upload(client, storx.StreamID(testrand.Bytes(32)))
+		require.True(t, metaclient.ErrUnexpectedCall.Has(err))
+
+		err = replayer.Check()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "recorded at")
+	})
+
+	t.Run("Matcher", func(t *testing.T) {
+		replayer, err := metaclient.NewReplayer(bytes.NewReader(recording.Bytes()))
+		require.NoError(t, err)
+
+		var matched []string
+		replayer.SetMatcher(func(recorded, request drpc.Message) bool {
+			switch request.(type) {
+			case *pb.SegmentMakeInlineRequest:
+				matched = append(matched, "inline")
+			case *pb.ObjectCommitRequest:
+				matched = append(matched, "commit")
+			}
+			return true
+		})
+
+		// the matcher decides, even for another stream.
+		client := metaclient.NewClient(pb.NewDRPCMetainfoClient(replayer.Conn()), apiKey, "")
+		require.NoError(t, upload(client, storx.StreamID(testrand.Bytes(32))))
+		require.NoError(t, replayer.Check())
+
+		// batches are matched item by item.
+		require.Equal(t, []string{"inline", "inline", "commit"}, matched)
+	})
+}
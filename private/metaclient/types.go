@@ -96,6 +96,9 @@ type Object struct {
 	IsPrefix bool
 
 	Metadata map[string]string
+	// ETag is the S3-style ETag of the object, stored in its encrypted
+	// stream info, or "" when it has none.
+	ETag string
 
 	ContentType string
 	Created     time.Time
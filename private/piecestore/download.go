@@ -19,6 +19,7 @@ import (
 	"common/signing"
 	"common/storx"
 	"common/sync2"
+	"uplink/private/ratelimit"
 )
 
 // Download implements downloading from a piecestore.
@@ -27,6 +28,7 @@ type Download struct {
 	limit      *pb.OrderLimit
 	privateKey storx.PiecePrivateKey
 	stream     downloadStream
+	limiter    *ratelimit.Limiter
 	ctx        context.Context
 	cancelCtx  func(error)
 
@@ -88,6 +90,7 @@ func (client *Client) Download(ctx context.Context, limit *pb.OrderLimit, pieceP
 		limit:      limit,
 		privateKey: piecePrivateKey,
 		stream:     stream,
+		limiter:    GetDownloadLimiter(ctx),
 		ctx:        ctx,
 		cancelCtx:  cancel,
 
@@ -224,6 +227,12 @@ func (client *Download) Read(data []byte) (read int, err error) {
 		if response != nil && response.Chunk != nil {
 			client.downloaded += int64(len(response.Chunk.Data))
 			client.unread.Fill(response.Chunk.Data)
+
+			// throttle the bytes received over the wire, if requested.
+			if waitErr := client.limiter.WaitN(ctx, len(response.Chunk.Data)); waitErr != nil {
+				client.unread.IncludeError(waitErr)
+				client.closeWithError(waitErr)
+			}
 		}
 		// This is a GET_REPAIR because we got a piece hash and the original order limit.
 		if response != nil && response.Hash != nil && response.Limit != nil {
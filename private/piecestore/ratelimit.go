@@ -0,0 +1,38 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package piecestore
+
+import (
+	"context"
+
+	"uplink/private/ratelimit"
+)
+
+type uploadLimiterKey struct{}
+
+type downloadLimiterKey struct{}
+
+// WithUploadLimiter sets the limiter used to throttle the bytes sent to
+// storage nodes by piece uploads.
+func WithUploadLimiter(ctx context.Context, limiter *ratelimit.Limiter) context.Context {
+	return context.WithValue(ctx, uploadLimiterKey{}, limiter)
+}
+
+// GetUploadLimiter returns the upload limiter or nil if none was set.
+func GetUploadLimiter(ctx context.Context) *ratelimit.Limiter {
+	limiter, _ := ctx.Value(uploadLimiterKey{}).(*ratelimit.Limiter)
+	return limiter
+}
+
+// WithDownloadLimiter sets the limiter used to throttle the bytes received
+// from storage nodes by piece downloads.
+func WithDownloadLimiter(ctx context.Context, limiter *ratelimit.Limiter) context.Context {
+	return context.WithValue(ctx, downloadLimiterKey{}, limiter)
+}
+
+// GetDownloadLimiter returns the download limiter or nil if none was set.
+func GetDownloadLimiter(ctx context.Context) *ratelimit.Limiter {
+	limiter, _ := ctx.Value(downloadLimiterKey{}).(*ratelimit.Limiter)
+	return limiter
+}
@@ -20,6 +20,7 @@ import (
 	"common/storx"
 	"common/sync2"
 	"drpc"
+	"uplink/private/ratelimit"
 )
 
 var mon = monkit.Package()
@@ -31,6 +32,7 @@ type upload struct {
 	privateKey storx.PiecePrivateKey
 	nodeID     storx.NodeID
 	stream     uploadStream
+	limiter    *ratelimit.Limiter
 
 	hash          hash.Hash // TODO: use concrete implementation
 	hashAlgorithm pb.PieceHashAlgorithm
@@ -118,6 +120,7 @@ func (client *Client) UploadReader(ctx context.Context, limit *pb.OrderLimit, pi
 		privateKey:    piecePrivateKey,
 		nodeID:        limit.StorageNodeId,
 		stream:        stream,
+		limiter:       GetUploadLimiter(ctx),
 		hash:          pb.NewHashFromAlgorithm(client.UploadHashAlgo),
 		hashAlgorithm: client.UploadHashAlgo,
 		offset:        0,
@@ -174,6 +177,11 @@ func (client *upload) write(ctx context.Context, data io.Reader) (hash *pb.Piece
 		}
 		sendData = sendData[:n]
 
+		// throttle the bytes sent over the wire, if requested.
+		if err := client.limiter.WaitN(ctx, len(sendData)); err != nil {
+			return nil, err
+		}
+
 		req := &pb.PieceUploadRequest{
 			Chunk: &pb.PieceUploadRequest_Chunk{
 				Offset: client.offset,
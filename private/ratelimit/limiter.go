@@ -0,0 +1,133 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket that limits the rate at which bytes can be
+// transferred. A nil Limiter does not limit anything.
+//
+// Callers are allowed to go into debt: WaitN always accounts for the full
+// amount requested and blocks until the bucket has been refilled enough to
+// cover it. This lets callers pass in chunks larger than the burst size
+// without having to split them.
+type Limiter struct {
+	rate   float64  // bytes per second
+	burst  float64  // maximum number of tokens the bucket can hold
+	parent *Limiter // limits the bytes allowed by this limiter as well
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter returns a Limiter that allows bytesPerSecond bytes to be
+// transferred every second, with bursts of up to one second worth of bytes.
+// It returns nil, which does not limit, if bytesPerSecond is not positive.
+func NewLimiter(bytesPerSecond int64) *Limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &Limiter{
+		rate:   float64(bytesPerSecond),
+		burst:  float64(bytesPerSecond),
+		tokens: float64(bytesPerSecond),
+		last:   time.Now(),
+	}
+}
+
+// Child returns a Limiter that allows bytesPerSecond bytes to be transferred
+// every second, and also waits for l, so that the transfers are limited by
+// both. It returns l if bytesPerSecond is not positive.
+func (l *Limiter) Child(bytesPerSecond int64) *Limiter {
+	child := NewLimiter(bytesPerSecond)
+	if child == nil {
+		return l
+	}
+	child.parent = l
+	return child
+}
+
+// Rate returns the number of bytes per second allowed by the limiter, or 0 if
+// the limiter does not limit.
+func (l *Limiter) Rate() int64 {
+	if l == nil {
+		return 0
+	}
+	return int64(l.rate)
+}
+
+// WaitN blocks until n bytes are allowed to be transferred or until the
+// context is canceled. The bytes are reserved from l and all of its parents
+// at once, and it waits for the longest of their delays. When the context is
+// canceled the reserved bytes are returned to the buckets.
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return ctx.Err()
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	for x := l; x != nil; x = x.parent {
+		if d := x.reserve(now, n); d > wait {
+			wait = d
+		}
+	}
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			for x := l; x != nil; x = x.parent {
+				x.release(n)
+			}
+			return ctx.Err()
+		}
+	}
+	return ctx.Err()
+}
+
+// reserve takes n tokens out of the bucket and returns how long the caller
+// must wait until the bucket is out of debt.
+func (l *Limiter) reserve(now time.Time, n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill(now)
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// release returns n tokens to the bucket.
+func (l *Limiter) release(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill(time.Now())
+	l.tokens += float64(n)
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
+
+// refill adds the tokens accumulated since the last refill.
+func (l *Limiter) refill(now time.Time) {
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens += elapsed.Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_Nil(t *testing.T) {
+	ctx := context.Background()
+
+	require.Nil(t, NewLimiter(0))
+	require.Nil(t, NewLimiter(-1))
+
+	var l *Limiter
+	require.NoError(t, l.WaitN(ctx, 1<<30))
+	require.Zero(t, l.Rate())
+}
+
+func TestLimiter_Reserve(t *testing.T) {
+	l := NewLimiter(1000)
+	now := l.last
+
+	// the initial burst is available immediately
+	require.Zero(t, l.reserve(now, 1000))
+
+	// going into debt requires waiting for the debt to be paid off
+	require.Equal(t, 500*time.Millisecond, l.reserve(now, 500))
+
+	// after the debt has been paid off, more tokens accumulate
+	require.Zero(t, l.reserve(now.Add(time.Second), 500))
+
+	// tokens never accumulate past the burst size
+	require.Zero(t, l.reserve(now.Add(time.Hour), 1000))
+	require.Equal(t, time.Second, l.reserve(now.Add(time.Hour), 1000))
+}
+
+func TestLimiter_WaitN(t *testing.T) {
+	ctx := context.Background()
+
+	l := NewLimiter(10000)
+	require.NoError(t, l.WaitN(ctx, 10000))
+
+	start := time.Now()
+	require.NoError(t, l.WaitN(ctx, 500))
+	require.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestLimiter_WaitNCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	l := NewLimiter(100)
+	require.NoError(t, l.WaitN(ctx, 100))
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	require.ErrorIs(t, l.WaitN(ctx, 100000), context.Canceled)
+
+	// the canceled reservation must be returned to the bucket
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	require.Greater(t, l.tokens, float64(-100))
+}
+
+func TestLimiter_Child(t *testing.T) {
+	ctx := context.Background()
+
+	parent := NewLimiter(10000)
+	require.Equal(t, parent, parent.Child(0))
+
+	var none *Limiter
+	require.Equal(t, int64(1000), none.Child(1000).Rate())
+
+	child := parent.Child(1000000)
+	require.Equal(t, int64(1000000), child.Rate())
+	require.NoError(t, child.WaitN(ctx, 10000))
+
+	// the child has tokens left, but the parent is out of them
+	start := time.Now()
+	require.NoError(t, child.WaitN(ctx, 500))
+	require.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+	// the bytes are charged to the parent, even when the child is faster
+	parent.mu.Lock()
+	require.Less(t, parent.tokens, float64(0))
+	parent.mu.Unlock()
+}
+
+func TestLimiter_ChildWaitsForSlowest(t *testing.T) {
+	ctx := context.Background()
+
+	parent := NewLimiter(10000)
+	child := parent.Child(10000)
+	require.NoError(t, child.WaitN(ctx, 10000))
+
+	// both are out of tokens and need 100ms to pay off the debt. They are
+	// waited for at the same time, not one after the other.
+	start := time.Now()
+	require.NoError(t, child.WaitN(ctx, 1000))
+	elapsed := time.Since(start)
+	require.GreaterOrEqual(t, elapsed, 90*time.Millisecond)
+	require.Less(t, elapsed, 180*time.Millisecond)
+}
+
+func TestLimiter_ChildWaitNCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	parent := NewLimiter(100)
+	child := parent.Child(1000)
+
+	require.ErrorIs(t, child.WaitN(ctx, 100000), context.Canceled)
+
+	// the canceled reservation is returned to both buckets
+	for _, l := range []*Limiter{parent, child} {
+		l.mu.Lock()
+		require.Greater(t, l.tokens, float64(0))
+		l.mu.Unlock()
+	}
+}
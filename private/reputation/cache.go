@@ -0,0 +1,294 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package reputation
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"common/storx"
+)
+
+// Outcome is the result of a transfer with a node.
+type Outcome int
+
+const (
+	// Success means the transfer completed.
+	Success Outcome = iota
+	// DialFailure means the node could not be dialed.
+	DialFailure
+	// Timeout means the transfer was cut because the node was too slow.
+	Timeout
+	// Failure means the transfer failed for any other reason.
+	Failure
+)
+
+// String implements fmt.Stringer.
+func (o Outcome) String() string {
+	switch o {
+	case Success:
+		return "success"
+	case DialFailure:
+		return "dial failure"
+	case Timeout:
+		return "timeout"
+	case Failure:
+		return "failure"
+	default:
+		return "unknown"
+	}
+}
+
+// badScore is the score below which a node is considered bad.
+const badScore = 0.5
+
+// Options controls the parameters of a Cache.
+type Options struct {
+	// MaximumNodes is the number of nodes to keep track of. When more nodes
+	// are recorded, the least recently recorded ones are forgotten.
+	MaximumNodes int
+
+	// HalfLife is how long it takes for an outcome to count half as much.
+	HalfLife time.Duration
+
+	// RecentFailure is how long a failure, not followed by a success, makes
+	// a node recently failed.
+	RecentFailure time.Duration
+}
+
+// DefaultOptions are the default options for a Cache.
+var DefaultOptions = Options{
+	MaximumNodes:  10000,
+	HalfLife:      10 * time.Minute,
+	RecentFailure: 5 * time.Minute,
+}
+
+// Cache keeps a bounded, time-decaying record of the outcomes of transfers
+// with nodes. A nil Cache records nothing and knows nothing.
+type Cache struct {
+	opts Options
+	now  func() time.Time
+
+	mu    sync.Mutex
+	nodes map[storx.NodeID]*entry
+}
+
+// NodeStats describes what is known about a node. The counts are decayed
+// over time, so they are not whole numbers.
+type NodeStats struct {
+	NodeID       storx.NodeID
+	Successes    float64
+	DialFailures float64
+	Timeouts     float64
+	Failures     float64
+	Throughput   float64 // bytes per second
+	LastSuccess  time.Time
+	LastFailure  time.Time
+	Score        float64
+}
+
+type entry struct {
+	updated      time.Time
+	successes    float64
+	dialFailures float64
+	timeouts     float64
+	failures     float64
+	throughput   float64
+	lastSuccess  time.Time
+	lastFailure  time.Time
+}
+
+// New returns a new Cache.
+func New(opts Options) *Cache {
+	if opts.MaximumNodes <= 0 {
+		opts.MaximumNodes = DefaultOptions.MaximumNodes
+	}
+	if opts.HalfLife <= 0 {
+		opts.HalfLife = DefaultOptions.HalfLife
+	}
+	if opts.RecentFailure <= 0 {
+		opts.RecentFailure = DefaultOptions.RecentFailure
+	}
+	return &Cache{
+		opts:  opts,
+		now:   time.Now,
+		nodes: make(map[storx.NodeID]*entry),
+	}
+}
+
+// Record records the outcome of a transfer with a node. For successful
+// transfers, the number of bytes and how long it took is used to track the
+// throughput of the node.
+func (c *Cache) Record(id storx.NodeID, outcome Outcome, bytes int64, duration time.Duration) {
+	if c == nil || id.IsZero() {
+		return
+	}
+
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.nodes[id]
+	if !ok {
+		if len(c.nodes) >= c.opts.MaximumNodes {
+			c.evictOldest()
+		}
+		e = &entry{updated: now}
+		c.nodes[id] = e
+	}
+	c.decay(e, now)
+
+	switch outcome {
+	case Success:
+		e.successes++
+		e.lastSuccess = now
+		if bytes > 0 && duration > 0 {
+			throughput := float64(bytes) / duration.Seconds()
+			if e.throughput == 0 {
+				e.throughput = throughput
+			} else {
+				e.throughput = 0.7*e.throughput + 0.3*throughput
+			}
+		}
+	case DialFailure:
+		e.dialFailures++
+		e.lastFailure = now
+	case Timeout:
+		e.timeouts++
+		e.lastFailure = now
+	default:
+		e.failures++
+		e.lastFailure = now
+	}
+}
+
+// Score returns a number between 0 and 1 describing how reliable the node
+// has been recently. Unknown nodes have a score of 1.
+func (c *Cache) Score(id storx.NodeID) float64 {
+	if c == nil {
+		return 1
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.nodes[id]
+	if !ok {
+		return 1
+	}
+	c.decay(e, c.now())
+	return e.score()
+}
+
+// Bad returns true if the node has failed much more often than it has
+// succeeded recently.
+func (c *Cache) Bad(id storx.NodeID) bool {
+	return c.Score(id) < badScore
+}
+
+// RecentlyFailed returns true if the last transfer with the node failed
+// recently.
+func (c *Cache) RecentlyFailed(id storx.NodeID) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.nodes[id]
+	if !ok {
+		return false
+	}
+	return e.lastFailure.After(e.lastSuccess) && c.now().Sub(e.lastFailure) < c.opts.RecentFailure
+}
+
+// Throughput returns the recent throughput of the node in bytes per second,
+// or 0 if it is unknown.
+func (c *Cache) Throughput(id storx.NodeID) float64 {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.nodes[id]; ok {
+		return e.throughput
+	}
+	return 0
+}
+
+// Stats returns what is known about every node, worst scores first.
+func (c *Cache) Stats() []NodeStats {
+	if c == nil {
+		return nil
+	}
+
+	now := c.now()
+
+	c.mu.Lock()
+	stats := make([]NodeStats, 0, len(c.nodes))
+	for id, e := range c.nodes {
+		c.decay(e, now)
+		stats = append(stats, NodeStats{
+			NodeID:       id,
+			Successes:    e.successes,
+			DialFailures: e.dialFailures,
+			Timeouts:     e.timeouts,
+			Failures:     e.failures,
+			Throughput:   e.throughput,
+			LastSuccess:  e.lastSuccess,
+			LastFailure:  e.lastFailure,
+			Score:        e.score(),
+		})
+	}
+	c.mu.Unlock()
+
+	sort.Slice(stats, func(i, k int) bool {
+		if stats[i].Score != stats[k].Score {
+			return stats[i].Score < stats[k].Score
+		}
+		return stats[i].NodeID.Less(stats[k].NodeID)
+	})
+	return stats
+}
+
+// decay scales down the counts of e for the time passed since it was last
+// updated. It must be called with the mutex held.
+func (c *Cache) decay(e *entry, now time.Time) {
+	elapsed := now.Sub(e.updated)
+	if elapsed <= 0 {
+		return
+	}
+	factor := math.Exp2(-elapsed.Seconds() / c.opts.HalfLife.Seconds())
+	e.successes *= factor
+	e.dialFailures *= factor
+	e.timeouts *= factor
+	e.failures *= factor
+	e.updated = now
+}
+
+// evictOldest forgets the least recently updated node. It must be called
+// with the mutex held.
+func (c *Cache) evictOldest() {
+	var oldestID storx.NodeID
+	var oldest *entry
+	for id, e := range c.nodes {
+		if oldest == nil || e.updated.Before(oldest.updated) {
+			oldestID, oldest = id, e
+		}
+	}
+	delete(c.nodes, oldestID)
+}
+
+// score weighs dial failures and timeouts more than other failures, since
+// they are more likely to happen again.
+func (e *entry) score() float64 {
+	good := e.successes + 1
+	return good / (good + 2*e.dialFailures + 2*e.timeouts + e.failures)
+}
@@ -0,0 +1,81 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package reputation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"common/storx"
+)
+
+func TestCache(t *testing.T) {
+	now := time.Now()
+
+	c := New(Options{HalfLife: time.Minute, RecentFailure: time.Minute})
+	c.now = func() time.Time { return now }
+
+	good, bad, unknown := storx.NodeID{1}, storx.NodeID{2}, storx.NodeID{3}
+
+	c.Record(good, Success, 1000, time.Second)
+	c.Record(good, Success, 3000, time.Second)
+	c.Record(bad, DialFailure, 0, 0)
+	c.Record(bad, Timeout, 0, 0)
+
+	require.Equal(t, 1.0, c.Score(unknown))
+	require.False(t, c.Bad(unknown))
+	require.False(t, c.RecentlyFailed(unknown))
+
+	require.False(t, c.Bad(good))
+	require.False(t, c.RecentlyFailed(good))
+	require.InDelta(t, 1600, c.Throughput(good), 1)
+
+	require.True(t, c.Bad(bad))
+	require.True(t, c.RecentlyFailed(bad))
+
+	stats := c.Stats()
+	require.Len(t, stats, 2)
+	require.Equal(t, bad, stats[0].NodeID)
+	require.Equal(t, good, stats[1].NodeID)
+
+	// failures are forgotten over time.
+	now = now.Add(10 * time.Minute)
+	require.False(t, c.Bad(bad))
+	require.False(t, c.RecentlyFailed(bad))
+
+	// a success clears a recent failure.
+	c.Record(good, Failure, 0, 0)
+	require.True(t, c.RecentlyFailed(good))
+	c.Record(good, Success, 0, 0)
+	require.False(t, c.RecentlyFailed(good))
+}
+
+func TestCacheBounded(t *testing.T) {
+	now := time.Now()
+
+	c := New(Options{MaximumNodes: 2})
+	c.now = func() time.Time { return now }
+
+	for i := byte(1); i <= 3; i++ {
+		c.Record(storx.NodeID{i}, Failure, 0, 0)
+		now = now.Add(time.Second)
+	}
+
+	var ids []storx.NodeID
+	for _, stat := range c.Stats() {
+		ids = append(ids, stat.NodeID)
+	}
+	require.ElementsMatch(t, []storx.NodeID{{2}, {3}}, ids)
+}
+
+func TestCacheNil(t *testing.T) {
+	var c *Cache
+	c.Record(storx.NodeID{1}, Failure, 0, 0)
+	require.Equal(t, 1.0, c.Score(storx.NodeID{1}))
+	require.False(t, c.RecentlyFailed(storx.NodeID{1}))
+	require.Zero(t, c.Throughput(storx.NodeID{1}))
+	require.Empty(t, c.Stats())
+}
@@ -0,0 +1,186 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package buffer
+
+import (
+	"context"
+	"sync"
+
+	"github.com/zeebo/errs"
+)
+
+// Budget limits the number of bytes held by backends across many buffers.
+// A nil Budget does not limit anything.
+type Budget struct {
+	limit int64
+
+	mu   sync.Mutex
+	used int64
+	wake chan struct{} // closed and replaced whenever bytes are released
+}
+
+// NewBudget returns a Budget that allows at most limit bytes to be acquired
+// at once. It returns nil, which does not limit, if limit is not positive.
+func NewBudget(limit int64) *Budget {
+	if limit <= 0 {
+		return nil
+	}
+	return &Budget{
+		limit: limit,
+		wake:  make(chan struct{}),
+	}
+}
+
+// Limit returns the maximum number of bytes that can be acquired at once, or
+// 0 if the budget does not limit.
+func (b *Budget) Limit() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.limit
+}
+
+// Used returns the number of bytes currently acquired.
+func (b *Budget) Used() int64 {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used
+}
+
+// Acquire blocks until n bytes are available or the context is canceled. A
+// request for more than the limit is reduced to the limit so that it can
+// eventually succeed. It returns the number of bytes acquired, which must be
+// given back with Release.
+func (b *Budget) Acquire(ctx context.Context, n int64) (int64, error) {
+	if b == nil || n <= 0 {
+		return 0, ctx.Err()
+	}
+	if n > b.limit {
+		n = b.limit
+	}
+
+	for {
+		b.mu.Lock()
+		if b.used+n <= b.limit {
+			b.used += n
+			b.mu.Unlock()
+			return n, nil
+		}
+		wake := b.wake
+		b.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// Release gives back n bytes previously returned by Acquire.
+func (b *Budget) Release(n int64) {
+	if b == nil || n <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.used -= n
+	if b.used < 0 {
+		b.used = 0
+	}
+	close(b.wake)
+	b.wake = make(chan struct{})
+}
+
+// BudgetedBackend wraps a Backend so that it holds bytes from a Budget
+// while it is in use. The bytes are acquired on the first Write, so that
+// creating a backend never blocks, and are released on Close.
+type BudgetedBackend struct {
+	ctx     context.Context
+	backend Backend
+	budget  *Budget
+	size    int64
+
+	mu       sync.Mutex
+	closed   bool
+	reserved int64
+}
+
+// NewBudgetedBackend returns a BudgetedBackend that acquires size bytes from
+// budget before the first byte is written to backend. The context is used to
+// stop waiting for the budget.
+func NewBudgetedBackend(ctx context.Context, backend Backend, budget *Budget, size int64) *BudgetedBackend {
+	return &BudgetedBackend{
+		ctx:     ctx,
+		backend: backend,
+		budget:  budget,
+		size:    size,
+	}
+}
+
+// Write acquires the bytes from the budget if not yet done and then writes
+// p to the underlying backend.
+func (b *BudgetedBackend) Write(p []byte) (n int, err error) {
+	if err := b.reserve(); err != nil {
+		return 0, err
+	}
+	return b.backend.Write(p)
+}
+
+// ReadAt reads from the underlying backend.
+func (b *BudgetedBackend) ReadAt(p []byte, off int64) (n int, err error) {
+	return b.backend.ReadAt(p, off)
+}
+
+// Close closes the underlying backend and releases the acquired bytes.
+func (b *BudgetedBackend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+
+	b.budget.Release(b.reserved)
+	b.reserved = 0
+
+	return b.backend.Close()
+}
+
+func (b *BudgetedBackend) reserve() error {
+	b.mu.Lock()
+	switch {
+	case b.closed:
+		b.mu.Unlock()
+		return errs.New("write to closed backend")
+	case b.reserved > 0 || b.budget == nil:
+		b.mu.Unlock()
+		return nil
+	}
+	b.mu.Unlock()
+
+	// N.B. the budget is acquired without holding the mutex so that Close
+	// is never blocked behind a waiting writer. Writes are serialized by the
+	// Buffer, so only one acquire is in flight at a time.
+	n, err := b.budget.Acquire(b.ctx, b.size)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		b.budget.Release(n)
+		return errs.New("write to closed backend")
+	}
+	b.reserved = n
+	return nil
+}
@@ -0,0 +1,114 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package buffer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudget(t *testing.T) {
+	ctx := context.Background()
+
+	b := NewBudget(10)
+	require.EqualValues(t, 10, b.Limit())
+
+	n, err := b.Acquire(ctx, 6)
+	require.NoError(t, err)
+	require.EqualValues(t, 6, n)
+	require.EqualValues(t, 6, b.Used())
+
+	// requests larger than the limit are reduced to the limit
+	acquired := make(chan int64)
+	go func() {
+		n, _ := b.Acquire(ctx, 100)
+		acquired <- n
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquired past the limit")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	b.Release(6)
+	require.EqualValues(t, 10, <-acquired)
+	require.EqualValues(t, 10, b.Used())
+
+	b.Release(10)
+	require.Zero(t, b.Used())
+}
+
+func TestBudgetCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	b := NewBudget(10)
+	_, err := b.Acquire(ctx, 10)
+	require.NoError(t, err)
+
+	cancel()
+	n, err := b.Acquire(ctx, 1)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Zero(t, n)
+	require.EqualValues(t, 10, b.Used())
+}
+
+func TestBudgetNil(t *testing.T) {
+	ctx := context.Background()
+
+	require.Nil(t, NewBudget(0))
+
+	var b *Budget
+	n, err := b.Acquire(ctx, 100)
+	require.NoError(t, err)
+	require.Zero(t, n)
+	require.Zero(t, b.Used())
+	require.Zero(t, b.Limit())
+	b.Release(100)
+}
+
+func TestBudgetedBackend(t *testing.T) {
+	ctx := context.Background()
+
+	budget := NewBudget(10)
+	first := NewBudgetedBackend(ctx, NewMemoryBackend(0), budget, 8)
+	second := NewBudgetedBackend(ctx, NewMemoryBackend(0), budget, 8)
+
+	// creating a backend does not acquire anything
+	require.Zero(t, budget.Used())
+
+	_, err := first.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.EqualValues(t, 8, budget.Used())
+
+	wrote := make(chan error)
+	go func() {
+		_, err := second.Write([]byte("world"))
+		wrote <- err
+	}()
+
+	select {
+	case <-wrote:
+		t.Fatal("wrote past the budget")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	require.NoError(t, first.Close())
+	require.NoError(t, <-wrote)
+	require.EqualValues(t, 8, budget.Used())
+
+	var buf [5]byte
+	n, err := second.ReadAt(buf[:], 0)
+	require.NoError(t, err)
+	require.Equal(t, "world", string(buf[:n]))
+
+	require.NoError(t, second.Close())
+	require.Zero(t, budget.Used())
+
+	_, err = second.Write([]byte("again"))
+	require.Error(t, err)
+}
@@ -0,0 +1,131 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package buffer
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"github.com/zeebo/errs"
+)
+
+// SpillBackend implements the Backend interface by keeping bytes in memory
+// until more than a threshold has been written, after which all of the bytes
+// are moved into a temporary file.
+type SpillBackend struct {
+	dir       string
+	threshold int64
+
+	mu     sync.RWMutex
+	closed bool
+	mem    []byte
+	file   *os.File
+	size   int64
+}
+
+// NewSpillBackend returns a SpillBackend that keeps up to threshold bytes in
+// memory before spilling them into a temporary file in dir. If dir is empty,
+// the default directory for temporary files is used.
+func NewSpillBackend(dir string, threshold int64) *SpillBackend {
+	return &SpillBackend{
+		dir:       dir,
+		threshold: threshold,
+	}
+}
+
+// Threshold returns the maximum number of bytes the backend keeps in memory.
+func (s *SpillBackend) Threshold() int64 { return s.threshold }
+
+// Spilled returns true if the bytes have been moved into a temporary file.
+func (s *SpillBackend) Spilled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.file != nil
+}
+
+// Write appends the data to the backend, spilling to disk if needed.
+func (s *SpillBackend) Write(p []byte) (n int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, errs.New("write to closed backend")
+	}
+
+	if s.file == nil && s.size+int64(len(p)) > s.threshold {
+		if err := s.spill(); err != nil {
+			return 0, err
+		}
+	}
+
+	if s.file == nil {
+		s.mem = append(s.mem, p...)
+		n = len(p)
+	} else {
+		n, err = s.file.Write(p)
+	}
+	s.size += int64(n)
+
+	return n, errs.Wrap(err)
+}
+
+// spill moves the bytes held in memory into a new temporary file. It must be
+// called with the mutex held.
+func (s *SpillBackend) spill() error {
+	file, err := os.CreateTemp(s.dir, "uplink-segment-*")
+	if err != nil {
+		return errs.Wrap(err)
+	}
+
+	if _, err := file.Write(s.mem); err != nil {
+		return errs.Combine(errs.Wrap(err), file.Close(), os.Remove(file.Name()))
+	}
+
+	s.file = file
+	s.mem = nil
+	return nil
+}
+
+// ReadAt reads into the provided buffer p starting at off.
+func (s *SpillBackend) ReadAt(p []byte, off int64) (n int, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	switch {
+	case s.closed:
+		return 0, errs.New("read from closed backend")
+	case off >= s.size:
+		return 0, io.EOF
+	case s.file == nil:
+		return copy(p, s.mem[off:]), nil
+	}
+
+	if rem := s.size - off; int64(len(p)) > rem {
+		p = p[:rem]
+	}
+	n, err = s.file.ReadAt(p, off)
+	if n == len(p) && errs.Is(err, io.EOF) {
+		err = nil
+	}
+	return n, err
+}
+
+// Close releases the memory and removes the temporary file, if any.
+func (s *SpillBackend) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	s.mem = nil
+
+	if s.file == nil {
+		return nil
+	}
+	return errs.Combine(s.file.Close(), os.Remove(s.file.Name()))
+}
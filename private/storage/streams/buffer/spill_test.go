@@ -0,0 +1,75 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package buffer
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpillBackend(t *testing.T) {
+	dir := t.TempDir()
+
+	b := NewSpillBackend(dir, 8)
+
+	_, err := b.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.False(t, b.Spilled())
+
+	var buf [16]byte
+	n, err := b.ReadAt(buf[:], 1)
+	require.NoError(t, err)
+	require.Equal(t, "ello", string(buf[:n]))
+
+	_, err = b.Write([]byte(" world"))
+	require.NoError(t, err)
+	require.True(t, b.Spilled())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	n, err = b.ReadAt(buf[:], 0)
+	require.NoError(t, err)
+	require.Equal(t, "hello world", string(buf[:n]))
+
+	n, err = b.ReadAt(buf[:5], 6)
+	require.NoError(t, err)
+	require.Equal(t, "world", string(buf[:n]))
+
+	_, err = b.ReadAt(buf[:], 11)
+	require.ErrorIs(t, err, io.EOF)
+
+	require.NoError(t, b.Close())
+	require.NoError(t, b.Close())
+
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	_, err = b.Write([]byte("again"))
+	require.Error(t, err)
+}
+
+func TestSpillBackendBuffer(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	buf := New(NewSpillBackend(t.TempDir(), 100), 10)
+	go func() {
+		_, err := buf.Write(data)
+		buf.DoneWriting(err)
+	}()
+
+	got, err := io.ReadAll(buf.Reader())
+	require.NoError(t, err)
+	require.Equal(t, data, got)
+
+	buf.DoneReading(nil)
+}
@@ -121,3 +121,25 @@ func nodeID(num piecenum, rev revision) pb.NodeID {
 	binary.LittleEndian.PutUint64(id[8:], uint64(rev.value))
 	return id
 }
+
+type avoidingExchanger struct {
+	fakeExchanger
+	avoid map[storx.NodeID]bool
+}
+
+func newAvoidingExchanger(pieceCount int, avoid ...storx.NodeID) *avoidingExchanger {
+	exchanger := &avoidingExchanger{
+		fakeExchanger: fakeExchanger{
+			limits: map[byte][]*pb.AddressedOrderLimit{0: makeLimits(pieceCount)},
+		},
+		avoid: make(map[storx.NodeID]bool),
+	}
+	for _, id := range avoid {
+		exchanger.avoid[id] = true
+	}
+	return exchanger
+}
+
+func (a *avoidingExchanger) AvoidNode(id storx.NodeID) bool {
+	return a.avoid[id]
+}
@@ -31,6 +31,14 @@ type LimitsExchanger interface {
 	ExchangeLimits(ctx context.Context, segmentID storx.SegmentID, pieceNumbers []int) (storx.SegmentID, []*pb.AddressedOrderLimit, error)
 }
 
+// NodeAvoider can be implemented by a LimitsExchanger to have limits for
+// nodes it wants to avoid exchanged before uploads to them are attempted.
+// Each piece is only exchanged this way once, so uploads make progress even
+// if the satellite keeps handing out the same nodes.
+type NodeAvoider interface {
+	AvoidNode(id storx.NodeID) bool
+}
+
 // Manager tracks piece uploads for a segment. It provides callers with piece
 // data and limits and tracks which uploads have been successful (or not). It
 // also manages obtaining new piece upload limits for failed uploads to
@@ -48,23 +56,53 @@ type Manager struct {
 	exchange  chan struct{}
 	done      chan struct{}
 	failed    []int
+	avoided   map[int]bool
 	results   []*pb.SegmentPieceUploadResult
 }
 
 // NewManager returns a new piece upload manager.
 func NewManager(exchanger LimitsExchanger, pieceReader PieceReader, segmentID storx.SegmentID, limits []*pb.AddressedOrderLimit) *Manager {
-	next := make(chan int, len(limits))
-	for num := 0; num < len(limits); num++ {
-		next <- num
-	}
-	return &Manager{
+	mgr := &Manager{
 		exchanger:   exchanger,
 		pieceReader: pieceReader,
 		segmentID:   segmentID,
 		limits:      limits,
-		next:        next,
+		next:        make(chan int, len(limits)),
 		exchange:    make(chan struct{}, 1),
 		done:        make(chan struct{}),
+		avoided:     make(map[int]bool),
+	}
+	nums := make([]int, 0, len(limits))
+	for num := 0; num < len(limits); num++ {
+		nums = append(nums, num)
+	}
+	mgr.queue(nums)
+	return mgr
+}
+
+// queue makes the pieces available to NextPiece, except for the ones with
+// limits for nodes the exchanger wants to avoid, which are marked as failed
+// so that they are exchanged. It must be called with the mutex held or
+// before the manager is shared.
+func (mgr *Manager) queue(nums []int) {
+	avoider, _ := mgr.exchanger.(NodeAvoider)
+	for _, num := range nums {
+		limit := mgr.limits[num]
+		if avoider != nil && !mgr.avoided[num] && limit != nil && avoider.AvoidNode(limit.Limit.StorageNodeId) {
+			mgr.avoided[num] = true
+			mgr.failed = append(mgr.failed, num)
+			continue
+		}
+		mgr.next <- num
+	}
+
+	// if every piece was avoided, nothing is in flight to trigger the
+	// exchange, so trigger it now.
+	if len(nums) > 0 && len(mgr.failed) == len(nums) {
+		select {
+		case mgr.exchange <- struct{}{}:
+		default:
+		}
 	}
 }
 
@@ -181,9 +219,8 @@ func (mgr *Manager) exchangeLimits(ctx context.Context) error {
 	}
 	mgr.segmentID = segmentID
 	mgr.limits = limits
-	for _, num := range mgr.failed {
-		mgr.next <- num
-	}
+	failed := append([]int(nil), mgr.failed...)
 	mgr.failed = mgr.failed[:0]
+	mgr.queue(failed)
 	return nil
 }
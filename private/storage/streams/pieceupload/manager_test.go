@@ -68,6 +68,36 @@ func TestManager(t *testing.T) {
 		_, _, _, err := manager.NextPiece(context.Background())
 		require.EqualError(t, err, "piece limit exchange failed: oh no")
 	})
+
+	t.Run("limits for avoided nodes are exchanged", func(t *testing.T) {
+		manager := newManagerWithExchanger(2, newAvoidingExchanger(2, nodeID(piecenum{1}, revision{0})))
+
+		// 1(0) is avoided, so only 0(0) is attempted before the exchange
+		requireNextPieceAndFinish(t, manager, piecenum{0}, revision{0}, true)
+		requireNextPieceAndFinish(t, manager, piecenum{1}, revision{1}, true)
+
+		requireDone(t, manager)
+
+		assertResults(t, manager, revision{1},
+			makeResult(piecenum{0}, revision{0}),
+			makeResult(piecenum{1}, revision{1}),
+		)
+	})
+
+	t.Run("pieces are only avoided once", func(t *testing.T) {
+		manager := newManagerWithExchanger(1, newAvoidingExchanger(1,
+			nodeID(piecenum{0}, revision{0}),
+			nodeID(piecenum{0}, revision{1}),
+		))
+
+		requireNextPieceAndFinish(t, manager, piecenum{0}, revision{1}, true)
+
+		requireDone(t, manager)
+
+		assertResults(t, manager, revision{1},
+			makeResult(piecenum{0}, revision{1}),
+		)
+	})
 }
 
 func makeResult(num piecenum, rev revision) *pb.SegmentPieceUploadResult {
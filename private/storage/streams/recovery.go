@@ -0,0 +1,219 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package streams
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+
+	"common/ranger"
+)
+
+// RecoveryMode controls what a download does with data that cannot be
+// recovered.
+type RecoveryMode int
+
+const (
+	// RecoveryDisabled fails the download when data cannot be recovered.
+	RecoveryDisabled RecoveryMode = iota
+	// RecoveryZeroFill replaces data that cannot be recovered with zeros.
+	RecoveryZeroFill
+	// RecoverySkip leaves data that cannot be recovered out of the download.
+	RecoverySkip
+)
+
+// ByteRange is a range of bytes of a stream that was either recovered or
+// lost.
+type ByteRange struct {
+	Offset    int64
+	Length    int64
+	Recovered bool
+	// Err is why the range was lost.
+	Err error
+}
+
+// RecoveryManifest records which ranges of a stream were recovered and
+// which were lost during a download in recovery mode. It is safe for
+// concurrent use.
+type RecoveryManifest struct {
+	mu     sync.Mutex
+	ranges []ByteRange
+}
+
+// Ranges returns the ranges read so far, in order, with adjacent ranges of
+// the same kind merged.
+func (m *RecoveryManifest) Ranges() []ByteRange {
+	m.mu.Lock()
+	ranges := append([]ByteRange(nil), m.ranges...)
+	m.mu.Unlock()
+
+	sort.SliceStable(ranges, func(i, k int) bool {
+		return ranges[i].Offset < ranges[k].Offset
+	})
+
+	merged := ranges[:0]
+	for _, r := range ranges {
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			if last.Recovered && r.Recovered && last.Offset+last.Length == r.Offset {
+				last.Length += r.Length
+				continue
+			}
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// Lost returns the number of bytes that were lost.
+func (m *RecoveryManifest) Lost() (lost int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.ranges {
+		if !r.Recovered {
+			lost += r.Length
+		}
+	}
+	return lost
+}
+
+func (m *RecoveryManifest) add(offset, length int64, err error) {
+	if length <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ranges = append(m.ranges, ByteRange{
+		Offset:    offset,
+		Length:    length,
+		Recovered: err == nil,
+		Err:       err,
+	})
+}
+
+// recoveringRanger wraps the ranger of a segment, so that failing to read
+// from it records the lost range in the manifest instead of failing the
+// whole download.
+type recoveringRanger struct {
+	ranger.Ranger
+	offset   int64 // offset of the segment in the stream
+	mode     RecoveryMode
+	manifest *RecoveryManifest
+}
+
+// Range implements ranger.Ranger.
+func (rr *recoveringRanger) Range(ctx context.Context, offset, length int64) (_ io.ReadCloser, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	r := &recoveringReader{
+		mode:     rr.mode,
+		manifest: rr.manifest,
+		start:    rr.offset + offset,
+		offset:   rr.offset + offset,
+		length:   length,
+	}
+
+	reader, err := rr.Ranger.Range(ctx, offset, length)
+	if err != nil {
+		r.lose(err)
+		return r, nil
+	}
+	r.reader = reader
+	return r, nil
+}
+
+// recoveringReader reads from a segment until the first error, then records
+// the rest of the range as lost and either zero fills it or skips it.
+type recoveringReader struct {
+	mode     RecoveryMode
+	manifest *RecoveryManifest
+	reader   io.ReadCloser
+
+	start  int64 // where the data read so far starts in the stream
+	offset int64 // the current offset in the stream
+	length int64 // how much is left to read
+
+	done bool  // whether the data read so far was recorded
+	fill int64 // how many zeros are left to fill the lost range with
+}
+
+func (r *recoveringReader) Read(p []byte) (n int, err error) {
+	if r.done {
+		if r.fill <= 0 {
+			return 0, io.EOF
+		}
+		if int64(len(p)) > r.fill {
+			p = p[:r.fill]
+		}
+		for i := range p {
+			p[i] = 0
+		}
+		r.fill -= int64(len(p))
+		return len(p), nil
+	}
+
+	if r.length <= 0 {
+		r.finish()
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > r.length {
+		p = p[:r.length]
+	}
+	n, err = r.reader.Read(p)
+	r.offset += int64(n)
+	r.length -= int64(n)
+
+	switch {
+	case err == nil:
+		return n, nil
+	case errors.Is(err, io.EOF) && r.length <= 0:
+		r.finish()
+		return n, io.EOF
+	case errors.Is(err, io.EOF):
+		err = io.ErrUnexpectedEOF
+	}
+
+	mon.Event("recovery_download_lost_range")
+	r.lose(err)
+	return n, nil
+}
+
+// finish records the data read so far as recovered.
+func (r *recoveringReader) finish() {
+	if r.done {
+		return
+	}
+	r.done = true
+	r.manifest.add(r.start, r.offset-r.start, nil)
+}
+
+// lose records the data read so far as recovered and the rest of the range
+// as lost.
+func (r *recoveringReader) lose(err error) {
+	r.finish()
+	r.manifest.add(r.offset, r.length, err)
+	if r.mode == RecoveryZeroFill {
+		r.fill = r.length
+	}
+	r.offset += r.length
+	r.length = 0
+}
+
+// Close implements io.Closer. Errors closing the segment are ignored, since
+// whatever could be read from it was already recorded.
+func (r *recoveringReader) Close() error {
+	r.finish()
+	r.fill = 0
+	if r.reader != nil {
+		_ = r.reader.Close()
+	}
+	return nil
+}
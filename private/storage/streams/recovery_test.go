@@ -0,0 +1,97 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package streams
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"common/ranger"
+)
+
+func TestRecoveringRanger(t *testing.T) {
+	errLost := errors.New("lost")
+
+	read := func(t *testing.T, mode RecoveryMode) (string, []ByteRange) {
+		manifest := new(RecoveryManifest)
+		wrap := func(rr ranger.Ranger, offset int64) ranger.Ranger {
+			return &recoveringRanger{Ranger: rr, offset: offset, mode: mode, manifest: manifest}
+		}
+
+		segments := []ranger.Ranger{
+			wrap(ranger.ByteRanger("hello"), 0),
+			wrap(&invalidRanger{size: 5, err: errLost}, 5),
+			wrap(&failingRanger{data: "wor", size: 5, err: errLost}, 10),
+			wrap(ranger.ByteRanger("!"), 15),
+		}
+
+		var readers []io.Reader
+		for _, rr := range segments {
+			r, err := rr.Range(context.Background(), 0, rr.Size())
+			require.NoError(t, err)
+			defer func() { require.NoError(t, r.Close()) }()
+			readers = append(readers, r)
+		}
+
+		data, err := io.ReadAll(io.MultiReader(readers...))
+		require.NoError(t, err)
+		return string(data), manifest.Ranges()
+	}
+
+	expected := []ByteRange{
+		{Offset: 0, Length: 5, Recovered: true},
+		{Offset: 5, Length: 5, Err: errLost},
+		{Offset: 10, Length: 3, Recovered: true},
+		{Offset: 13, Length: 2, Err: errLost},
+		{Offset: 15, Length: 1, Recovered: true},
+	}
+
+	data, ranges := read(t, RecoveryZeroFill)
+	require.Equal(t, "hello\x00\x00\x00\x00\x00wor\x00\x00!", data)
+	require.Equal(t, expected, ranges)
+
+	data, ranges = read(t, RecoverySkip)
+	require.Equal(t, "hellowor!", data)
+	require.Equal(t, expected, ranges)
+}
+
+func TestRecoveryManifestMerges(t *testing.T) {
+	manifest := new(RecoveryManifest)
+	manifest.add(10, 5, nil)
+	manifest.add(0, 10, nil)
+	manifest.add(20, 5, io.ErrUnexpectedEOF)
+	manifest.add(15, 5, io.ErrUnexpectedEOF)
+
+	require.Equal(t, []ByteRange{
+		{Offset: 0, Length: 15, Recovered: true},
+		{Offset: 15, Length: 5, Err: io.ErrUnexpectedEOF},
+		{Offset: 20, Length: 5, Err: io.ErrUnexpectedEOF},
+	}, manifest.Ranges())
+	require.EqualValues(t, 10, manifest.Lost())
+}
+
+// failingRanger returns data and then fails with err.
+type failingRanger struct {
+	data string
+	size int64
+	err  error
+}
+
+func (f *failingRanger) Size() int64 { return f.size }
+
+func (f *failingRanger) Range(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	return io.NopCloser(io.MultiReader(
+		io.LimitReader(strings.NewReader(f.data), length),
+		errReader{f.err},
+	)), nil
+}
+
+type errReader struct{ err error }
+
+func (e errReader) Read([]byte) (int, error) { return 0, e.err }
@@ -5,8 +5,10 @@ package splitter
 
 import (
 	"context"
+	"errors"
 	"io"
 	"sync"
+	"sync/atomic"
 
 	"github.com/zeebo/errs"
 )
@@ -35,6 +37,10 @@ type baseSplitter struct {
 	written    int64              // how many bytes written into current
 	next       chan WriteFinisher // channel for the next split to write into
 	current    WriteFinisher      // current split being written to
+
+	expected int64 // how many bytes will be written, if positive
+	total    int64 // how many bytes were written so far, accessed atomically
+	planned  int64 // how many bytes the emitted splits cover, if expected
 }
 
 func newBaseSplitter(split, minimum int64) *baseSplitter {
@@ -48,8 +54,42 @@ func newBaseSplitter(split, minimum int64) *baseSplitter {
 	}
 }
 
+// expect tells the splitter how many bytes will be written, so that it can
+// plan the splits before their data is written. It must be called before
+// any other method.
+func (bs *baseSplitter) expect(size int64) {
+	if size <= 0 {
+		return
+	}
+	bs.expected = size
+	// a remote split can be emitted before anything is written into it.
+	bs.next = make(chan WriteFinisher, 1)
+}
+
+// nextSize returns the size of the next split and whether it is known. It
+// must only be called by the caller of Next.
+func (bs *baseSplitter) nextSize() (size int64, ok bool) {
+	if bs.expected <= 0 {
+		return 0, false
+	}
+	size = bs.expected - bs.planned
+	switch {
+	case size > bs.split:
+		size = bs.split
+	case size < 0:
+		size = 0
+	}
+	return size, true
+}
+
 func (bs *baseSplitter) Finish(err error) {
 	bs.finishOnce.Do(func() {
+		if err == nil && bs.expected > 0 {
+			if total := atomic.LoadInt64(&bs.total); total != bs.expected {
+				err = errs.New("finished after %d bytes, but expected %d", total, bs.expected)
+			}
+		}
+
 		bs.err = err
 		close(bs.term)
 		bs.currentMu.Lock()
@@ -57,6 +97,16 @@ func (bs *baseSplitter) Finish(err error) {
 			bs.current.DoneWriting(err)
 		}
 		bs.currentMu.Unlock()
+
+		// a split emitted before its data was written may still be waiting
+		// in next, and nothing else is going to finish it.
+		bs.nextMu.Lock()
+		select {
+		case wf := <-bs.next:
+			wf.DoneWriting(err)
+		default:
+		}
+		bs.nextMu.Unlock()
 	})
 }
 
@@ -65,6 +115,143 @@ func (bs *baseSplitter) Write(p []byte) (n int, err error) {
 	bs.writeMu.Lock()
 	defer bs.writeMu.Unlock()
 
+	return bs.write(p)
+}
+
+// splitReaderFrom is a split that can read data directly into itself.
+type splitReaderFrom interface {
+	// readFromN reads up to n bytes from r. It returns the error from r
+	// separately from an error writing into the split.
+	readFromN(r io.Reader, n int64) (read int64, rerr, werr error)
+}
+
+// readFromBufferSize is the most ReadFrom reads at once into a split that
+// can't read by itself.
+const readFromBufferSize = 256 * 1024
+
+// ReadFrom writes data from r until EOF or an error. It reads directly into
+// the temporary buffer, and then into the splits when they support it,
+// skipping the copy a Write would make.
+func (bs *baseSplitter) ReadFrom(r io.Reader) (n int64, err error) {
+	// only ever allow one Write or ReadFrom call at a time
+	bs.writeMu.Lock()
+	defer bs.writeMu.Unlock()
+
+	var buf []byte
+	var probe [1]byte
+	for {
+		select {
+		case <-bs.term:
+			if bs.err != nil {
+				return n, bs.err
+			}
+			return n, errs.New("already finished")
+		default:
+		}
+
+		var rerr error
+		rf, direct := bs.current.(splitReaderFrom)
+
+		switch {
+		case bs.current == nil && len(bs.temp) < cap(bs.temp):
+			var nn int
+			nn, rerr = r.Read(bs.temp[len(bs.temp):cap(bs.temp)])
+			bs.temp = bs.temp[:len(bs.temp)+nn]
+			n += int64(nn)
+
+			if err := bs.wrote(nn); err != nil {
+				return n, err
+			}
+
+		case direct && bs.written < bs.split:
+			rem := bs.split - bs.written
+			if bs.expected > 0 {
+				// read one byte more than expected to notice it.
+				if left := bs.expected - atomic.LoadInt64(&bs.total) + 1; left < rem {
+					rem = left
+				}
+			}
+
+			var nn int64
+			var werr error
+			nn, rerr, werr = rf.readFromN(r, rem)
+			bs.written += nn
+			n += nn
+
+			if err := bs.wrote(int(nn)); err != nil {
+				return n, err
+			}
+			if werr != nil {
+				bs.Finish(werr)
+				return n, werr
+			}
+
+		default:
+			// the next split only starts once there is data for it, so read
+			// a single byte until then and let write start it.
+			p := probe[:]
+			if bs.current != nil && bs.written < bs.split {
+				if buf == nil {
+					buf = make([]byte, readFromSize(r))
+				}
+				p = buf
+			}
+
+			var nn int
+			nn, rerr = r.Read(p)
+			if nn > 0 {
+				nn, err := bs.write(p[:nn])
+				n += int64(nn)
+				if err != nil {
+					return n, err
+				}
+			}
+		}
+
+		if errors.Is(rerr, io.EOF) {
+			return n, nil
+		} else if rerr != nil {
+			return n, rerr
+		}
+	}
+}
+
+// readFromSize returns how big a buffer ReadFrom should use for r, based on
+// how many bytes r has left if it can tell.
+func readFromSize(r io.Reader) int {
+	var hint int64 = -1
+	switch r := r.(type) {
+	case interface{ Len() int }:
+		hint = int64(r.Len())
+	case *io.LimitedReader:
+		hint = r.N
+	}
+
+	switch {
+	case hint < 0 || hint > readFromBufferSize:
+		return readFromBufferSize
+	case hint < 512:
+		// still large enough to see EOF without many small reads
+		return 512
+	default:
+		return int(hint)
+	}
+}
+
+// wrote records that n more bytes were written and fails the splitter if
+// that is more than expected.
+func (bs *baseSplitter) wrote(n int) error {
+	total := atomic.AddInt64(&bs.total, int64(n))
+	if bs.expected > 0 && total > bs.expected {
+		err := errs.New("wrote %d bytes, but expected %d", total, bs.expected)
+		bs.Finish(err)
+		return err
+	}
+	return nil
+}
+
+// write is Write without holding writeMu.
+func (bs *baseSplitter) write(p []byte) (n int, err error) {
 	select {
 	case <-bs.term:
 		if bs.err != nil {
@@ -74,6 +261,11 @@ func (bs *baseSplitter) Write(p []byte) (n int, err error) {
 	default:
 	}
 
+	if bs.expected > 0 && atomic.LoadInt64(&bs.total)+int64(len(p)) > bs.expected {
+		return 0, bs.wrote(len(p))
+	}
+	defer func() { _ = bs.wrote(n) }()
+
 	for len(p) > 0 {
 		// if we have no remaining bytes to write, close and move on
 		rem := bs.split - bs.written
@@ -161,12 +353,19 @@ func (bs *baseSplitter) Next(ctx context.Context, wf WriteFinisher) (inline []by
 	bs.nextMu.Lock()
 	defer bs.nextMu.Unlock()
 
+	// a nil wf is never sent, so only the end of the stream is waited for.
+	next := bs.next
+	if wf == nil {
+		next = nil
+	}
+
 	select {
 	case <-ctx.Done():
 		return nil, false, ctx.Err()
 
-	case bs.next <- wf:
+	case next <- wf:
 		bs.emitted = true
+		bs.planned += bs.split
 		return nil, false, nil
 
 	case <-bs.term:
@@ -32,10 +32,15 @@ func TestBaseSplitter(t *testing.T) {
 		err    error
 	}
 
-	readResult := func(splitter *baseSplitter) (res result, ok bool) {
+	readResult := func(splitter *baseSplitter, direct bool) (res result, ok bool) {
 		buf := buffer.New(new(buffer.MemoryBackend), 10)
 
-		inline, eof, err := splitter.Next(ctx, buf)
+		var wf WriteFinisher = buf
+		if direct {
+			wf = directBuffer{buf}
+		}
+
+		inline, eof, err := splitter.Next(ctx, wf)
 		if err != nil {
 			return result{"error", 0, err}, false
 		} else if eof {
@@ -115,28 +120,193 @@ func TestBaseSplitter(t *testing.T) {
 	for _, tc := range cases {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
-			splitter := newBaseSplitter(split, minimum)
-			go func() {
-				n, err := io.CopyN(randomWriter{splitter}, emptyReader{}, tc.write)
-				splitter.Finish(tc.finish)
-				if n != tc.write || err != nil {
-					panic(fmt.Sprintln("not enough bytes written or error:", n, tc.write, err))
-				}
-			}()
-
-			var results []result
-			for {
-				res, ok := readResult(splitter)
-				results = append(results, res)
-				if !ok {
-					break
+			run := func(t *testing.T, direct bool, write func(*baseSplitter) (int64, error)) {
+				splitter := newBaseSplitter(split, minimum)
+				go func() {
+					n, err := write(splitter)
+					splitter.Finish(tc.finish)
+					if n != tc.write || err != nil {
+						panic(fmt.Sprintln("not enough bytes written or error:", n, tc.write, err))
+					}
+				}()
+
+				var results []result
+				for {
+					res, ok := readResult(splitter, direct)
+					results = append(results, res)
+					if !ok {
+						break
+					}
 				}
+				require.Equal(t, tc.results, results)
 			}
-			require.Equal(t, tc.results, results)
+
+			t.Run("Write", func(t *testing.T) {
+				run(t, false, func(splitter *baseSplitter) (int64, error) {
+					return io.CopyN(randomWriter{splitter}, emptyReader{}, tc.write)
+				})
+			})
+
+			t.Run("ReadFrom", func(t *testing.T) {
+				run(t, false, func(splitter *baseSplitter) (int64, error) {
+					return splitter.ReadFrom(randomReader{io.LimitReader(emptyReader{}, tc.write)})
+				})
+			})
+
+			t.Run("ReadFrom_Direct", func(t *testing.T) {
+				run(t, true, func(splitter *baseSplitter) (int64, error) {
+					return splitter.ReadFrom(randomReader{io.LimitReader(emptyReader{}, tc.write)})
+				})
+			})
 		})
 	}
 }
 
+func TestBaseSplitterExpectedSize(t *testing.T) {
+	ctx := context.Background()
+
+	const (
+		split   = 20
+		minimum = 10
+	)
+
+	// next emits the next split the way Splitter does: inline splits are
+	// only waited for, and remote ones get a buffer that is read in the
+	// background.
+	type result struct {
+		kind   string
+		amount int64
+	}
+	next := func(splitter *baseSplitter) (res chan result, err error) {
+		res = make(chan result, 1)
+
+		if size, ok := splitter.nextSize(); ok && size <= minimum {
+			inline, eof, err := splitter.Next(ctx, nil)
+			switch {
+			case err != nil:
+				return nil, err
+			case eof:
+				res <- result{"done", 0}
+			default:
+				res <- result{"inline", int64(len(inline))}
+			}
+			return res, nil
+		}
+
+		buf := buffer.New(new(buffer.MemoryBackend), minimum)
+		inline, eof, err := splitter.Next(ctx, buf)
+		switch {
+		case err != nil:
+			return nil, err
+		case eof:
+			res <- result{"done", 0}
+			return res, nil
+		case inline != nil:
+			res <- result{"inline", int64(len(inline))}
+			return res, nil
+		}
+
+		go func() {
+			amount, err := io.Copy(io.Discard, buf.Reader())
+			buf.DoneReading(nil)
+			if err != nil {
+				res <- result{"error", 0}
+				return
+			}
+			res <- result{"buffer", amount}
+		}()
+		return res, nil
+	}
+
+	t.Run("Basic", func(t *testing.T) {
+		splitter := newBaseSplitter(split, minimum)
+		splitter.expect(45)
+
+		// the first split is emitted before anything is written.
+		first, err := next(splitter)
+		require.NoError(t, err)
+
+		go func() {
+			_, _ = splitter.ReadFrom(randomReader{io.LimitReader(emptyReader{}, 45)})
+			splitter.Finish(nil)
+		}()
+
+		var results []result
+		results = append(results, <-first)
+		for {
+			res, err := next(splitter)
+			require.NoError(t, err)
+			r := <-res
+			results = append(results, r)
+			if r.kind == "done" {
+				break
+			}
+		}
+
+		require.Equal(t, []result{
+			{"buffer", 20},
+			{"buffer", 20},
+			{"inline", 5},
+			{"done", 0},
+		}, results)
+	})
+
+	t.Run("Inline", func(t *testing.T) {
+		splitter := newBaseSplitter(split, minimum)
+		splitter.expect(5)
+
+		go func() {
+			_, _ = io.CopyN(randomWriter{splitter}, emptyReader{}, 5)
+			splitter.Finish(nil)
+		}()
+
+		res, err := next(splitter)
+		require.NoError(t, err)
+		require.Equal(t, result{"inline", 5}, <-res)
+	})
+
+	t.Run("TooMuch", func(t *testing.T) {
+		splitter := newBaseSplitter(split, minimum)
+		splitter.expect(30)
+
+		first, err := next(splitter)
+		require.NoError(t, err)
+
+		_, err = splitter.Write(make([]byte, 30))
+		require.NoError(t, err)
+
+		n, err := splitter.Write(make([]byte, 1))
+		require.Error(t, err)
+		require.Zero(t, n)
+
+		require.Equal(t, result{"buffer", 20}, <-first)
+		_, err = next(splitter)
+		require.Error(t, err)
+	})
+
+	t.Run("TooLittle", func(t *testing.T) {
+		splitter := newBaseSplitter(split, minimum)
+		splitter.expect(50)
+
+		first, err := next(splitter)
+		require.NoError(t, err)
+
+		_, err = splitter.Write(make([]byte, 25))
+		require.NoError(t, err)
+
+		second, err := next(splitter)
+		require.NoError(t, err)
+		splitter.Finish(nil)
+
+		// the split that was never written into fails instead of hanging.
+		require.Equal(t, result{"buffer", 20}, <-first)
+		require.Equal(t, result{"error", 0}, <-second)
+
+		_, err = next(splitter)
+		require.Error(t, err)
+	})
+}
+
 func BenchmarkBaseSplitter(b *testing.B) {
 	ctx := context.Background()
 
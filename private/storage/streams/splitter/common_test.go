@@ -6,6 +6,8 @@ package splitter
 import (
 	"io"
 	"math/rand"
+
+	"uplink/private/storage/streams/buffer"
 )
 
 var emptyLimitReaderBuf [4096]byte
@@ -64,3 +66,19 @@ func (r randomWriter) Write(p []byte) (n int, err error) {
 
 	return n, nil
 }
+
+type randomReader struct{ io.Reader }
+
+func (r randomReader) Read(p []byte) (n int, err error) {
+	return r.Reader.Read(p[:rand.Intn(len(p)+1)])
+}
+
+// directBuffer is a buffer that the splitter reads into directly.
+type directBuffer struct{ *buffer.Buffer }
+
+func (d directBuffer) readFromN(r io.Reader, n int64) (read int64, rerr, werr error) {
+	p := make([]byte, n)
+	nn, rerr := r.Read(p)
+	_, werr = d.Write(p[:nn])
+	return int64(nn), rerr, werr
+}
@@ -4,39 +4,142 @@
 package splitter
 
 import (
+	"bytes"
 	"io"
 	"sync"
 
 	"github.com/zeebo/errs"
 
+	"common/encryption"
 	"uplink/private/storage/streams/buffer"
 )
 
+// encryptedBuffer encrypts the data written to it block by block, and pads
+// the last block, into a buffer.
+//
+// It encrypts the blocks itself, instead of through an encryption writer,
+// so that readFromN can read into the block that is encrypted next.
 type encryptedBuffer struct {
 	sbuf *buffer.Buffer
-	wrc  io.WriteCloser
+	enc  encryption.Transformer
+
+	// wmu protects the fields below, which are used by the writer.
+	wmu      sync.Mutex
+	in       []byte // the plain data of the next block
+	out      []byte
+	blockNum int64
+	done     bool
 
 	mu    sync.Mutex
 	plain int64
 }
 
-func newEncryptedBuffer(sbuf *buffer.Buffer, wrc io.WriteCloser) *encryptedBuffer {
+func newEncryptedBuffer(sbuf *buffer.Buffer, enc encryption.Transformer) *encryptedBuffer {
 	return &encryptedBuffer{
 		sbuf: sbuf,
-		wrc:  wrc,
+		enc:  enc,
+		in:   make([]byte, 0, enc.InBlockSize()),
+		out:  make([]byte, 0, enc.OutBlockSize()),
 	}
 }
 
 func (e *encryptedBuffer) Reader() io.Reader     { return e.sbuf.Reader() }
 func (e *encryptedBuffer) DoneReading(err error) { e.sbuf.DoneReading(err) }
 
-func (e *encryptedBuffer) Write(p []byte) (int, error) {
-	e.mu.Lock()
-	defer e.mu.Unlock()
+func (e *encryptedBuffer) Write(p []byte) (n int, err error) {
+	e.wmu.Lock()
+	defer e.wmu.Unlock()
+
+	for len(p) > 0 {
+		if e.done {
+			return n, errs.New("write after done")
+		}
+
+		nn := copy(e.in[len(e.in):cap(e.in)], p)
+		e.in = e.in[:len(e.in)+nn]
+		p = p[nn:]
+		n += nn
+		e.addPlain(nn)
+
+		if err := e.flushFull(); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
 
-	n, err := e.wrc.Write(p)
+// readFromN reads up to n bytes from r directly into the blocks to encrypt.
+func (e *encryptedBuffer) readFromN(r io.Reader, n int64) (read int64, rerr, werr error) {
+	e.wmu.Lock()
+	defer e.wmu.Unlock()
+
+	for read < n && rerr == nil {
+		if e.done {
+			return read, nil, errs.New("write after done")
+		}
+
+		in := e.in[len(e.in):cap(e.in)]
+		if rem := n - read; rem < int64(len(in)) {
+			in = in[:rem]
+		}
+
+		var nn int
+		nn, rerr = r.Read(in)
+		e.in = e.in[:len(e.in)+nn]
+		read += int64(nn)
+		e.addPlain(nn)
+
+		if err := e.flushFull(); err != nil {
+			return read, rerr, err
+		}
+	}
+	return read, rerr, nil
+}
+
+// addPlain records that n more plain bytes were written.
+func (e *encryptedBuffer) addPlain(n int) {
+	e.mu.Lock()
 	e.plain += int64(n)
-	return n, err
+	e.mu.Unlock()
+}
+
+// flushFull encrypts the next block into the buffer once it is full.
+func (e *encryptedBuffer) flushFull() error {
+	if len(e.in) < cap(e.in) {
+		return nil
+	}
+	err := e.flush(e.in)
+	e.in = e.in[:0]
+	return err
+}
+
+// flush encrypts a full block into the buffer.
+func (e *encryptedBuffer) flush(block []byte) error {
+	out, err := e.enc.Transform(e.out[:0], block, e.blockNum)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	e.blockNum++
+
+	_, err = e.sbuf.Write(out)
+	return err
+}
+
+// finish pads and encrypts the last block into the buffer.
+func (e *encryptedBuffer) finish() error {
+	padded, err := io.ReadAll(encryption.PadReader(io.NopCloser(bytes.NewReader(e.in)), cap(e.in)))
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	e.in = e.in[:0]
+
+	for len(padded) > 0 {
+		if err := e.flush(padded[:cap(e.in)]); err != nil {
+			return err
+		}
+		padded = padded[cap(e.in):]
+	}
+	return nil
 }
 
 func (e *encryptedBuffer) PlainSize() int64 {
@@ -46,10 +149,17 @@ func (e *encryptedBuffer) PlainSize() int64 {
 	return e.plain
 }
 
+// DoneWriting finishes the buffer. Without an error it first encrypts the
+// last block, after any write in progress. With an error it doesn't wait for
+// the writes, so that it can interrupt them.
 func (e *encryptedBuffer) DoneWriting(err error) {
-	e.mu.Lock()
-	defer e.mu.Unlock()
-
-	cerr := e.wrc.Close()
-	e.sbuf.DoneWriting(errs.Combine(err, cerr))
+	if err == nil {
+		e.wmu.Lock()
+		if !e.done {
+			e.done = true
+			err = e.finish()
+		}
+		e.wmu.Unlock()
+	}
+	e.sbuf.DoneWriting(err)
 }
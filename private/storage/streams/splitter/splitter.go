@@ -79,13 +79,26 @@ type Options struct {
 	// PartNumber is the segment's part number if doing multipart
 	// uploads, and 0 otherwise.
 	PartNumber int32
+
+	// Budget, if not nil, limits the number of bytes buffered across
+	// all of the segments that share it. Each remote segment holds
+	// bytes from the budget from its first write until it is done.
+	Budget *buffer.Budget
+
+	// ExpectedSize, if positive, is the plaintext number of bytes that
+	// will be written. It lets the Splitter pick inline segments without
+	// waiting for the data, size remote segments exactly, and return
+	// remote segments from Next before their data is written. Writing
+	// more or finishing with fewer bytes fails the stream.
+	ExpectedSize int64
 }
 
 // Splitter takes an incoming stream of bytes and splits it into
 // encrypted segments.
 type Splitter struct {
 	// NewBackend lets one swap out the backend used to store segments
-	// while they are being uploaded.
+	// while they are being uploaded. When nil, segments are stored in
+	// memory.
 	NewBackend func() (buffer.Backend, error)
 
 	split          *baseSplitter
@@ -101,15 +114,45 @@ func New(opts Options) (*Splitter, error) {
 		return nil, errs.Wrap(err)
 	}
 
-	return &Splitter{
-		NewBackend: func() (buffer.Backend, error) { return buffer.NewMemoryBackend(0), nil },
+	split := newBaseSplitter(opts.Split, opts.Minimum)
+	split.expect(opts.ExpectedSize)
 
-		split:          newBaseSplitter(opts.Split, opts.Minimum),
+	return &Splitter{
+		split:          split,
 		opts:           opts,
 		maxSegmentSize: maxSegmentSize,
 	}, nil
 }
 
+// newBackend returns the backend to buffer a remote segment of up to size
+// encrypted bytes in. The size is exact when known is true.
+func (s *Splitter) newBackend(ctx context.Context, known bool, size int64) (buffer.Backend, error) {
+	var backend buffer.Backend
+	switch {
+	case s.NewBackend != nil:
+		var err error
+		backend, err = s.NewBackend()
+		if err != nil {
+			return nil, err
+		}
+	case known && s.opts.Budget == nil:
+		// allocate the whole segment up front instead of growing into it.
+		// with a budget, memory must not be allocated before it is reserved.
+		backend = buffer.NewMemoryBackend(int(size))
+	default:
+		backend = buffer.NewMemoryBackend(0)
+	}
+
+	if s.opts.Budget != nil {
+		// backends that spill to disk only hold up to their threshold in memory.
+		if spill, ok := backend.(*buffer.SpillBackend); ok && spill.Threshold() < size {
+			size = spill.Threshold()
+		}
+		backend = buffer.NewBudgetedBackend(ctx, backend, s.opts.Budget, size)
+	}
+	return backend, nil
+}
+
 // Finish informs the Splitter that no more writes are coming, along with any error
 // that may have caused the writes to stop.
 func (s *Splitter) Finish(err error) { s.split.Finish(err) }
@@ -117,6 +160,9 @@ func (s *Splitter) Finish(err error) { s.split.Finish(err) }
 // Write appends data into the stream.
 func (s *Splitter) Write(p []byte) (int, error) { return s.split.Write(p) }
 
+// ReadFrom appends data read from r into the stream until EOF.
+func (s *Splitter) ReadFrom(r io.Reader) (int64, error) { return s.split.ReadFrom(r) }
+
 // Next returns the next Segment split from the stream. If the stream is finished then
 // it will return nil, nil.
 func (s *Splitter) Next(ctx context.Context) (Segment, error) {
@@ -146,30 +192,58 @@ func (s *Splitter) Next(ctx context.Context) (Segment, error) {
 	if err != nil {
 		return nil, errs.Wrap(err)
 	}
-	backend, err := s.NewBackend()
-	if err != nil {
-		return nil, errs.Wrap(err)
-	}
-
-	buf := buffer.New(backend, s.opts.Minimum)
-	wrc := encryption.TransformWriterPadded(buf, enc)
-	encBuf := newEncryptedBuffer(buf, wrc)
 	segEncryption := metaclient.SegmentEncryption{
 		EncryptedKeyNonce: keyNonce,
 		EncryptedKey:      encKey,
 	}
 
+	// when the size of the segment is known, remote segments are sized
+	// exactly and inline segments don't need a buffer at all.
+	maxSegmentSize := s.maxSegmentSize
+	plannedSize, planned := s.split.nextSize()
+	if planned && plannedSize > s.opts.Minimum {
+		maxSegmentSize, err = encryption.CalcEncryptedSize(plannedSize, s.opts.Params)
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+	}
+
+	var backend buffer.Backend
+	var encBuf *encryptedBuffer
+	if !planned || plannedSize > s.opts.Minimum {
+		backend, err = s.newBackend(ctx, planned, maxSegmentSize)
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+
+		encBuf = newEncryptedBuffer(buffer.New(backend, s.opts.Minimum), enc)
+	}
+	closeBackend := func() {
+		if backend != nil {
+			_ = backend.Close()
+		}
+	}
+
 	// check for the next segment/inline boundary. if an error, don't update any
-	// local state.
-	inline, eof, err := s.split.Next(ctx, encBuf)
+	// local state. a nil buffer only waits for the inline data.
+	var wf WriteFinisher
+	if encBuf != nil {
+		wf = encBuf
+	}
+	inline, eof, err := s.split.Next(ctx, wf)
 	switch {
 	case err != nil:
+		closeBackend()
 		return nil, errs.Wrap(err)
 
 	case eof:
+		closeBackend()
 		return nil, nil
 
 	case inline != nil:
+		// the buffer is never written to for inline segments.
+		closeBackend()
+
 		// encrypt the inline data, and update the internal state if it succeeds.
 		encData, err := encryption.Encrypt(inline, s.opts.Params.CipherSuite, &contentKey, &nonce)
 		if err != nil {
@@ -199,7 +273,7 @@ func (s *Splitter) Next(ctx context.Context) (Segment, error) {
 			encParams:  s.opts.Params,
 			contentKey: &contentKey,
 
-			maxSegmentSize: s.maxSegmentSize,
+			maxSegmentSize: maxSegmentSize,
 			encTransformer: enc,
 			encBuf:         encBuf,
 		}, nil
@@ -129,26 +129,40 @@ func TestSplitter(t *testing.T) {
 	for _, tc := range cases {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
-			splitter, err := New(opts)
-			require.NoError(t, err)
-
-			go func() {
-				n, err := io.CopyN(randomWriter{splitter}, emptyReader{}, tc.write)
-				splitter.Finish(tc.finish)
-				if n != tc.write || err != nil {
-					panic(fmt.Sprintln("not enough bytes written or error:", n, tc.write, err))
-				}
-			}()
-
-			var results []result
-			for {
-				res, ok := readResult(splitter)
-				results = append(results, res)
-				if !ok {
-					break
+			run := func(t *testing.T, write func(*Splitter) (int64, error)) {
+				splitter, err := New(opts)
+				require.NoError(t, err)
+
+				go func() {
+					n, err := write(splitter)
+					splitter.Finish(tc.finish)
+					if n != tc.write || err != nil {
+						panic(fmt.Sprintln("not enough bytes written or error:", n, tc.write, err))
+					}
+				}()
+
+				var results []result
+				for {
+					res, ok := readResult(splitter)
+					results = append(results, res)
+					if !ok {
+						break
+					}
 				}
+				require.Equal(t, tc.results, results)
 			}
-			require.Equal(t, tc.results, results)
+
+			t.Run("Write", func(t *testing.T) {
+				run(t, func(splitter *Splitter) (int64, error) {
+					return io.CopyN(randomWriter{splitter}, emptyReader{}, tc.write)
+				})
+			})
+
+			t.Run("ReadFrom", func(t *testing.T) {
+				run(t, func(splitter *Splitter) (int64, error) {
+					return splitter.ReadFrom(randomReader{io.LimitReader(emptyReader{}, tc.write)})
+				})
+			})
 		})
 	}
 }
@@ -60,6 +60,21 @@ type Metadata interface {
 type Store struct {
 	*Uploader
 
+	// HedgePolicy, if not nil, makes downloads start reading from a subset
+	// of the pieces and hedge against slow ones using the rest of them.
+	HedgePolicy *eestream.HedgePolicy
+
+	// CorruptPieces, if not nil, makes downloads always read more than the
+	// required pieces, so that corrupt pieces are detected and corrected,
+	// and collects the pieces that were corrupt.
+	CorruptPieces *ecclient.CorruptPieces
+
+	// Recovery, when enabled, makes downloads continue past segments that
+	// cannot be read, recording what was recovered and what was lost in
+	// RecoveryManifest, which must be set.
+	Recovery         RecoveryMode
+	RecoveryManifest *RecoveryManifest
+
 	metainfo             *metaclient.Client
 	ec                   ecclient.Client
 	segmentSize          int64
@@ -186,10 +201,29 @@ func (s *Store) Put(ctx context.Context, bucket, unencryptedKey string, data io.
 
 		sizeReader := SizeReader(eofReader)
 		peekReader := NewPeekThresholdReader(io.LimitReader(sizeReader, s.segmentSize))
-		// If the data is larger than the inline threshold size, then it will be a remote segment
-		isRemote, err := peekReader.IsLargerThan(s.inlineThreshold)
-		if err != nil {
-			return Meta{}, errs.Wrap(err)
+
+		// When the size is known there is no need to peek, and the order
+		// limit of the last segment can be as large as the segment.
+		var isRemote bool
+		maxOrderLimit := maxEncryptedSegmentSize
+		if s.ExpectedSize > 0 {
+			plainSize := s.ExpectedSize - streamSize
+			if plainSize > s.segmentSize {
+				plainSize = s.segmentSize
+			}
+			isRemote = plainSize > int64(s.inlineThreshold)
+			if isRemote {
+				maxOrderLimit, err = encryption.CalcEncryptedSize(plainSize, s.encryptionParameters)
+				if err != nil {
+					return Meta{}, errs.Wrap(err)
+				}
+			}
+		} else {
+			// If the data is larger than the inline threshold size, then it will be a remote segment
+			isRemote, err = peekReader.IsLargerThan(s.inlineThreshold)
+			if err != nil {
+				return Meta{}, errs.Wrap(err)
+			}
 		}
 
 		segmentEncryption := metaclient.SegmentEncryption{}
@@ -210,7 +244,7 @@ func (s *Store) Put(ctx context.Context, bucket, unencryptedKey string, data io.
 			transformedReader := encryption.TransformReader(paddedReader, encrypter, 0)
 
 			beginSegment := &metaclient.BeginSegmentParams{
-				MaxOrderLimit: maxEncryptedSegmentSize,
+				MaxOrderLimit: maxOrderLimit,
 				Position: metaclient.SegmentPosition{
 					Index: int32(currentSegment),
 				},
@@ -685,23 +719,15 @@ func (s *Store) Get(ctx context.Context, bucket, unencryptedKey string, info met
 				listed = listed[1:]
 			}
 
-			encryptedRanger, err := s.Ranger(ctx, segment)
-			if err != nil {
-				return nil, errs.Wrap(err)
-			}
-
-			contentNonce, err := deriveContentNonce(*segment.Info.Position)
-			if err != nil {
-				return nil, errs.Wrap(err)
-			}
-
-			enc := segment.Info.SegmentEncryption
-			decrypted, err := decryptRanger(ctx, encryptedRanger, segment.Info.PlainSize, object.EncryptionParameters, derivedKey, enc.EncryptedKey, &enc.EncryptedKeyNonce, &contentNonce)
+			decrypted, err := s.decryptedRanger(ctx, segment, object.EncryptionParameters, derivedKey)
 			if err != nil {
-				return nil, errs.Wrap(err)
+				if s.Recovery == RecoveryDisabled {
+					return nil, errs.Wrap(err)
+				}
+				decrypted = &invalidRanger{size: segment.Info.PlainSize, err: err}
 			}
 
-			rangers = append(rangers, decrypted)
+			rangers = append(rangers, s.recoverable(decrypted, offset))
 			offset += segment.Info.PlainSize
 
 		case len(listed) > 0 && listed[0].PlainOffset == offset:
@@ -713,7 +739,7 @@ func (s *Store) Get(ctx context.Context, bucket, unencryptedKey string, info met
 				return nil, errs.Wrap(err)
 			}
 
-			rangers = append(rangers, &lazySegmentRanger{
+			rangers = append(rangers, s.recoverable(&lazySegmentRanger{
 				metainfo:             s.metainfo,
 				streams:              s,
 				streamID:             object.ID,
@@ -722,11 +748,29 @@ func (s *Store) Get(ctx context.Context, bucket, unencryptedKey string, info met
 				derivedKey:           derivedKey,
 				startingNonce:        &contentNonce,
 				encryptionParameters: object.EncryptionParameters,
-			})
+			}, offset))
 			offset += segment.PlainSize
 
 		default:
-			return nil, errs.New("missing segment for offset %d", offset)
+			err := errs.New("missing segment for offset %d", offset)
+			if s.Recovery == RecoveryDisabled {
+				return nil, err
+			}
+
+			// treat everything up to the next known segment as lost.
+			next := object.Size
+			if len(downloaded) > 0 && downloaded[0].Info.PlainOffset < next {
+				next = downloaded[0].Info.PlainOffset
+			}
+			if len(listed) > 0 && listed[0].PlainOffset < next {
+				next = listed[0].PlainOffset
+			}
+			if next <= offset {
+				return nil, err
+			}
+
+			rangers = append(rangers, s.recoverable(&invalidRanger{size: next - offset, err: err}, offset))
+			offset = next
 		}
 	}
 
@@ -740,6 +784,37 @@ func (s *Store) Get(ctx context.Context, bucket, unencryptedKey string, info met
 	return ranger.Concat(rangers...), nil
 }
 
+// decryptedRanger returns the decrypted ranger of a downloaded segment.
+func (s *Store) decryptedRanger(ctx context.Context, segment metaclient.DownloadSegmentWithRSResponse, encryptionParameters storx.EncryptionParameters, derivedKey *storx.Key) (ranger.Ranger, error) {
+	encryptedRanger, err := s.Ranger(ctx, segment)
+	if err != nil {
+		return nil, err
+	}
+
+	contentNonce, err := deriveContentNonce(*segment.Info.Position)
+	if err != nil {
+		return nil, err
+	}
+
+	enc := segment.Info.SegmentEncryption
+	return decryptRanger(ctx, encryptedRanger, segment.Info.PlainSize, encryptionParameters, derivedKey, enc.EncryptedKey, &enc.EncryptedKeyNonce, &contentNonce)
+}
+
+// recoverable wraps the ranger of the segment at offset in the stream, so
+// that failing to read it is recorded instead of failing the download, when
+// downloading in recovery mode.
+func (s *Store) recoverable(rr ranger.Ranger, offset int64) ranger.Ranger {
+	if s.Recovery == RecoveryDisabled {
+		return rr
+	}
+	return &recoveringRanger{
+		Ranger:   rr,
+		offset:   offset,
+		mode:     s.Recovery,
+		manifest: s.RecoveryManifest,
+	}
+}
+
 func deriveContentNonce(pos metaclient.SegmentPosition) (storx.Nonce, error) {
 	// The increment by 1 is to avoid nonce reuse with the metadata encryption,
 	// which is encrypted with the zero nonce.
@@ -881,38 +956,111 @@ func (s *Store) Ranger(ctx context.Context, response metaclient.DownloadSegmentW
 		return ranger.ByteRanger(info.EncryptedInlineData), nil
 	}
 
-	needed := info.RedundancyScheme.DownloadNodes()
+	redundancy, err := eestream.NewRedundancyStrategyFromStorx(info.RedundancyScheme)
+	if err != nil {
+		return nil, err
+	}
+
+	ranked := s.rankLimits(limits, redundancy.RequiredCount())
+
+	ec := s.ec
+	if s.CorruptPieces != nil {
+		ec = ec.WithForceErrorDetection(true).WithCorruptPieces(s.CorruptPieces)
+	}
+
+	// when hedging, every usable limit is handed to the client, which
+	// decides which of them to read from. Verified downloads read from
+	// enough pieces to correct errors from the start instead.
+	if s.HedgePolicy != nil && s.CorruptPieces == nil {
+		selected := make([]*pb.AddressedOrderLimit, len(limits))
+		for _, i := range ranked {
+			selected[i] = limits[i]
+		}
+		rr, err = ec.WithHedgePolicy(s.HedgePolicy).Get(ctx, selected, info.PiecePrivateKey, redundancy, info.EncryptedSize)
+		return rr, err
+	}
+
+	needed := int(info.RedundancyScheme.DownloadNodes())
+	// correcting a corrupt piece needs two more pieces than required.
+	if s.CorruptPieces != nil && needed < redundancy.RequiredCount()+2 {
+		needed = redundancy.RequiredCount() + 2
+	}
+	if needed > len(ranked) {
+		needed = len(ranked)
+	}
 	selected := make([]*pb.AddressedOrderLimit, len(limits))
+	for _, i := range ranked[:needed] {
+		selected[i] = limits[i]
+	}
+
+	rr, err = ec.Get(ctx, selected, info.PiecePrivateKey, redundancy, info.EncryptedSize)
+	return rr, err
+}
+
+// rankLimits returns the indexes of the non-nil limits in the order they
+// should be downloaded from. Without a reputation cache the order is random.
+// Otherwise nodes with a better reputation come first, and nodes known to be
+// bad are left out if there are enough other limits to reconstruct the
+// segment.
+func (s *Store) rankLimits(limits []*pb.AddressedOrderLimit, required int) []int {
 	s.rngMu.Lock()
 	perm := s.rng.Perm(len(limits))
 	s.rngMu.Unlock()
 
+	ranked := make([]int, 0, len(limits))
 	for _, i := range perm {
-		limit := limits[i]
-		if limit == nil {
-			continue
+		if limits[i] != nil {
+			ranked = append(ranked, i)
 		}
+	}
+	if s.Reputation == nil {
+		return ranked
+	}
 
-		selected[i] = limit
-
-		needed--
-		if needed <= 0 {
-			break
+	type rank struct {
+		bad        bool
+		score      float64
+		throughput float64
+	}
+	ranks := make(map[int]rank, len(ranked))
+	good := 0
+	for _, i := range ranked {
+		id := limits[i].GetLimit().StorageNodeId
+		r := rank{
+			bad:        s.Reputation.Bad(id),
+			score:      s.Reputation.Score(id),
+			throughput: s.Reputation.Throughput(id),
 		}
+		if !r.bad {
+			good++
+		}
+		ranks[i] = r
 	}
 
-	redundancy, err := eestream.NewRedundancyStrategyFromStorx(info.RedundancyScheme)
-	if err != nil {
-		return nil, err
-	}
+	sort.SliceStable(ranked, func(a, b int) bool {
+		ra, rb := ranks[ranked[a]], ranks[ranked[b]]
+		switch {
+		case ra.bad != rb.bad:
+			return !ra.bad
+		case ra.score != rb.score:
+			return ra.score > rb.score
+		default:
+			return ra.throughput > rb.throughput
+		}
+	})
 
-	rr, err = s.ec.Get(ctx, selected, info.PiecePrivateKey, redundancy, info.EncryptedSize)
-	return rr, err
+	// bad nodes are sorted last.
+	if good >= required {
+		ranked = ranked[:good]
+	}
+	return ranked
 }
 
-// invalidRanger is used to mark a range as invalid.
+// invalidRanger is used to mark a range as invalid. When err is set, it is
+// returned for any non-empty range.
 type invalidRanger struct {
 	size int64
+	err  error
 }
 
 func (d *invalidRanger) Size() int64 { return d.size }
@@ -928,6 +1076,9 @@ func (d *invalidRanger) Range(ctx context.Context, offset, length int64) (io.Rea
 	if 0 <= offset && offset <= d.size && length == 0 {
 		return emptyReader{}, nil
 	}
+	if d.err != nil {
+		return nil, d.err
+	}
 	return nil, errs.New("invalid range %d:%d (size:%d)", offset, length, d.size)
 }
 
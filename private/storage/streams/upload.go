@@ -4,6 +4,7 @@
 package streams
 
 import (
+	"io"
 	"sync"
 
 	"github.com/zeebo/errs"
@@ -37,6 +38,14 @@ func (u *Upload) Write(p []byte) (int, error) {
 	return u.split.Write(p)
 }
 
+// ReadFrom uploads the object or part data read from r until EOF.
+func (u *Upload) ReadFrom(r io.Reader) (int64, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return u.split.ReadFrom(r)
+}
+
 // Abort aborts the upload. If called more than once, or after Commit, it will
 // return an error.
 func (u *Upload) Abort() error {
@@ -16,6 +16,8 @@ import (
 	"common/pb"
 	"common/storx"
 	"uplink/private/metaclient"
+	"uplink/private/reputation"
+	"uplink/private/storage/streams/buffer"
 	"uplink/private/storage/streams/pieceupload"
 	"uplink/private/storage/streams/segmentupload"
 	"uplink/private/storage/streams/splitter"
@@ -39,6 +41,23 @@ type Uploader struct {
 	inlineThreshold      int
 	longTailMargin       int
 
+	// MemoryBudget, if not nil, limits the number of bytes buffered for
+	// remote segments across every upload started with this Uploader.
+	MemoryBudget *buffer.Budget
+
+	// NewBackend, if not nil, replaces the backend used to buffer segments
+	// while they are being uploaded.
+	NewBackend func() (buffer.Backend, error)
+
+	// Reputation, if not nil, is used to exchange the limits for nodes that
+	// failed recently before uploading to them.
+	Reputation *reputation.Cache
+
+	// ExpectedSize, if positive, is the number of bytes that will be
+	// written to the upload. It is used to plan the segments ahead of the
+	// data, and writing a different number of bytes fails the upload.
+	ExpectedSize int64
+
 	// The backend is fixed to the real backend in production but is overridden
 	// for testing.
 	backend uploaderBackend
@@ -93,15 +112,20 @@ func (u *Uploader) UploadObject(ctx context.Context, bucket, unencryptedKey stri
 	}
 
 	split, err := splitter.New(splitter.Options{
-		Split:      u.segmentSize,
-		Minimum:    int64(u.inlineThreshold),
-		Params:     u.encryptionParameters,
-		Key:        derivedKey,
-		PartNumber: 0,
+		Split:        u.segmentSize,
+		Minimum:      int64(u.inlineThreshold),
+		Params:       u.encryptionParameters,
+		Key:          derivedKey,
+		PartNumber:   0,
+		Budget:       u.MemoryBudget,
+		ExpectedSize: u.ExpectedSize,
 	})
 	if err != nil {
 		return nil, errs.Wrap(err)
 	}
+	if u.NewBackend != nil {
+		split.NewBackend = u.NewBackend
+	}
 	go func() {
 		<-ctx.Done()
 		split.Finish(ctx.Err())
@@ -114,7 +138,7 @@ func (u *Uploader) UploadObject(ctx context.Context, bucket, unencryptedKey stri
 		EncryptionParameters: u.encryptionParameters,
 	}
 
-	uploader := segmentUploader{metainfo: u.metainfo, piecePutter: u.piecePutter, sched: sched, longTailMargin: u.longTailMargin}
+	uploader := segmentUploader{metainfo: u.metainfo, piecePutter: u.piecePutter, sched: sched, longTailMargin: u.longTailMargin, reputation: u.Reputation}
 
 	encMeta := u.newEncryptedMetadata(metadata, derivedKey)
 
@@ -164,21 +188,26 @@ func (u *Uploader) UploadPart(ctx context.Context, bucket, unencryptedKey string
 	}
 
 	split, err := splitter.New(splitter.Options{
-		Split:      u.segmentSize,
-		Minimum:    int64(u.inlineThreshold),
-		Params:     u.encryptionParameters,
-		Key:        derivedKey,
-		PartNumber: partNumber,
+		Split:        u.segmentSize,
+		Minimum:      int64(u.inlineThreshold),
+		Params:       u.encryptionParameters,
+		Key:          derivedKey,
+		PartNumber:   partNumber,
+		Budget:       u.MemoryBudget,
+		ExpectedSize: u.ExpectedSize,
 	})
 	if err != nil {
 		return nil, errs.Wrap(err)
 	}
+	if u.NewBackend != nil {
+		split.NewBackend = u.NewBackend
+	}
 	go func() {
 		<-ctx.Done()
 		split.Finish(ctx.Err())
 	}()
 
-	uploader := segmentUploader{metainfo: u.metainfo, piecePutter: u.piecePutter, sched: sched, longTailMargin: u.longTailMargin}
+	uploader := segmentUploader{metainfo: u.metainfo, piecePutter: u.piecePutter, sched: sched, longTailMargin: u.longTailMargin, reputation: u.Reputation}
 
 	go func() {
 		info, err := u.backend.UploadPart(
@@ -219,14 +248,21 @@ type segmentUploader struct {
 	piecePutter    pieceupload.PiecePutter
 	sched          segmentupload.Scheduler
 	longTailMargin int
+	reputation     *reputation.Cache
 }
 
 func (u segmentUploader) Begin(ctx context.Context, beginSegment *metaclient.BeginSegmentResponse, segment splitter.Segment) (streamupload.SegmentUpload, error) {
-	return segmentupload.Begin(ctx, beginSegment, segment, limitsExchanger{u.metainfo}, u.piecePutter, u.sched, u.longTailMargin)
+	return segmentupload.Begin(ctx, beginSegment, segment, limitsExchanger{u.metainfo, u.reputation}, u.piecePutter, u.sched, u.longTailMargin)
 }
 
 type limitsExchanger struct {
-	metainfo MetainfoUpload
+	metainfo   MetainfoUpload
+	reputation *reputation.Cache
+}
+
+// AvoidNode implements pieceupload.NodeAvoider.
+func (e limitsExchanger) AvoidNode(id storx.NodeID) bool {
+	return e.reputation.RecentlyFailed(id)
 }
 
 func (e limitsExchanger) ExchangeLimits(ctx context.Context, segmentID storx.SegmentID, pieceNumbers []int) (storx.SegmentID, []*pb.AddressedOrderLimit, error) {
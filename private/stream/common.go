@@ -9,3 +9,7 @@ import (
 
 // Error is the errs class of stream errors.
 var Error = errs.Class("stream")
+
+// copyBufferSize is the size of the buffer used by Download.WriteTo, larger
+// than the io.Copy default to make fewer calls into the decoding pipeline.
+const copyBufferSize = 256 * 1024
@@ -5,6 +5,7 @@ package stream
 
 import (
 	"context"
+	"errors"
 	"io"
 
 	"uplink/private/metaclient"
@@ -81,6 +82,58 @@ func (download *Download) Read(data []byte) (n int, err error) {
 	return n, err
 }
 
+// WriteTo writes the rest of the stream to w.
+//
+// See io.WriterTo for more details.
+func (download *Download) WriteTo(w io.Writer) (n int64, err error) {
+	if download.closed {
+		return 0, Error.New("already closed")
+	}
+
+	if download.reader == nil {
+		err = download.resetReader()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	size := int64(copyBufferSize)
+	if download.length < size {
+		size = download.length
+	}
+	buf := make([]byte, size)
+
+	for download.length > 0 {
+		data := buf
+		if download.length < int64(len(data)) {
+			data = data[:download.length]
+		}
+
+		nr, rerr := download.reader.Read(data)
+		download.length -= int64(nr)
+		download.offset += int64(nr)
+
+		if nr > 0 {
+			nw, werr := w.Write(data[:nr])
+			n += int64(nw)
+			if werr != nil {
+				return n, werr
+			}
+			if nw != nr {
+				return n, io.ErrShortWrite
+			}
+		}
+
+		if errors.Is(rerr, io.EOF) {
+			break
+		} else if rerr != nil {
+			return n, rerr
+		}
+	}
+
+	return n, nil
+}
+
 // Close closes the stream and releases the underlying resources.
 func (download *Download) Close() error {
 	if download.closed {
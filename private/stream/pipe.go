@@ -0,0 +1,204 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package stream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+)
+
+// errClosedPipe is returned when writing to a pipe after it is closed.
+var errClosedPipe = errors.New("write on closed pipe")
+
+// pipe is like io.Pipe, except that a reader can be handed to the reading
+// side with ReadFrom. The reading side then reads from it directly into its
+// own buffers, instead of the data being copied through a writer.
+type pipe struct {
+	wrMu sync.Mutex // serializes Write and ReadFrom
+
+	srcMu sync.Mutex // held while reading from src
+	src   io.Reader  // the source being read, nil when there is none
+	read  int64      // how much was read from src
+	ready chan struct{}
+	done  chan pipeResult
+
+	once   sync.Once
+	closed chan struct{}
+	err    error // set before closed is closed
+}
+
+// pipeResult is how much the reading side read from a source, and the
+// error the source returned, if any.
+type pipeResult struct {
+	n   int64
+	err error
+}
+
+// newPipe returns the two sides of a new pipe.
+func newPipe() (*pipeReader, *pipeWriter) {
+	p := &pipe{
+		ready:  make(chan struct{}, 1),
+		done:   make(chan pipeResult, 1),
+		closed: make(chan struct{}),
+	}
+	return &pipeReader{pipe: p}, &pipeWriter{pipe: p}
+}
+
+// close closes the pipe with err, unless it is already closed.
+func (p *pipe) close(err error) {
+	p.once.Do(func() {
+		p.err = err
+		close(p.closed)
+	})
+}
+
+// isClosed returns whether the pipe is closed.
+func (p *pipe) isClosed() bool {
+	select {
+	case <-p.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// pipeReader is the reading side of a pipe. It must only be used by a
+// single goroutine.
+type pipeReader struct {
+	pipe *pipe
+}
+
+// Read reads from the current source, waiting for one if needed.
+func (r *pipeReader) Read(data []byte) (n int, err error) {
+	p := r.pipe
+	for {
+		p.srcMu.Lock()
+		// the source is never read after the pipe is closed, because the
+		// writer may hand it back to its caller.
+		if p.isClosed() {
+			p.srcMu.Unlock()
+			return 0, p.err
+		}
+
+		if p.src == nil {
+			p.srcMu.Unlock()
+			select {
+			case <-p.ready:
+			case <-p.closed:
+			}
+			continue
+		}
+
+		if len(data) == 0 {
+			p.srcMu.Unlock()
+			return 0, nil
+		}
+
+		n, err = p.src.Read(data)
+		p.read += int64(n)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = nil
+			}
+			// a source is done with its first error, which goes back to
+			// the writer instead of the reader.
+			p.done <- pipeResult{n: p.read, err: err}
+			p.src = nil
+		}
+		p.srcMu.Unlock()
+
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// CloseWithError closes the pipe, so that writes return err.
+func (r *pipeReader) CloseWithError(err error) error {
+	if err == nil {
+		err = io.ErrClosedPipe
+	}
+	r.pipe.close(err)
+	return nil
+}
+
+// pipeWriter is the writing side of a pipe.
+type pipeWriter struct {
+	pipe *pipe
+}
+
+// Write blocks until the reading side has read all of data, or the pipe is
+// closed.
+func (w *pipeWriter) Write(data []byte) (n int, err error) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+	m, err := w.ReadFrom(bytes.NewReader(data))
+	return int(m), err
+}
+
+// ReadFrom hands src to the reading side and blocks until the reading side
+// has read it to EOF, src returned an error, or the pipe is closed. src is
+// not read anymore once ReadFrom returns.
+func (w *pipeWriter) ReadFrom(src io.Reader) (n int64, err error) {
+	p := w.pipe
+	p.wrMu.Lock()
+	defer p.wrMu.Unlock()
+
+	p.srcMu.Lock()
+	if p.isClosed() {
+		p.srcMu.Unlock()
+		return 0, w.closedErr()
+	}
+	p.src, p.read = src, 0
+	p.srcMu.Unlock()
+
+	select {
+	case p.ready <- struct{}{}:
+	default: // the reading side is already woken up.
+	}
+
+	select {
+	case result := <-p.done:
+		return result.n, result.err
+	case <-p.closed:
+	}
+
+	// take src back, waiting for a read from it to finish.
+	p.srcMu.Lock()
+	defer p.srcMu.Unlock()
+	if p.src != nil {
+		p.src = nil
+		return p.read, w.closedErr()
+	}
+	// the reading side was done with src before the pipe closed.
+	result := <-p.done
+	return result.n, result.err
+}
+
+// closedErr is the error for writes after the pipe is closed.
+func (w *pipeWriter) closedErr() error {
+	if errors.Is(w.pipe.err, io.EOF) {
+		return errClosedPipe
+	}
+	return w.pipe.err
+}
+
+// Close closes the pipe, so that the reading side gets io.EOF after it has
+// read everything written.
+func (w *pipeWriter) Close() error {
+	return w.CloseWithError(nil)
+}
+
+// CloseWithError closes the pipe, so that the reading side gets err after it
+// has read everything written.
+func (w *pipeWriter) CloseWithError(err error) error {
+	if err == nil {
+		err = io.EOF
+	}
+	w.pipe.close(err)
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package stream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipe(t *testing.T) {
+	reader, writer := newPipe()
+
+	data := make([]byte, 100000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	type result struct {
+		data []byte
+		err  error
+	}
+	results := make(chan result, 1)
+	go func() {
+		read, err := io.ReadAll(iotest.OneByteReader(reader))
+		results <- result{data: read, err: err}
+	}()
+
+	n, err := writer.Write(data[:10])
+	require.NoError(t, err)
+	require.Equal(t, 10, n)
+
+	m, err := writer.ReadFrom(bytes.NewReader(data[10:50000]))
+	require.NoError(t, err)
+	require.EqualValues(t, 49990, m)
+
+	// errors of the source go back to the writer.
+	errSource := errors.New("source failed")
+	m, err = writer.ReadFrom(io.MultiReader(bytes.NewReader(data[50000:]), iotest.ErrReader(errSource)))
+	require.ErrorIs(t, err, errSource)
+	require.EqualValues(t, 50000, m)
+
+	require.NoError(t, writer.Close())
+
+	res := <-results
+	require.NoError(t, res.err)
+	require.Equal(t, data, res.data)
+
+	_, err = writer.Write(data)
+	require.Error(t, err)
+}
+
+func TestPipeReaderClosed(t *testing.T) {
+	reader, writer := newPipe()
+
+	errReader := errors.New("reader failed")
+	go func() {
+		buf := make([]byte, 10)
+		_, _ = io.ReadFull(reader, buf)
+		_ = reader.CloseWithError(errReader)
+	}()
+
+	_, err := writer.ReadFrom(bytes.NewReader(make([]byte, 100)))
+	require.ErrorIs(t, err, errReader)
+
+	_, err = writer.Write([]byte{1})
+	require.ErrorIs(t, err, errReader)
+}
+
+func TestPipeWriterClosedWithError(t *testing.T) {
+	reader, writer := newPipe()
+
+	errWriter := errors.New("aborted")
+	require.NoError(t, writer.CloseWithError(errWriter))
+
+	_, err := reader.Read(make([]byte, 10))
+	require.ErrorIs(t, err, errWriter)
+}
+
+func TestPipeClosedWhileReading(t *testing.T) {
+	reader, writer := newPipe()
+
+	src := bytes.NewReader(make([]byte, 100))
+	errReader := errors.New("reader failed")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		buf := make([]byte, 10)
+		_, _ = io.ReadFull(reader, buf)
+		_ = reader.CloseWithError(errReader)
+
+		_, err := reader.Read(buf)
+		require.ErrorIs(t, err, errReader)
+	}()
+
+	n, err := writer.ReadFrom(src)
+	require.ErrorIs(t, err, errReader)
+	require.EqualValues(t, 10, n)
+
+	<-done
+	// src was not read after it was handed back.
+	require.Equal(t, 90, src.Len())
+}
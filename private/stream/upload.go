@@ -20,7 +20,7 @@ type Upload struct {
 	ctx      context.Context
 	stream   *metaclient.MutableStream
 	streams  *streams.Store
-	writer   *io.PipeWriter
+	writer   *pipeWriter
 	errgroup errgroup.Group
 
 	// mu protects closed
@@ -34,7 +34,7 @@ type Upload struct {
 
 // NewUpload creates new stream upload.
 func NewUpload(ctx context.Context, stream *metaclient.MutableStream, streamsStore *streams.Store) *Upload {
-	reader, writer := io.Pipe()
+	reader, writer := newPipe()
 
 	upload := Upload{
 		ctx:     ctx,
@@ -92,6 +92,17 @@ func (upload *Upload) Write(data []byte) (n int, err error) {
 	return upload.writer.Write(data)
 }
 
+// ReadFrom writes data read from r to the underlying data stream until EOF.
+// The data is read from r directly into the buffers it is encrypted from.
+//
+// See io.ReaderFrom for more details.
+func (upload *Upload) ReadFrom(r io.Reader) (n int64, err error) {
+	if upload.isClosed() {
+		return 0, Error.New("already closed")
+	}
+	return upload.writer.ReadFrom(r)
+}
+
 // Commit closes the stream and releases the underlying resources.
 func (upload *Upload) Commit() error {
 	if err := upload.close(); err != nil {
@@ -19,7 +19,7 @@ import (
 type PartUpload struct {
 	ctx      context.Context
 	streams  *streams.Store
-	writer   *io.PipeWriter
+	writer   *pipeWriter
 	errgroup errgroup.Group
 
 	// mu protects closed
@@ -33,7 +33,7 @@ type PartUpload struct {
 
 // NewUploadPart creates new part upload.
 func NewUploadPart(ctx context.Context, bucket, key string, streamID storx.StreamID, partNumber uint32, eTagCh <-chan []byte, streamsStore *streams.Store) *PartUpload {
-	reader, writer := io.Pipe()
+	reader, writer := newPipe()
 
 	upload := PartUpload{
 		ctx:     ctx,
@@ -93,6 +93,17 @@ func (upload *PartUpload) Write(data []byte) (n int, err error) {
 	return upload.writer.Write(data)
 }
 
+// ReadFrom writes data read from r to the underlying data stream until EOF.
+// The data is read from r directly into the buffers it is encrypted from.
+//
+// See io.ReaderFrom for more details.
+func (upload *PartUpload) ReadFrom(r io.Reader) (n int64, err error) {
+	if upload.isClosed() {
+		return 0, Error.New("already closed")
+	}
+	return upload.writer.ReadFrom(r)
+}
+
 // Commit closes the stream and releases the underlying resources.
 func (upload *PartUpload) Commit() error {
 	if err := upload.close(); err != nil {
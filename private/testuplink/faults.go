@@ -0,0 +1,225 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package testuplink
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"common/storx"
+)
+
+type faultsKey struct{}
+
+// ErrInjectedFault is the error of failures injected with Faults.
+var ErrInjectedFault = errors.New("injected fault")
+
+// Faults describes failures to inject into the piece transfers and metainfo
+// requests made with a context. Only for testing purposes.
+//
+// The storage nodes affected by a rate are picked by hashing their ID with
+// Seed, so the same nodes fail for every transfer that uses the same Seed.
+type Faults struct {
+	// Seed picks the storage nodes affected by the rates.
+	Seed int64
+
+	// DialFailureRate is the fraction of storage nodes, from 0 to 1, that
+	// fail to dial.
+	DialFailureRate float64
+
+	// PieceDelay delays the first byte of every piece upload and download.
+	PieceDelay time.Duration
+
+	// StallRate is the fraction of storage nodes whose piece uploads and
+	// downloads stall after StallAfter bytes, until they are canceled.
+	StallRate  float64
+	StallAfter int64
+
+	// CorruptRate is the fraction of storage nodes whose downloaded piece
+	// bytes are corrupted.
+	CorruptRate float64
+
+	// RPCs are the metainfo requests to fail, by method name, such as
+	// "BeginObject". Requests sent in a batch are matched by their own
+	// method name, and fail the whole batch.
+	RPCs map[string]RPCFault
+
+	mu     sync.Mutex
+	failed map[string]int
+}
+
+// RPCFault describes how a metainfo request fails.
+type RPCFault struct {
+	// Err is returned instead of sending the request. When nil, an error
+	// wrapping ErrInjectedFault is returned.
+	Err error
+
+	// Count is how many requests fail before the rest succeed. When zero,
+	// every request fails.
+	Count int
+}
+
+// WithFaults creates a context that injects faults into the piece transfers
+// and metainfo requests made with it.
+func WithFaults(ctx context.Context, faults *Faults) context.Context {
+	return context.WithValue(ctx, faultsKey{}, faults)
+}
+
+// GetFaults returns the faults to inject from the context, or nil if there
+// are none. The methods of a nil Faults inject nothing.
+func GetFaults(ctx context.Context) *Faults {
+	faults, _ := ctx.Value(faultsKey{}).(*Faults)
+	return faults
+}
+
+// DialError returns the error dialing the node fails with, or nil if the
+// dial should proceed.
+func (faults *Faults) DialError(node storx.NodeID) error {
+	if faults == nil || !faults.picks("dial", node, faults.DialFailureRate) {
+		return nil
+	}
+	return fmt.Errorf("%w: dialing node %s", ErrInjectedFault, node)
+}
+
+// PieceReader returns r with the delays, stalls and, for downloads,
+// corruption of the node injected. ctx is the context of the transfer.
+func (faults *Faults) PieceReader(ctx context.Context, node storx.NodeID, r io.Reader, download bool) io.Reader {
+	if faults == nil {
+		return r
+	}
+
+	reader := &faultyPieceReader{
+		ctx:        ctx,
+		r:          r,
+		delay:      faults.PieceDelay,
+		stallAfter: -1,
+		corrupt:    download && faults.picks("corrupt", node, faults.CorruptRate),
+	}
+	if faults.picks("stall", node, faults.StallRate) {
+		reader.stallAfter = faults.StallAfter
+	}
+	if reader.delay <= 0 && reader.stallAfter < 0 && !reader.corrupt {
+		return r
+	}
+	return reader
+}
+
+// RPCError returns the error the metainfo request fails with, or nil if it
+// should be sent. rpc is either the method name or the full DRPC name, such
+// as "/metainfo.Metainfo/BeginObject".
+func (faults *Faults) RPCError(rpc string) error {
+	if faults == nil {
+		return nil
+	}
+	method := rpc[strings.LastIndexByte(rpc, '/')+1:]
+
+	fault, ok := faults.RPCs[method]
+	if !ok {
+		return nil
+	}
+
+	faults.mu.Lock()
+	defer faults.mu.Unlock()
+
+	if fault.Count > 0 && faults.failed[method] >= fault.Count {
+		return nil
+	}
+	if faults.failed == nil {
+		faults.failed = make(map[string]int)
+	}
+	faults.failed[method]++
+
+	if fault.Err != nil {
+		return fault.Err
+	}
+	return fmt.Errorf("%w: %s", ErrInjectedFault, method)
+}
+
+// Failed returns how many requests with the method name were failed.
+func (faults *Faults) Failed(method string) int {
+	if faults == nil {
+		return 0
+	}
+
+	faults.mu.Lock()
+	defer faults.mu.Unlock()
+
+	return faults.failed[method]
+}
+
+// picks returns whether the node is in the fraction rate of nodes affected
+// by the kind of fault.
+func (faults *Faults) picks(kind string, node storx.NodeID, rate float64) bool {
+	switch {
+	case rate <= 0:
+		return false
+	case rate >= 1:
+		return true
+	}
+
+	h := fnv.New64a()
+	var seed [8]byte
+	binary.BigEndian.PutUint64(seed[:], uint64(faults.Seed))
+	_, _ = h.Write(seed[:])
+	_, _ = h.Write([]byte(kind))
+	_, _ = h.Write(node[:])
+
+	// the top 53 bits of the hash as a fraction from 0 to 1.
+	return float64(h.Sum64()>>11)/(1<<53) < rate
+}
+
+// faultyPieceReader injects faults into the piece data read through it.
+type faultyPieceReader struct {
+	ctx        context.Context
+	r          io.Reader
+	delay      time.Duration
+	stallAfter int64
+	corrupt    bool
+
+	started bool
+	read    int64
+}
+
+func (reader *faultyPieceReader) Read(p []byte) (n int, err error) {
+	if !reader.started {
+		reader.started = true
+		if reader.delay > 0 {
+			timer := time.NewTimer(reader.delay)
+			select {
+			case <-timer.C:
+			case <-reader.ctx.Done():
+				timer.Stop()
+				return 0, reader.ctx.Err()
+			}
+		}
+	}
+
+	if reader.stallAfter >= 0 {
+		remaining := reader.stallAfter - reader.read
+		if remaining <= 0 {
+			<-reader.ctx.Done()
+			return 0, reader.ctx.Err()
+		}
+		if int64(len(p)) > remaining {
+			p = p[:remaining]
+		}
+	}
+
+	n, err = reader.r.Read(p)
+	reader.read += int64(n)
+
+	if reader.corrupt {
+		for i := range p[:n] {
+			p[i] ^= 0xFF
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,136 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package testuplink_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"common/storx"
+	"common/testcontext"
+	"common/testrand"
+	"uplink/private/testuplink"
+)
+
+func TestFaults_Nil(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	faults := testuplink.GetFaults(ctx)
+	require.Nil(t, faults)
+
+	node := testrand.NodeID()
+	require.NoError(t, faults.DialError(node))
+	require.NoError(t, faults.RPCError("BeginObject"))
+	require.Zero(t, faults.Failed("BeginObject"))
+
+	r := bytes.NewReader(nil)
+	require.Equal(t, io.Reader(r), faults.PieceReader(ctx, node, r, true))
+}
+
+func TestFaults_DialFailureRate(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	nodes := make([]storx.NodeID, 1000)
+	for i := range nodes {
+		nodes[i] = testrand.NodeID()
+	}
+
+	failing := func(faults *testuplink.Faults) (failed map[storx.NodeID]bool) {
+		failed = make(map[storx.NodeID]bool)
+		for _, node := range nodes {
+			if err := faults.DialError(node); err != nil {
+				require.True(t, errors.Is(err, testuplink.ErrInjectedFault))
+				failed[node] = true
+			}
+		}
+		return failed
+	}
+
+	require.Empty(t, failing(&testuplink.Faults{}))
+	require.Len(t, failing(&testuplink.Faults{DialFailureRate: 1}), len(nodes))
+
+	faults := testuplink.GetFaults(testuplink.WithFaults(ctx, &testuplink.Faults{DialFailureRate: 0.3, Seed: 1}))
+	failed := failing(faults)
+	require.InDelta(t, 300, len(failed), 60)
+
+	// the same nodes fail every time.
+	require.Equal(t, failed, failing(faults))
+	require.NotEqual(t, failed, failing(&testuplink.Faults{DialFailureRate: 0.3, Seed: 2}))
+}
+
+func TestFaults_PieceReader(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	data := testrand.Bytes(1024)
+	node := testrand.NodeID()
+
+	t.Run("Delay", func(t *testing.T) {
+		faults := &testuplink.Faults{PieceDelay: 50 * time.Millisecond}
+
+		start := time.Now()
+		read, err := io.ReadAll(faults.PieceReader(ctx, node, bytes.NewReader(data), false))
+		require.NoError(t, err)
+		require.Equal(t, data, read)
+		require.GreaterOrEqual(t, time.Since(start), faults.PieceDelay)
+	})
+
+	t.Run("Stall", func(t *testing.T) {
+		faults := &testuplink.Faults{StallRate: 1, StallAfter: 100}
+
+		stallCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		read, err := io.ReadAll(faults.PieceReader(stallCtx, node, bytes.NewReader(data), false))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Equal(t, data[:100], read)
+	})
+
+	t.Run("Corrupt", func(t *testing.T) {
+		faults := &testuplink.Faults{CorruptRate: 1}
+
+		// uploads are never corrupted.
+		read, err := io.ReadAll(faults.PieceReader(ctx, node, bytes.NewReader(data), false))
+		require.NoError(t, err)
+		require.Equal(t, data, read)
+
+		read, err = io.ReadAll(faults.PieceReader(ctx, node, bytes.NewReader(data), true))
+		require.NoError(t, err)
+		require.Len(t, read, len(data))
+		for i := range read {
+			require.NotEqual(t, data[i], read[i])
+		}
+	})
+}
+
+func TestFaults_RPCError(t *testing.T) {
+	custom := errors.New("custom")
+	faults := &testuplink.Faults{
+		RPCs: map[string]testuplink.RPCFault{
+			"BeginObject":  {},
+			"CommitObject": {Err: custom, Count: 2},
+		},
+	}
+
+	for i := 0; i < 3; i++ {
+		err := faults.RPCError("/metainfo.Metainfo/BeginObject")
+		require.True(t, errors.Is(err, testuplink.ErrInjectedFault))
+	}
+	require.Equal(t, 3, faults.Failed("BeginObject"))
+
+	require.Equal(t, custom, faults.RPCError("CommitObject"))
+	require.Equal(t, custom, faults.RPCError("CommitObject"))
+	require.NoError(t, faults.RPCError("CommitObject"))
+	require.Equal(t, 2, faults.Failed("CommitObject"))
+
+	require.NoError(t, faults.RPCError("GetObject"))
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 
 	"common/memory"
+	"uplink/private/eestream"
 	"uplink/private/eestream/scheduler"
 )
 
@@ -18,6 +19,8 @@ type listLimitKey struct{}
 
 type concurrentSegmentUploadsConfigKey struct{}
 
+type downloadHedgePolicyKey struct{}
+
 // WithMaxSegmentSize creates context with max segment size for testing purposes.
 //
 // Created context needs to be used with uplink.OpenProject to manipulate default
@@ -66,6 +69,12 @@ type ConcurrentSegmentUploadsConfig struct {
 	// segments.
 	SchedulerOptions scheduler.Options
 
+	// SharedSchedulerOptions are the options for the scheduler shared by
+	// all of the uploads of a project, which places a limit on the amount
+	// of concurrent piece uploads across all of them. A zero
+	// MaximumConcurrent disables the shared limit.
+	SharedSchedulerOptions scheduler.SharedOptions
+
 	// LongTailMargin represents the maximum number of piece uploads beyond the
 	// optimal threshold that will be uploaded for a given segment. Once an
 	// upload has reached the optimal threshold, the remaining piece uploads
@@ -110,3 +119,20 @@ func GetConcurrentSegmentUploadsConfig(ctx context.Context) *ConcurrentSegmentUp
 	}
 	return nil
 }
+
+// WithDownloadHedgePolicy creates a context that enables hedged reads for
+// downloads, using the given policy.
+//
+// The context needs to be used with uplink.OpenProject to have effect.
+func WithDownloadHedgePolicy(ctx context.Context, policy eestream.HedgePolicy) context.Context {
+	return context.WithValue(ctx, downloadHedgePolicyKey{}, policy)
+}
+
+// GetDownloadHedgePolicy returns the policy to use for hedged reads, or nil
+// if no policy has been set.
+func GetDownloadHedgePolicy(ctx context.Context) *eestream.HedgePolicy {
+	if policy, ok := ctx.Value(downloadHedgePolicyKey{}).(eestream.HedgePolicy); ok {
+		return &policy
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ package uplink
 
 import (
 	"context"
+	_ "unsafe" // for go:linkname
 
 	"github.com/zeebo/errs"
 
@@ -12,8 +13,14 @@ import (
 	"common/rpc"
 	"common/storx"
 	"uplink/private/ecclient"
+	"uplink/private/eestream"
+	"uplink/private/eestream/scheduler"
 	"uplink/private/metaclient"
+	"uplink/private/piecestore"
+	"uplink/private/ratelimit"
+	"uplink/private/reputation"
 	"uplink/private/storage/streams"
+	"uplink/private/storage/streams/buffer"
 	"uplink/private/testuplink"
 	"uplink/private/version"
 )
@@ -35,6 +42,13 @@ type Project struct {
 	segmentSize                   int64
 	encryptionParameters          storx.EncryptionParameters
 	concurrentSegmentUploadConfig *testuplink.ConcurrentSegmentUploadsConfig
+	uploadLimiter                 *ratelimit.Limiter
+	downloadLimiter               *ratelimit.Limiter
+	memoryBudget                  *buffer.Budget
+	sharedScheduler               *scheduler.Shared
+	hedgePolicy                   *eestream.HedgePolicy
+	hedgeLatencies                *eestream.LatencyTracker
+	reputation                    *reputation.Cache
 }
 
 // OpenProject opens a project with the specific access grant.
@@ -104,7 +118,44 @@ func (config Config) OpenProject(ctx context.Context, access *Access) (project *
 		}
 	}
 
-	ec := ecclient.New(storagenodeDialer, 0)
+	nodeReputation := reputation.New(reputation.DefaultOptions)
+	memoryBudget := buffer.NewBudget(config.MemoryLimit)
+	ec := ecclient.New(storagenodeDialer, 0).WithReputation(nodeReputation).WithMemoryBudget(memoryBudget)
+
+	concurrentSegmentUploadConfig := testuplink.GetConcurrentSegmentUploadsConfig(ctx)
+	if concurrentSegmentUploadConfig == nil && (memoryBudget != nil || config.BufferSpillThreshold > 0 || config.MaximumConcurrentPieceUploads > 0) {
+		// only the concurrent segment upload codepath buffers segments in a
+		// way that can be charged to the memory limit or spilled to disk,
+		// and schedules its piece uploads.
+		defaults := testuplink.DefaultConcurrentSegmentUploadsConfig()
+		concurrentSegmentUploadConfig = &defaults
+	}
+
+	// latencies are observed across all downloads of the project, unless
+	// the policy shares them even further.
+	hedgeLatencies := eestream.NewLatencyTracker(0)
+	hedgePolicy := testuplink.GetDownloadHedgePolicy(ctx)
+	if hedgePolicy == nil && config.DownloadHedgePolicy != nil {
+		hedgePolicy = convertHedgePolicy(config.DownloadHedgePolicy, hedgeLatencies)
+	}
+	if hedgePolicy != nil && hedgePolicy.Latencies == nil {
+		hedgePolicy.Latencies = hedgeLatencies
+	}
+
+	sharedSchedulerOptions := scheduler.SharedOptions{
+		MaximumConcurrent: config.MaximumConcurrentPieceUploads,
+		StarvationTimeout: config.PieceUploadStarvationTimeout,
+	}
+	switch {
+	case sharedSchedulerOptions.StarvationTimeout < 0:
+		sharedSchedulerOptions.StarvationTimeout = 0 // always by kind
+	case sharedSchedulerOptions.StarvationTimeout == 0:
+		sharedSchedulerOptions.StarvationTimeout = defaultPieceUploadStarvationTimeout
+	}
+	if concurrentSegmentUploadConfig != nil && concurrentSegmentUploadConfig.SharedSchedulerOptions.MaximumConcurrent > 0 {
+		sharedSchedulerOptions = concurrentSegmentUploadConfig.SharedSchedulerOptions
+	}
+	sharedScheduler := scheduler.NewShared(sharedSchedulerOptions)
 
 	return &Project{
 		config:                        config,
@@ -114,10 +165,93 @@ func (config Config) OpenProject(ctx context.Context, access *Access) (project *
 		ec:                            ec,
 		segmentSize:                   segmentsSize,
 		encryptionParameters:          encryptionParameters,
-		concurrentSegmentUploadConfig: testuplink.GetConcurrentSegmentUploadsConfig(ctx),
+		concurrentSegmentUploadConfig: concurrentSegmentUploadConfig,
+		uploadLimiter:                 ratelimit.NewLimiter(config.MaximumUploadBytesPerSecond),
+		downloadLimiter:               ratelimit.NewLimiter(config.MaximumDownloadBytesPerSecond),
+		memoryBudget:                  memoryBudget,
+		sharedScheduler:               sharedScheduler,
+		hedgePolicy:                   hedgePolicy,
+		hedgeLatencies:                hedgeLatencies,
+		reputation:                    nodeReputation,
 	}, nil
 }
 
+// newBufferBackend returns a function that creates backends spilling to
+// disk past threshold bytes, or nil to use the default backend if threshold is
+// not positive.
+func (project *Project) newBufferBackend(threshold int64) func() (buffer.Backend, error) {
+	if threshold <= 0 {
+		return nil
+	}
+	dir := project.config.BufferSpillDirectory
+	return func() (buffer.Backend, error) {
+		return buffer.NewSpillBackend(dir, threshold), nil
+	}
+}
+
+// segmentUploadConfig returns the configuration of the concurrent segment
+// upload codepath, which is the default one when the project was opened
+// without it.
+func (project *Project) segmentUploadConfig() testuplink.ConcurrentSegmentUploadsConfig {
+	if project.concurrentSegmentUploadConfig == nil {
+		return testuplink.DefaultConcurrentSegmentUploadsConfig()
+	}
+	return *project.concurrentSegmentUploadConfig
+}
+
+// newScheduler returns a scheduler for the piece uploads of a single upload
+// that also acquires from the scheduler shared by the project, if any.
+func (project *Project) newScheduler(prio scheduler.Priority) *scheduler.Scheduler {
+	opts := project.segmentUploadConfig().SchedulerOptions
+	opts.Shared = project.sharedScheduler
+	opts.Priority = prio
+	return scheduler.New(opts)
+}
+
+// nodeReputation exposes the node reputation cache of the project.
+//
+// NB: this is used with linkname in internal/expose.
+// It needs to be updated when this is updated.
+//
+//lint:ignore U1000, used with linkname
+//nolint:unused
+//go:linkname project_nodeReputation
+func project_nodeReputation(project *Project) *reputation.Cache {
+	return project.reputation
+}
+
+// MemoryUsage returns the number of bytes currently held by in-flight
+// uploads and downloads against Config.MemoryLimit. It always returns 0 if no
+// limit is configured.
+func (project *Project) MemoryUsage() int64 {
+	return project.memoryBudget.Used()
+}
+
+// PieceUploadStats describes the piece uploads limited by
+// Config.MaximumConcurrentPieceUploads.
+type PieceUploadStats struct {
+	// Active is the number of piece uploads in progress.
+	Active int
+	// Waiting is the number of piece uploads of interactive uploads waiting
+	// to start.
+	Waiting int
+	// WaitingBackground is the number of piece uploads of
+	// UploadOptions.Background uploads waiting to start.
+	WaitingBackground int
+}
+
+// PieceUploadStats returns the number of piece uploads in progress and
+// waiting for Config.MaximumConcurrentPieceUploads. It always returns zeros
+// if no limit is configured.
+func (project *Project) PieceUploadStats() PieceUploadStats {
+	stats := project.sharedScheduler.Stats()
+	return PieceUploadStats{
+		Active:            stats.Active,
+		Waiting:           stats.Waiting[scheduler.PriorityInteractive],
+		WaitingBackground: stats.Waiting[scheduler.PriorityBackground],
+	}
+}
+
 // Close closes the project and all associated resources.
 func (project *Project) Close() (err error) {
 	// only close the connection pools if it's created through OpenProject / getDialer()
@@ -146,11 +280,6 @@ func (project *Project) getStreamsStore(ctx context.Context) (_ *streams.Store,
 		}
 	}()
 
-	var longTailMargin int
-	if project.concurrentSegmentUploadConfig != nil {
-		longTailMargin = project.concurrentSegmentUploadConfig.LongTailMargin
-	}
-
 	streamStore, err := streams.NewStreamStore(
 		metainfoClient,
 		project.ec,
@@ -158,14 +287,57 @@ func (project *Project) getStreamsStore(ctx context.Context) (_ *streams.Store,
 		project.access.encAccess.Store,
 		project.encryptionParameters,
 		maxInlineSize,
-		longTailMargin)
+		project.segmentUploadConfig().LongTailMargin)
 	if err != nil {
 		return nil, packageError.Wrap(err)
 	}
+	streamStore.MemoryBudget = project.memoryBudget
+	streamStore.NewBackend = project.newBufferBackend(project.config.BufferSpillThreshold)
+	streamStore.HedgePolicy = project.hedgePolicy
+	streamStore.Reputation = project.reputation
 
 	return streamStore, nil
 }
 
+// withUploadLimiter returns a context that throttles piece uploads to
+// bytesPerSecond when it is positive, instead of the project-wide limit.
+// A limiter already in ctx, like the one shared by the parts of a parallel
+// upload, replaces the project-wide limit as well, and keeps limiting the
+// upload in addition to bytesPerSecond.
+func (project *Project) withUploadLimiter(ctx context.Context, bytesPerSecond int64) context.Context {
+	limiter := piecestore.GetUploadLimiter(ctx)
+	switch {
+	case limiter != nil:
+		limiter = limiter.Child(bytesPerSecond)
+	case bytesPerSecond > 0:
+		limiter = ratelimit.NewLimiter(bytesPerSecond)
+	default:
+		limiter = project.uploadLimiter
+	}
+	if limiter == nil {
+		return ctx
+	}
+	return piecestore.WithUploadLimiter(ctx, limiter)
+}
+
+// withDownloadLimiter returns a context that throttles piece downloads to
+// bytesPerSecond when it is positive, instead of the project-wide limit.
+func (project *Project) withDownloadLimiter(ctx context.Context, bytesPerSecond int64) context.Context {
+	limiter := piecestore.GetDownloadLimiter(ctx)
+	switch {
+	case limiter != nil:
+		limiter = limiter.Child(bytesPerSecond)
+	case bytesPerSecond > 0:
+		limiter = ratelimit.NewLimiter(bytesPerSecond)
+	default:
+		limiter = project.downloadLimiter
+	}
+	if limiter == nil {
+		return ctx
+	}
+	return piecestore.WithDownloadLimiter(ctx, limiter)
+}
+
 func (project *Project) dialMetainfoDB(ctx context.Context) (_ *metaclient.DB, err error) {
 	defer mon.Task()(&ctx)(&err)
 
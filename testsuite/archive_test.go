@@ -0,0 +1,326 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package testsuite_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"common/memory"
+	"common/testcontext"
+	"storx/private/testplanet"
+	"uplink"
+)
+
+func TestExportArchive(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 0,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project, err := uplink.OpenProject(ctx, planet.Uplinks[0].Access[planet.Satellites[0].ID()])
+		require.NoError(t, err)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		objects := map[string]*uplink.Object{}
+		for _, key := range []string{"data/a", "data/sub/b", "data/sub/c", "database/d", "other/e"} {
+			objects[key] = uploadObjectWithMetadata(t, ctx, project, "testbucket", key, memory.Size(len(key))*memory.KiB/4,
+				uplink.CustomMetadata{"key": key, "a=b%": "c"})
+		}
+		contents := map[string][]byte{}
+		for key := range objects {
+			download, err := project.DownloadObject(ctx, "testbucket", key, nil)
+			require.NoError(t, err)
+			contents[key], err = io.ReadAll(download)
+			require.NoError(t, err)
+			require.NoError(t, download.Close())
+		}
+
+		_, err = project.ExportArchive(ctx, "", "", io.Discard, nil)
+		require.True(t, errors.Is(err, uplink.ErrBucketNameInvalid))
+
+		for _, options := range []*uplink.ExportArchiveOptions{
+			nil,
+			{Concurrency: 1},
+			// the objects are downloaded in several ranges.
+			{PrefetchSize: 1000},
+			{PrefetchSize: 1000, Concurrency: 1},
+		} {
+			var archive bytes.Buffer
+			report, err := project.ExportArchive(ctx, "testbucket", "data/", &archive, options)
+			require.NoError(t, err)
+			require.Equal(t, 3, report.Objects)
+
+			reader := tar.NewReader(&archive)
+			entries := map[string]bool{}
+			var total int64
+			for {
+				header, err := reader.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				require.NoError(t, err)
+
+				key := "data/" + header.Name
+				data, err := io.ReadAll(reader)
+				require.NoError(t, err)
+				require.Equal(t, contents[key], data, key)
+				require.WithinDuration(t, objects[key].System.Created, header.ModTime, time.Second)
+				require.Equal(t, key, header.PAXRecords["STORX.custom.key"])
+				require.Equal(t, "c", header.PAXRecords["STORX.custom.a%3Db%25"])
+
+				entries[header.Name] = true
+				total += int64(len(data))
+			}
+			require.Equal(t, map[string]bool{"a": true, "sub/b": true, "sub/c": true}, entries)
+			require.Equal(t, total, report.Bytes)
+		}
+
+		t.Run("Zip", func(t *testing.T) {
+			var archive bytes.Buffer
+			report, err := project.ExportArchive(ctx, "testbucket", "data", &archive, &uplink.ExportArchiveOptions{
+				Format: uplink.ArchiveZip,
+				Filter: func(object *uplink.Object) bool {
+					return object.Key != "data/sub/c"
+				},
+			})
+			require.NoError(t, err)
+			require.Equal(t, 3, report.Objects)
+
+			reader, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+			require.NoError(t, err)
+
+			var names []string
+			for _, file := range reader.File {
+				names = append(names, file.Name)
+
+				var custom uplink.CustomMetadata
+				require.NoError(t, json.Unmarshal([]byte(file.Comment), &custom))
+				require.Equal(t, file.Name, custom["key"])
+
+				entry, err := file.Open()
+				require.NoError(t, err)
+				data, err := io.ReadAll(entry)
+				require.NoError(t, err)
+				require.NoError(t, entry.Close())
+				require.Equal(t, contents[file.Name], data)
+			}
+			require.ElementsMatch(t, []string{"data/a", "data/sub/b", "database/d"}, names)
+		})
+	})
+}
+
+func TestImportArchive(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 0,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project, err := uplink.OpenProject(ctx, planet.Uplinks[0].Access[planet.Satellites[0].ID()])
+		require.NoError(t, err)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		keys := []string{"a", "sub/b", "sub/c"}
+		for _, key := range keys {
+			uploadObjectWithMetadata(t, ctx, project, "testbucket", "data/"+key, memory.KiB, uplink.CustomMetadata{"key": key})
+		}
+
+		for _, format := range []uplink.ArchiveFormat{uplink.ArchiveTar, uplink.ArchiveZip} {
+			var archive bytes.Buffer
+			_, err := project.ExportArchive(ctx, "testbucket", "data/", &archive, &uplink.ExportArchiveOptions{Format: format})
+			require.NoError(t, err)
+
+			_, err = project.ImportArchive(ctx, "", "", bytes.NewReader(archive.Bytes()), &uplink.ImportArchiveOptions{Format: format})
+			require.True(t, errors.Is(err, uplink.ErrBucketNameInvalid))
+
+			// the exported objects conflict with themselves.
+			report, err := project.ImportArchive(ctx, "testbucket", "data/", bytes.NewReader(archive.Bytes()), &uplink.ImportArchiveOptions{Format: format})
+			require.True(t, errors.Is(err, uplink.ErrObjectExists))
+			require.Zero(t, report.Uploaded)
+
+			report, err = project.ImportArchive(ctx, "testbucket", "data/", bytes.NewReader(archive.Bytes()), &uplink.ImportArchiveOptions{
+				Format:   format,
+				Conflict: uplink.ImportConflictSkip,
+			})
+			require.NoError(t, err)
+			require.Equal(t, 3, report.Skipped)
+			require.Zero(t, report.Uploaded)
+
+			report, err = project.ImportArchive(ctx, "testbucket", "copy/", bytes.NewReader(archive.Bytes()), &uplink.ImportArchiveOptions{
+				Format:     format,
+				BufferSize: 1,
+			})
+			require.NoError(t, err)
+			require.Equal(t, 3, report.Uploaded)
+			require.Equal(t, int64(3*memory.KiB), report.Bytes)
+
+			for _, key := range keys {
+				original, err := project.DownloadObject(ctx, "testbucket", "data/"+key, nil)
+				require.NoError(t, err)
+				expected, err := io.ReadAll(original)
+				require.NoError(t, err)
+				require.NoError(t, original.Close())
+
+				imported, err := project.DownloadObject(ctx, "testbucket", "copy/"+key, nil)
+				require.NoError(t, err)
+				data, err := io.ReadAll(imported)
+				require.NoError(t, err)
+				require.NoError(t, imported.Close())
+
+				require.Equal(t, expected, data)
+				require.Equal(t, uplink.CustomMetadata{"key": key}, imported.Info().Custom)
+			}
+
+			report, err = project.ImportArchive(ctx, "testbucket", "copy/", bytes.NewReader(archive.Bytes()), &uplink.ImportArchiveOptions{
+				Format:   format,
+				Conflict: uplink.ImportConflictOverwrite,
+			})
+			require.NoError(t, err)
+			require.Equal(t, 3, report.Uploaded)
+
+			for _, key := range keys {
+				_, err := project.DeleteObject(ctx, "testbucket", "copy/"+key)
+				require.NoError(t, err)
+			}
+		}
+	})
+}
+
+func TestImportArchive_Entries(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 0,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project, err := uplink.OpenProject(ctx, planet.Uplinks[0].Access[planet.Satellites[0].ID()])
+		require.NoError(t, err)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		var archive bytes.Buffer
+		writer := tar.NewWriter(&archive)
+		addEntry := func(name, data string, records map[string]string) {
+			require.NoError(t, writer.WriteHeader(&tar.Header{
+				Typeflag:   tar.TypeReg,
+				Name:       name,
+				Size:       int64(len(data)),
+				Mode:       0o644,
+				Format:     tar.FormatPAX,
+				PAXRecords: records,
+			}))
+			_, err := writer.Write([]byte(data))
+			require.NoError(t, err)
+		}
+		for _, data := range []string{"first", "second", "third"} {
+			addEntry("same", data, nil)
+		}
+		// keys of archives that were not exported are kept as they are.
+		addEntry("foreign", "data", map[string]string{"STORX.custom.100%": "a", "STORX.custom.%41%3D": "b"})
+		addEntry("invalid", "data", map[string]string{"STORX.custom.a%00": "c"})
+		addEntry("last", "data", nil)
+		require.NoError(t, writer.Close())
+
+		report, err := project.ImportArchive(ctx, "testbucket", "", bytes.NewReader(archive.Bytes()), &uplink.ImportArchiveOptions{
+			Conflict: uplink.ImportConflictOverwrite,
+		})
+		require.NoError(t, err)
+		require.Equal(t, 5, report.Uploaded)
+		require.Equal(t, 1, report.Failed)
+		require.Error(t, report.Objects[4].Err)
+		require.Equal(t, "invalid", report.Objects[4].Key)
+
+		download, err := project.DownloadObject(ctx, "testbucket", "same", nil)
+		require.NoError(t, err)
+		data, err := io.ReadAll(download)
+		require.NoError(t, err)
+		require.NoError(t, download.Close())
+		require.Equal(t, "third", string(data))
+
+		object, err := project.StatObject(ctx, "testbucket", "foreign")
+		require.NoError(t, err)
+		require.Equal(t, uplink.CustomMetadata{"100%": "a", "%41=": "b"}, object.Custom)
+
+		_, err = project.StatObject(ctx, "testbucket", "invalid")
+		require.True(t, errors.Is(err, uplink.ErrObjectNotFound))
+
+		_, err = project.StatObject(ctx, "testbucket", "last")
+		require.NoError(t, err)
+
+		// the first entry is imported, and the others conflict with it.
+		_, err = project.DeleteObject(ctx, "testbucket", "same")
+		require.NoError(t, err)
+
+		report, err = project.ImportArchive(ctx, "testbucket", "", bytes.NewReader(archive.Bytes()), &uplink.ImportArchiveOptions{
+			Conflict: uplink.ImportConflictSkip,
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, report.Uploaded)
+		require.Equal(t, 4, report.Skipped)
+
+		download, err = project.DownloadObject(ctx, "testbucket", "same", nil)
+		require.NoError(t, err)
+		data, err = io.ReadAll(download)
+		require.NoError(t, err)
+		require.NoError(t, download.Close())
+		require.Equal(t, "first", string(data))
+	})
+}
+
+func TestImportArchive_ZipComments(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 0,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project, err := uplink.OpenProject(ctx, planet.Uplinks[0].Access[planet.Satellites[0].ID()])
+		require.NoError(t, err)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		var archive bytes.Buffer
+		writer := zip.NewWriter(&archive)
+		addEntry := func(name, comment string) {
+			w, err := writer.CreateHeader(&zip.FileHeader{Name: name, Comment: comment, Method: zip.Store})
+			require.NoError(t, err)
+			_, err = w.Write([]byte("data"))
+			require.NoError(t, err)
+		}
+		addEntry("exported", `{"key":"value"}`)
+		// comments of archives that were not exported are ignored, even
+		// when they are only partly custom metadata.
+		addEntry("partial", `{"key":"value","size":1}`)
+		addEntry("text", "not metadata")
+		require.NoError(t, writer.Close())
+
+		report, err := project.ImportArchive(ctx, "testbucket", "", bytes.NewReader(archive.Bytes()), &uplink.ImportArchiveOptions{
+			Format: uplink.ArchiveZip,
+		})
+		require.NoError(t, err)
+		require.Equal(t, 3, report.Uploaded)
+
+		object, err := project.StatObject(ctx, "testbucket", "exported")
+		require.NoError(t, err)
+		require.Equal(t, uplink.CustomMetadata{"key": "value"}, object.Custom)
+
+		for _, key := range []string{"partial", "text"} {
+			object, err := project.StatObject(ctx, "testbucket", key)
+			require.NoError(t, err)
+			require.Empty(t, object.Custom)
+		}
+	})
+}
@@ -26,6 +26,7 @@ import (
 	"drpc/drpcserver"
 	"uplink"
 	"uplink/edge"
+	"uplink/edge/edgetest"
 )
 
 const minimalAccess = "13J4Upun87ATb3T5T5sDXVeQaCzWFZeF9Ly4ELfxS5hUwTL8APEkwahTEJ1wxZjyErimiDs3kgid33kDLuYPYtwaY7Toy32mCTapfrUB814X13RiA844HPWK3QLKZb9cAoVceTowmNZXWbcUMKNbkMHCURE4hn8ZrdHPE3S86yngjvDxwKmarfGx"
@@ -109,6 +110,82 @@ func TestRegisterAccessTLS(t *testing.T) {
 	)
 }
 
+func TestRegisterAccessExpires(t *testing.T) {
+	ctx := testcontext.NewWithTimeout(t, 10*time.Second)
+	defer ctx.Cleanup()
+
+	server, err := edgetest.NewServer(edgetest.Options{})
+	require.NoError(t, err)
+
+	cancelCtx, authCancel := context.WithCancel(ctx)
+	ctx.Go(func() error { return server.Run(cancelCtx) })
+	defer authCancel()
+
+	access, err := uplink.ParseAccess(minimalAccess)
+	require.NoError(t, err)
+
+	edgeConfig := server.Config()
+
+	_, err = edgeConfig.RegisterAccess(ctx, access, &edge.RegisterAccessOptions{Expires: time.Now().Add(-time.Minute)})
+	require.Error(t, err)
+	require.Empty(t, server.Registrations())
+
+	_, err = edgeConfig.RegisterAccess(ctx, access, &edge.RegisterAccessOptions{Expires: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+
+	registrations := server.Registrations()
+	require.Len(t, registrations, 1)
+	require.NotEqual(t, minimalAccess, registrations[0].AccessGrant)
+}
+
+func TestCreateShareLink(t *testing.T) {
+	ctx := testcontext.NewWithTimeout(t, 10*time.Second)
+	defer ctx.Cleanup()
+
+	cancelCtx, authCancel := context.WithCancel(ctx)
+	port := startMockAuthServiceUnencrypted(cancelCtx, ctx, t)
+	defer authCancel()
+
+	access, err := uplink.ParseAccess(minimalAccess)
+	require.NoError(t, err)
+
+	edgeConfig := edge.Config{
+		AuthServiceAddress:            "localhost:" + strconv.Itoa(port),
+		InsecureUnencryptedConnection: true,
+	}
+
+	_, err = edgeConfig.CreateShareLink(ctx, access, "mybucket", "myobject", nil)
+	require.Error(t, err)
+
+	_, err = edgeConfig.CreateShareLink(ctx, access, "mybucket", "myprefix/", &edge.ShareLinkOptions{
+		BaseURL: "https://linksharing.test",
+		Raw:     true,
+	})
+	require.Error(t, err)
+
+	_, err = edgeConfig.CreateShareLink(ctx, access, "mybucket", "myobject", &edge.ShareLinkOptions{
+		BaseURL:  "https://linksharing.test",
+		NotAfter: time.Now().Add(-time.Hour),
+	})
+	require.Error(t, err)
+
+	link, err := edgeConfig.CreateShareLink(ctx, access, "mybucket", "myprefix/", &edge.ShareLinkOptions{
+		BaseURL:  "https://linksharing.test",
+		NotAfter: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "https://linksharing.test/s/l5pucy3dmvzxgs3fpfewix27l5pq/mybucket/myprefix/", link.URL)
+	require.Equal(t, "l5pucy3dmvzxgs3fpfewix27l5pq", link.Credentials.AccessKeyID)
+	require.NotNil(t, link.Access)
+
+	link, err = edgeConfig.CreateShareLink(ctx, access, "mybucket", "myprefix/myobject", &edge.ShareLinkOptions{
+		BaseURL: "https://linksharing.test",
+		Raw:     true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "https://linksharing.test/raw/l5pucy3dmvzxgs3fpfewix27l5pq/mybucket/myprefix/myobject", link.URL)
+}
+
 func startMockAuthServiceUnencrypted(cancelCtx context.Context, testCtx *testcontext.Context, t *testing.T) (port int) {
 	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
 	require.NoError(t, err)
@@ -0,0 +1,120 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package testsuite_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"common/memory"
+	"common/testcontext"
+	"storx/private/testplanet"
+	"uplink"
+	"uplink/private/testuplink"
+)
+
+func TestInspectObject(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		access := planet.Uplinks[0].Access[planet.Satellites[0].ID()]
+		project, err := uplink.OpenProject(testuplink.WithMaxSegmentSize(ctx, 20*memory.KiB), access)
+		require.NoError(t, err)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		_, err = project.InspectObject(ctx, "testbucket", "missing", nil)
+		require.True(t, errors.Is(err, uplink.ErrObjectNotFound))
+
+		t.Run("Inline", func(t *testing.T) {
+			uploadObject(t, ctx, project, "testbucket", "inline", memory.KiB)
+
+			inspection, err := project.InspectObject(ctx, "testbucket", "inline", nil)
+			require.NoError(t, err)
+			require.Equal(t, "inline", inspection.Object.Key)
+			require.Len(t, inspection.Segments, 1)
+
+			segment := inspection.Segments[0]
+			require.True(t, segment.Inline)
+			require.Equal(t, memory.KiB.Int64(), segment.PlainSize)
+			require.Greater(t, segment.EncryptedSize, segment.PlainSize)
+			require.Empty(t, segment.Pieces)
+			require.Equal(t, -1, segment.Reachable)
+			require.Equal(t, -1, segment.Margin())
+		})
+
+		uploadObject(t, ctx, project, "testbucket", "remote", 50*memory.KiB)
+
+		t.Run("Remote", func(t *testing.T) {
+			inspection, err := project.InspectObject(ctx, "testbucket", "remote", nil)
+			require.NoError(t, err)
+			require.Equal(t, int64(50*memory.KiB), inspection.Object.System.ContentLength)
+
+			// 20 KiB, 20 KiB and 10 KiB.
+			require.Len(t, inspection.Segments, 3)
+
+			var plainSize int64
+			for i, segment := range inspection.Segments {
+				require.EqualValues(t, i, segment.Index)
+				require.False(t, segment.Inline)
+				require.Equal(t, -1, segment.Reachable)
+
+				scheme := segment.RedundancyScheme
+				require.NotZero(t, scheme.Required)
+				require.LessOrEqual(t, scheme.Required, scheme.Repair)
+				require.LessOrEqual(t, scheme.Optimal, scheme.Total)
+				require.GreaterOrEqual(t, len(segment.Pieces), scheme.Required)
+				require.Equal(t, len(segment.Pieces)-scheme.Required, segment.Margin())
+
+				nodes := map[string]bool{}
+				for _, piece := range segment.Pieces {
+					require.NotEmpty(t, piece.NodeID)
+					require.NotEmpty(t, piece.NodeAddress)
+					require.NoError(t, piece.DialError)
+					nodes[piece.NodeID] = true
+				}
+				require.Len(t, nodes, len(segment.Pieces), "pieces must be on distinct nodes")
+
+				plainSize += segment.PlainSize
+			}
+			require.Equal(t, int64(50*memory.KiB), plainSize)
+		})
+
+		t.Run("DialNodes", func(t *testing.T) {
+			inspection, err := project.InspectObject(ctx, "testbucket", "remote", &uplink.InspectObjectOptions{DialNodes: true})
+			require.NoError(t, err)
+			for _, segment := range inspection.Segments {
+				require.Equal(t, len(segment.Pieces), segment.Reachable)
+			}
+
+			stopped := planet.StorageNodes[0]
+			require.NoError(t, planet.StopPeer(stopped))
+
+			inspection, err = project.InspectObject(ctx, "testbucket", "remote", &uplink.InspectObjectOptions{
+				DialNodes:   true,
+				DialTimeout: 5 * time.Second,
+			})
+			require.NoError(t, err)
+			for _, segment := range inspection.Segments {
+				unreachable := 0
+				for _, piece := range segment.Pieces {
+					if piece.NodeID == stopped.ID().String() {
+						require.Error(t, piece.DialError)
+						unreachable++
+					} else {
+						require.NoError(t, piece.DialError)
+					}
+				}
+				require.Equal(t, len(segment.Pieces)-unreachable, segment.Reachable)
+				require.Equal(t, segment.Reachable-segment.RedundancyScheme.Required, segment.Margin())
+			}
+		})
+	})
+}
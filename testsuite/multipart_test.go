@@ -4,8 +4,11 @@
 package testsuite_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
 	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -22,6 +25,7 @@ import (
 	"storx/private/testplanet"
 	"storx/satellite/metabase"
 	"uplink"
+	"uplink/private/multipart"
 	"uplink/private/testuplink"
 )
 
@@ -399,6 +403,97 @@ func TestUploadPart_ETag(t *testing.T) {
 	})
 }
 
+func TestUploadPart_ComputePartETags(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		newCtx := testuplink.WithMaxSegmentSize(ctx, 10*memory.KiB)
+
+		config := uplink.Config{ComputePartETags: true}
+		project, err := config.OpenProject(newCtx, planet.Uplinks[0].Access[planet.Satellites[0].ID()])
+		require.NoError(t, err)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		info, err := project.BeginUpload(newCtx, "testbucket", "multipart-object", nil)
+		require.NoError(t, err)
+
+		var partSums []byte
+		for part, size := range []memory.Size{15 * memory.KiB, 2 * memory.KiB} {
+			data := testrand.Bytes(size)
+			sum := md5.Sum(data)
+			partSums = append(partSums, sum[:]...)
+
+			upload, err := project.UploadPart(newCtx, "testbucket", "multipart-object", info.UploadID, uint32(part+1))
+			require.NoError(t, err)
+			_, err = upload.Write(data)
+			require.NoError(t, err)
+			require.NoError(t, upload.Commit())
+			require.Equal(t, sum[:], upload.Info().ETag)
+		}
+
+		composite := md5.Sum(partSums)
+		expected := hex.EncodeToString(composite[:]) + "-2"
+
+		object, err := project.CommitUpload(newCtx, "testbucket", "multipart-object", info.UploadID, &uplink.CommitUploadOptions{
+			CustomMetadata: uplink.CustomMetadata{"key": "value"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, expected, object.System.ETag)
+
+		object, err = project.StatObject(ctx, "testbucket", "multipart-object")
+		require.NoError(t, err)
+		require.Equal(t, expected, object.System.ETag)
+		// the ETag is system metadata only.
+		require.Equal(t, uplink.CustomMetadata{"key": "value"}, object.Custom)
+
+		objects := project.ListObjects(ctx, "testbucket", &uplink.ListObjectsOptions{System: true, Custom: true})
+		require.True(t, objects.Next())
+		require.Equal(t, expected, objects.Item().System.ETag)
+		require.Equal(t, uplink.CustomMetadata{"key": "value"}, objects.Item().Custom)
+		require.False(t, objects.Next())
+		require.NoError(t, objects.Err())
+
+		// replacing the custom metadata keeps the ETag.
+		err = project.UpdateObjectMetadata(ctx, "testbucket", "multipart-object", uplink.CustomMetadata{"other": "value"}, nil)
+		require.NoError(t, err)
+		object, err = project.StatObject(ctx, "testbucket", "multipart-object")
+		require.NoError(t, err)
+		require.Equal(t, expected, object.System.ETag)
+		require.Equal(t, uplink.CustomMetadata{"other": "value"}, object.Custom)
+
+		// custom metadata keys that S3 gateways use are left alone.
+		gateway := uplink.CustomMetadata{"s3:etag": "gateway"}
+		err = project.UpdateObjectMetadata(ctx, "testbucket", "multipart-object", gateway, nil)
+		require.NoError(t, err)
+		object, err = project.StatObject(ctx, "testbucket", "multipart-object")
+		require.NoError(t, err)
+		require.Equal(t, expected, object.System.ETag)
+		require.Equal(t, gateway, object.Custom)
+
+		// the ETag is stored without custom metadata too.
+		info, err = project.BeginUpload(newCtx, "testbucket", "no-metadata", nil)
+		require.NoError(t, err)
+		upload, err := project.UploadPart(newCtx, "testbucket", "no-metadata", info.UploadID, 1)
+		require.NoError(t, err)
+		_, err = upload.Write([]byte("data"))
+		require.NoError(t, err)
+		require.NoError(t, upload.Commit())
+
+		_, err = project.CommitUpload(newCtx, "testbucket", "no-metadata", info.UploadID, nil)
+		require.NoError(t, err)
+		object, err = project.StatObject(ctx, "testbucket", "no-metadata")
+		require.NoError(t, err)
+		sum := md5.Sum([]byte("data"))
+		composite = md5.Sum(sum[:])
+		require.Equal(t, hex.EncodeToString(composite[:])+"-1", object.System.ETag)
+		require.Empty(t, object.Custom)
+	})
+}
+
 func TestUploadPart_CheckNoEmptyInlineSegment(t *testing.T) {
 	testplanet.Run(t, testplanet.Config{
 		SatelliteCount:   1,
@@ -534,6 +629,104 @@ func TestAbortUpload_Multipart(t *testing.T) {
 	})
 }
 
+func TestAbortStaleUploads(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 0,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project, err := uplink.OpenProject(ctx, planet.Uplinks[0].Access[planet.Satellites[0].ID()])
+		require.NoError(t, err)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		for _, key := range []string{"logs/a", "logs/b", "logs-old/c", "data/d"} {
+			_, err := project.BeginUpload(ctx, "testbucket", key, nil)
+			require.NoError(t, err)
+		}
+
+		_, err = project.AbortStaleUploads(ctx, "", time.Hour, nil)
+		require.True(t, errors.Is(err, uplink.ErrBucketNameInvalid))
+
+		// a zero or negative age would select every upload.
+		for _, olderThan := range []time.Duration{0, -time.Hour} {
+			_, err = project.AbortStaleUploads(ctx, "testbucket", olderThan, nil)
+			require.Error(t, err)
+		}
+		assertUploadList(ctx, t, project, "testbucket", &uplink.ListUploadsOptions{Recursive: true},
+			"logs/a", "logs/b", "logs-old/c", "data/d")
+
+		// nothing is older than an hour.
+		report, err := project.AbortStaleUploads(ctx, "testbucket", time.Hour, nil)
+		require.NoError(t, err)
+		require.Empty(t, report.Uploads)
+
+		report, err = project.AbortStaleUploads(ctx, "testbucket", time.Nanosecond, &uplink.AbortStaleUploadsOptions{
+			Prefix: "logs",
+			DryRun: true,
+		})
+		require.NoError(t, err)
+		require.Len(t, report.Uploads, 3)
+		require.Zero(t, report.Aborted)
+		for _, upload := range report.Uploads {
+			require.False(t, upload.Aborted)
+			require.False(t, upload.Created.IsZero())
+		}
+		// the uploads are reported in key order.
+		require.Equal(t, "logs-old/c", report.Uploads[0].Key)
+		require.Equal(t, "logs/a", report.Uploads[1].Key)
+		require.Equal(t, "logs/b", report.Uploads[2].Key)
+		assertUploadList(ctx, t, project, "testbucket", &uplink.ListUploadsOptions{Recursive: true},
+			"logs/a", "logs/b", "logs-old/c", "data/d")
+
+		report, err = project.AbortStaleUploads(ctx, "testbucket", time.Nanosecond, &uplink.AbortStaleUploadsOptions{
+			Prefix: "logs/",
+		})
+		require.NoError(t, err)
+		require.Len(t, report.Uploads, 2)
+		require.Equal(t, 2, report.Aborted)
+		require.Zero(t, report.Failed)
+		for _, upload := range report.Uploads {
+			require.True(t, upload.Aborted)
+			require.NoError(t, upload.Err)
+		}
+		assertUploadList(ctx, t, project, "testbucket", &uplink.ListUploadsOptions{Recursive: true},
+			"logs-old/c", "data/d")
+
+		for key, owner := range map[string]string{"tagged/e": "job-1", "tagged/f": "job-2"} {
+			_, err := multipart.BeginUpload(ctx, project, "testbucket", key, &multipart.UploadOptions{
+				CustomMetadata: uplink.CustomMetadata{"owner": owner, "kind": "tagged"},
+			})
+			require.NoError(t, err)
+		}
+
+		// only uploads with all the custom metadata entries are selected.
+		report, err = project.AbortStaleUploads(ctx, "testbucket", time.Nanosecond, &uplink.AbortStaleUploadsOptions{
+			Custom: uplink.CustomMetadata{"owner": "job-1", "kind": "tagged"},
+		})
+		require.NoError(t, err)
+		require.Len(t, report.Uploads, 1)
+		require.Equal(t, "tagged/e", report.Uploads[0].Key)
+		require.Equal(t, 1, report.Aborted)
+		assertUploadList(ctx, t, project, "testbucket", &uplink.ListUploadsOptions{Recursive: true},
+			"logs-old/c", "data/d", "tagged/f")
+
+		report, err = project.AbortStaleUploads(ctx, "testbucket", time.Nanosecond, &uplink.AbortStaleUploadsOptions{
+			Custom: uplink.CustomMetadata{"owner": "job-3"},
+		})
+		require.NoError(t, err)
+		require.Empty(t, report.Uploads)
+
+		report, err = project.AbortStaleUploads(ctx, "testbucket", time.Nanosecond, &uplink.AbortStaleUploadsOptions{
+			Concurrency: 1,
+		})
+		require.NoError(t, err)
+		require.Equal(t, 3, report.Aborted)
+		assertUploadList(ctx, t, project, "testbucket", nil)
+	})
+}
+
 func TestListUploads_NonExistingBucket(t *testing.T) {
 	testplanet.Run(t, testplanet.Config{
 		SatelliteCount:   1,
@@ -1163,6 +1356,73 @@ func TestListUploadParts_SingleObject_CursorAndPaging(t *testing.T) {
 	})
 }
 
+func TestUploadParallel_Resume(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		segmentSize := 10 * memory.KiB
+		newCtx := testuplink.WithMaxSegmentSize(ctx, segmentSize)
+
+		project, err := planet.Uplinks[0].OpenProject(newCtx, planet.Satellites[0])
+		require.NoError(t, err)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		data := testrand.Bytes(45 * memory.KiB)
+		source := bytes.NewReader(data)
+
+		// stop the upload after the first part is uploaded.
+		stopCtx, stop := context.WithCancel(newCtx)
+		defer stop()
+
+		reports := 0
+		_, state, err := project.UploadParallel(stopCtx, "testbucket", "parallel-object", source, int64(len(data)), &uplink.ParallelUploadOptions{
+			PartSize:    int64(segmentSize),
+			Concurrency: 1,
+			Progress: func(string) {
+				// the first report is for beginning the upload.
+				reports++
+				if reports == 2 {
+					stop()
+				}
+			},
+		})
+		require.Error(t, err)
+		require.NotEmpty(t, state)
+
+		// a state is only good for the same upload.
+		_, _, err = project.UploadParallel(newCtx, "testbucket", "other-object", source, int64(len(data)), &uplink.ParallelUploadOptions{
+			Resume: state,
+		})
+		require.True(t, errors.Is(err, uplink.ErrResumeStateInvalid))
+
+		// resuming only uploads the missing parts.
+		uploaded := 0
+		_, state, err = project.UploadParallel(newCtx, "testbucket", "parallel-object", source, int64(len(data)), &uplink.ParallelUploadOptions{
+			Resume:      state,
+			Concurrency: 2,
+			Progress:    func(string) { uploaded++ },
+		})
+		require.NoError(t, err)
+		require.Equal(t, 4, uploaded)
+		// there is nothing to resume after a successful upload.
+		require.Empty(t, state)
+
+		download, err := project.DownloadObject(ctx, "testbucket", "parallel-object", nil)
+		require.NoError(t, err)
+		downloaded, err := io.ReadAll(download)
+		require.NoError(t, err)
+		require.NoError(t, download.Close())
+		require.Equal(t, data, downloaded)
+
+		// the upload was committed, so nothing is pending.
+		assertUploadList(ctx, t, project, "testbucket", nil)
+	})
+}
+
 func assertUploadList(ctx context.Context, t *testing.T, project *uplink.Project, bucket string, options *uplink.ListUploadsOptions, objectKeys ...string) {
 	list := project.ListUploads(ctx, bucket, options)
 	require.NoError(t, list.Err())
@@ -642,3 +642,95 @@ func uploadObjectWithMetadata(t *testing.T, ctx *testcontext.Context, project *u
 
 	return upload.Info()
 }
+
+func TestDownloadHedgePolicy(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		config := uplink.Config{
+			DownloadHedgePolicy: &uplink.HedgePolicy{
+				Margin:       1,
+				MinimumDelay: 10 * time.Millisecond,
+			},
+		}
+		project, err := config.OpenProject(ctx, planet.Uplinks[0].Access[planet.Satellites[0].ID()])
+		require.NoError(t, err)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		expected := testrand.Bytes(100 * memory.KiB)
+		err = planet.Uplinks[0].Upload(ctx, planet.Satellites[0], "testbucket", "object", expected)
+		require.NoError(t, err)
+
+		// the reserve pieces replace the one of the stopped node.
+		require.NoError(t, planet.StopPeer(planet.StorageNodes[0]))
+
+		for _, options := range []*uplink.DownloadOptions{
+			nil,
+			{Offset: 0, Length: -1, HedgePolicy: &uplink.HedgePolicy{}},
+		} {
+			download, err := project.DownloadObject(ctx, "testbucket", "object", options)
+			require.NoError(t, err)
+			data, err := io.ReadAll(download)
+			require.NoError(t, err)
+			require.NoError(t, download.Close())
+			require.Equal(t, expected, data)
+		}
+	})
+}
+
+func TestDownloadWriteTo(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		newCtx := testuplink.WithMaxSegmentSize(ctx, 10*memory.KiB)
+
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		data := testrand.Bytes(25 * memory.KiB) // 3 segments
+		upload, err := project.UploadObject(newCtx, "testbucket", "test.dat", nil)
+		require.NoError(t, err)
+		_, err = upload.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, upload.Commit())
+
+		for _, options := range []*uplink.DownloadOptions{
+			nil,
+			{Offset: 5 * memory.KiB.Int64(), Length: 12 * memory.KiB.Int64()},
+			{Offset: 100, Length: -1},
+		} {
+			expected := data
+			if options != nil {
+				expected = expected[options.Offset:]
+				if options.Length >= 0 {
+					expected = expected[:options.Length]
+				}
+			}
+
+			download, err := project.DownloadObject(ctx, "testbucket", "test.dat", options)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			n, err := download.WriteTo(&buf)
+			require.NoError(t, err)
+			require.Equal(t, int64(len(expected)), n)
+			require.Equal(t, expected, buf.Bytes())
+			require.Equal(t, n, download.Stats().Bytes)
+
+			// the download is done.
+			n, err = download.WriteTo(&buf)
+			require.NoError(t, err)
+			require.Zero(t, n)
+
+			require.NoError(t, download.Close())
+		}
+	})
+}
@@ -0,0 +1,126 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package ecclient_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vivint/infectious"
+
+	"common/memory"
+	"common/pb"
+	"common/signing"
+	"common/storx"
+	"common/testcontext"
+	"common/testrand"
+	"storx/private/testplanet"
+	"uplink/private/ecclient"
+	"uplink/private/eestream"
+)
+
+func TestVerify(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 4, UplinkCount: 1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		fc, err := infectious.NewFEC(2, 4)
+		require.NoError(t, err)
+		es := eestream.NewRSScheme(fc, 1*memory.KiB.Int())
+		rs, err := eestream.NewRedundancyStrategy(es, 0, 0)
+		require.NoError(t, err)
+
+		satellite := planet.Satellites[0]
+		signer := signing.SignerFromFullIdentity(satellite.Identity)
+		publicKey, privateKey, err := storx.NewPieceKey()
+		require.NoError(t, err)
+		rootPieceID := testrand.PieceID()
+
+		newLimits := func(action pb.PieceAction) []*pb.AddressedOrderLimit {
+			limits := make([]*pb.AddressedOrderLimit, len(planet.StorageNodes))
+			for i, node := range planet.StorageNodes {
+				now := time.Now()
+				limit, err := signing.SignOrderLimit(ctx, signer, &pb.OrderLimit{
+					SatelliteId:     satellite.ID(),
+					UplinkPublicKey: publicKey,
+					StorageNodeId:   node.ID(),
+					PieceId:         rootPieceID.Derive(node.ID(), int32(i)),
+					Action:          action,
+					SerialNumber:    testrand.SerialNumber(),
+					OrderCreation:   now,
+					OrderExpiration: now.Add(time.Hour),
+					PieceExpiration: now.Add(24 * time.Hour),
+					Limit:           memory.MiB.Int64(),
+				})
+				require.NoError(t, err)
+
+				limits[i] = &pb.AddressedOrderLimit{
+					Limit:              limit,
+					StorageNodeAddress: &pb.NodeAddress{Address: node.Addr()},
+				}
+			}
+			return limits
+		}
+
+		ec := ecclient.New(planet.Uplinks[0].Dialer, 0)
+
+		data := testrand.Bytes(10 * memory.KiB)
+		results, err := ec.PutSingleResult(ctx, newLimits(pb.PieceAction_PUT), privateKey, rs, bytes.NewReader(data))
+		require.NoError(t, err)
+		require.Len(t, results, 4)
+
+		pieceSize := eestream.CalcPieceSize(int64(len(data)), rs)
+
+		t.Run("download", func(t *testing.T) {
+			result, err := ec.Verify(ctx, newLimits(pb.PieceAction_GET), privateKey, es, int64(len(data)))
+			require.NoError(t, err)
+			require.Zero(t, result.UnrecoverableStripes)
+			require.Zero(t, result.UncheckedStripes)
+			require.Zero(t, result.UncorrectableStripes)
+			require.Len(t, result.Pieces, 4)
+			for _, piece := range result.Pieces {
+				require.NoError(t, piece.Err)
+				require.Equal(t, pieceSize, piece.Downloaded)
+				require.Zero(t, piece.CorruptStripes)
+				// nodes only send the hash for repair order limits.
+				require.False(t, piece.HashSent)
+				require.NoError(t, piece.HashErr)
+			}
+		})
+
+		t.Run("repair", func(t *testing.T) {
+			result, err := ec.Verify(ctx, newLimits(pb.PieceAction_GET_REPAIR), privateKey, es, int64(len(data)))
+			require.NoError(t, err)
+			require.Zero(t, result.UnrecoverableStripes)
+			require.Len(t, result.Pieces, 4)
+			for _, piece := range result.Pieces {
+				require.NoError(t, piece.Err)
+				require.True(t, piece.HashSent)
+				require.NoError(t, piece.HashErr)
+			}
+		})
+
+		t.Run("missing", func(t *testing.T) {
+			limits := newLimits(pb.PieceAction_GET)
+			limits[1] = nil
+			require.NoError(t, planet.StopPeer(planet.StorageNodes[0]))
+
+			result, err := ec.Verify(ctx, limits, privateKey, es, int64(len(data)))
+			require.NoError(t, err)
+			require.Len(t, result.Pieces, 3)
+			require.Equal(t, result.Stripes, result.UncheckedStripes)
+
+			for _, piece := range result.Pieces {
+				if piece.Num == 0 {
+					require.Error(t, piece.Err)
+					require.Zero(t, piece.Downloaded)
+					continue
+				}
+				require.NoError(t, piece.Err)
+				require.Equal(t, pieceSize, piece.Downloaded)
+			}
+		})
+	})
+}
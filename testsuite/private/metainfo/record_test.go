@@ -0,0 +1,92 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package metainfo_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"common/memory"
+	"common/testcontext"
+	"common/testrand"
+	"storx/private/testplanet"
+	"uplink"
+	"uplink/private/metaclient"
+)
+
+func TestRecordReplayProject(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 4, UplinkCount: 1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		access := planet.Uplinks[0].Access[planet.Satellites[0].ID()]
+		apiKey := planet.Uplinks[0].APIKey[planet.Satellites[0].ID()]
+
+		// small enough to be inline, so that reading it needs no nodes.
+		data := testrand.Bytes(memory.KiB)
+		require.NoError(t, planet.Uplinks[0].Upload(ctx, planet.Satellites[0], "testbucket", "object", data))
+
+		type result struct {
+			object  *uplink.Object
+			data    []byte
+			listed  []string
+			buckets []string
+		}
+
+		read := func(ctx context.Context, access *uplink.Access) (res result) {
+			project, err := uplink.OpenProject(ctx, access)
+			require.NoError(t, err)
+			defer func() { require.NoError(t, project.Close()) }()
+
+			res.object, err = project.StatObject(ctx, "testbucket", "object")
+			require.NoError(t, err)
+
+			download, err := project.DownloadObject(ctx, "testbucket", "object", nil)
+			require.NoError(t, err)
+			res.data, err = io.ReadAll(download)
+			require.NoError(t, err)
+			require.NoError(t, download.Close())
+
+			objects := project.ListObjects(ctx, "testbucket", &uplink.ListObjectsOptions{System: true, Custom: true})
+			for objects.Next() {
+				res.listed = append(res.listed, objects.Item().Key)
+			}
+			require.NoError(t, objects.Err())
+
+			buckets := project.ListBuckets(ctx, nil)
+			for buckets.Next() {
+				res.buckets = append(res.buckets, buckets.Item().Name)
+			}
+			require.NoError(t, buckets.Err())
+
+			return res
+		}
+
+		var recording bytes.Buffer
+		recorder := metaclient.NewRecorder(&recording)
+		recorded := read(metaclient.WithRecorder(ctx, recorder), access)
+		require.NoError(t, recorder.Err())
+		require.Equal(t, data, recorded.data)
+		require.Equal(t, []string{"object"}, recorded.listed)
+		require.Equal(t, []string{"testbucket"}, recorded.buckets)
+
+		replayer, err := metaclient.NewReplayer(bytes.NewReader(recording.Bytes()))
+		require.NoError(t, err)
+		require.NotEmpty(t, replayer.Remaining())
+		for _, call := range replayer.Remaining() {
+			require.False(t, bytes.Contains(call.Request, apiKey.SerializeRaw()), call.String())
+		}
+
+		// replaying works with a different API key for the same project.
+		shared, err := access.Share(uplink.FullPermission())
+		require.NoError(t, err)
+
+		replayed := read(metaclient.WithReplayer(ctx, replayer), shared)
+		require.Equal(t, recorded, replayed)
+		require.NoError(t, replayer.Check())
+	})
+}
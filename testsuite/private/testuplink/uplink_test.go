@@ -57,3 +57,39 @@ func TestWithMaxSegmentSize_Upload(t *testing.T) {
 		require.Equal(t, 2, len(segments))
 	})
 }
+
+func TestWithFaults(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 4, UplinkCount: 1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		uplink := planet.Uplinks[0]
+		satellite := planet.Satellites[0]
+		expectedData := testrand.Bytes(20 * memory.KiB)
+
+		faults := &testuplink.Faults{
+			RPCs: map[string]testuplink.RPCFault{
+				"CommitObject": {Count: 1},
+			},
+		}
+		faultsCtx := testuplink.WithFaults(ctx, faults)
+
+		err := uplink.Upload(faultsCtx, satellite, "super-bucket", "super-object", expectedData)
+		require.ErrorIs(t, err, testuplink.ErrInjectedFault)
+		require.Equal(t, 1, faults.Failed("CommitObject"))
+
+		err = uplink.Upload(faultsCtx, satellite, "super-bucket", "super-object", expectedData)
+		require.NoError(t, err)
+
+		unreachable := testuplink.WithFaults(ctx, &testuplink.Faults{DialFailureRate: 1})
+
+		err = uplink.Upload(unreachable, satellite, "super-bucket", "other-object", expectedData)
+		require.Error(t, err)
+
+		_, err = uplink.Download(unreachable, satellite, "super-bucket", "super-object")
+		require.Error(t, err)
+
+		data, err := uplink.Download(ctx, satellite, "super-bucket", "super-object")
+		require.NoError(t, err)
+		require.Equal(t, expectedData, data)
+	})
+}
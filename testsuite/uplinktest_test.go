@@ -0,0 +1,29 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package testsuite_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"common/testcontext"
+	"storx/private/testplanet"
+	"uplink"
+	"uplink/uplinktest"
+)
+
+func TestUplinkTest_Conformance(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project, err := uplink.OpenProject(ctx, planet.Uplinks[0].Access[planet.Satellites[0].ID()])
+		require.NoError(t, err)
+		defer ctx.Check(project.Close)
+
+		uplinktest.RunConformance(t, ctx, uplinktest.Wrap(project))
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"os"
 	"strconv"
 	"testing"
 	"time"
@@ -412,3 +413,283 @@ func TestConcurrentUploadToSamePath(t *testing.T) {
 		require.Equal(t, expectedData, downloaded)
 	})
 }
+
+func TestUploadMemoryLimit(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		limit := 100 * memory.KiB.Int64()
+
+		project, err := uplink.Config{MemoryLimit: limit}.OpenProject(ctx, planet.Uplinks[0].Access[planet.Satellites[0].ID()])
+		require.NoError(t, err)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		first, err := project.UploadObject(ctx, "testbucket", "first", nil)
+		require.NoError(t, err)
+		_, err = first.Write(testrand.Bytes(10 * memory.KiB))
+		require.NoError(t, err)
+
+		// the open segment of the first upload holds the whole limit.
+		require.Equal(t, limit, project.MemoryUsage())
+
+		second, err := project.UploadObject(ctx, "testbucket", "second", nil)
+		require.NoError(t, err)
+
+		written := make(chan error, 1)
+		go func() {
+			_, err := second.Write(testrand.Bytes(10 * memory.KiB))
+			written <- err
+		}()
+
+		select {
+		case err := <-written:
+			t.Fatalf("write did not wait for the memory limit: %v", err)
+		case <-time.After(500 * time.Millisecond):
+		}
+
+		require.NoError(t, first.Commit())
+		require.NoError(t, <-written)
+		require.NoError(t, second.Commit())
+		require.Zero(t, project.MemoryUsage())
+
+		// downloads are charged as well and give the memory back.
+		download, err := project.DownloadObject(ctx, "testbucket", "first", nil)
+		require.NoError(t, err)
+		data, err := io.ReadAll(download)
+		require.NoError(t, err)
+		require.Len(t, data, 10*memory.KiB.Int())
+		require.NoError(t, download.Close())
+		require.Zero(t, project.MemoryUsage())
+	})
+}
+
+func TestUploadBufferSpill(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		dir := ctx.Dir("spill")
+		access := planet.Uplinks[0].Access[planet.Satellites[0].ID()]
+
+		for _, tt := range []struct {
+			name    string
+			config  uplink.Config
+			options *uplink.UploadOptions
+		}{
+			{
+				name:   "config",
+				config: uplink.Config{BufferSpillDirectory: dir, BufferSpillThreshold: memory.KiB.Int64()},
+			},
+			{
+				name:    "options",
+				config:  uplink.Config{BufferSpillDirectory: dir},
+				options: &uplink.UploadOptions{BufferSpillThreshold: memory.KiB.Int64()},
+			},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				project, err := tt.config.OpenProject(ctx, access)
+				require.NoError(t, err)
+				defer ctx.Check(project.Close)
+
+				createBucket(t, ctx, project, "testbucket")
+				defer func() {
+					_, err := project.DeleteBucketWithObjects(ctx, "testbucket")
+					require.NoError(t, err)
+				}()
+
+				expected := testrand.Bytes(10 * memory.KiB)
+
+				upload, err := project.UploadObject(ctx, "testbucket", "object", tt.options)
+				require.NoError(t, err)
+				_, err = upload.Write(expected)
+				require.NoError(t, err)
+
+				// the open segment is past the threshold, so it is on disk.
+				files, err := os.ReadDir(dir)
+				require.NoError(t, err)
+				require.Len(t, files, 1)
+
+				require.NoError(t, upload.Commit())
+
+				files, err = os.ReadDir(dir)
+				require.NoError(t, err)
+				require.Empty(t, files)
+
+				download, err := project.DownloadObject(ctx, "testbucket", "object", nil)
+				require.NoError(t, err)
+				data, err := io.ReadAll(download)
+				require.NoError(t, err)
+				require.NoError(t, download.Close())
+				require.Equal(t, expected, data)
+			})
+		}
+	})
+}
+
+func TestUploadMaximumConcurrentPieceUploads(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		config := uplink.Config{MaximumConcurrentPieceUploads: 2}
+		project, err := config.OpenProject(ctx, planet.Uplinks[0].Access[planet.Satellites[0].ID()])
+		require.NoError(t, err)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		expected := map[string][]byte{}
+		for i := 0; i < 4; i++ {
+			expected["object"+strconv.Itoa(i)] = testrand.Bytes(10 * memory.KiB)
+		}
+
+		// the stats never show more than the two piece uploads in progress.
+		maxActive := make(chan int, 1)
+		stopStats := make(chan struct{})
+		go func() {
+			active := 0
+			for {
+				select {
+				case <-stopStats:
+					maxActive <- active
+					return
+				case <-time.After(time.Millisecond):
+				}
+				if stats := project.PieceUploadStats(); stats.Active > active {
+					active = stats.Active
+				}
+			}
+		}()
+
+		// uploads of both kinds share the two piece uploads.
+		errs := make(chan error, len(expected))
+		i := 0
+		for key, data := range expected {
+			key, data, background := key, data, i%2 == 0
+			i++
+			go func() {
+				upload, err := project.UploadObject(ctx, "testbucket", key, &uplink.UploadOptions{Background: background})
+				if err != nil {
+					errs <- err
+					return
+				}
+				if _, err := upload.Write(data); err != nil {
+					_ = upload.Abort()
+					errs <- err
+					return
+				}
+				errs <- upload.Commit()
+			}()
+		}
+		for range expected {
+			require.NoError(t, <-errs)
+		}
+		close(stopStats)
+		require.LessOrEqual(t, <-maxActive, 2)
+		require.Equal(t, uplink.PieceUploadStats{}, project.PieceUploadStats())
+
+		info, err := project.BeginUpload(ctx, "testbucket", "multipart", nil)
+		require.NoError(t, err)
+		part, err := project.UploadPartWithOptions(ctx, "testbucket", "multipart", info.UploadID, 1, &uplink.UploadPartOptions{Background: true})
+		require.NoError(t, err)
+		expected["multipart"] = testrand.Bytes(10 * memory.KiB)
+		_, err = part.Write(expected["multipart"])
+		require.NoError(t, err)
+		require.NoError(t, part.Commit())
+		_, err = project.CommitUpload(ctx, "testbucket", "multipart", info.UploadID, nil)
+		require.NoError(t, err)
+
+		for key, data := range expected {
+			download, err := project.DownloadObject(ctx, "testbucket", key, nil)
+			require.NoError(t, err)
+			downloaded, err := io.ReadAll(download)
+			require.NoError(t, err)
+			require.NoError(t, download.Close())
+			require.Equal(t, data, downloaded, key)
+		}
+	})
+}
+
+func TestUploadExpectedSize(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		data := testrand.Bytes(20 * memory.KiB)
+		options := &uplink.UploadOptions{ExpectedSize: int64(len(data))}
+
+		download := func(t *testing.T, key string) []byte {
+			download, err := project.DownloadObject(ctx, "testbucket", key, nil)
+			require.NoError(t, err)
+			defer ctx.Check(download.Close)
+
+			downloaded, err := io.ReadAll(download)
+			require.NoError(t, err)
+			return downloaded
+		}
+
+		t.Run("Exact", func(t *testing.T) {
+			upload, err := project.UploadObject(ctx, "testbucket", "exact", options)
+			require.NoError(t, err)
+
+			_, err = io.Copy(upload, bytes.NewReader(data))
+			require.NoError(t, err)
+			require.NoError(t, upload.Commit())
+
+			require.Equal(t, data, download(t, "exact"))
+		})
+
+		t.Run("Fewer", func(t *testing.T) {
+			upload, err := project.UploadObject(ctx, "testbucket", "fewer", options)
+			require.NoError(t, err)
+
+			_, err = upload.Write(data[1:])
+			require.NoError(t, err)
+			require.True(t, errors.Is(upload.Commit(), uplink.ErrUploadSizeMismatch))
+
+			_, err = project.StatObject(ctx, "testbucket", "fewer")
+			require.True(t, errors.Is(err, uplink.ErrObjectNotFound))
+		})
+
+		t.Run("MoreWritten", func(t *testing.T) {
+			upload, err := project.UploadObject(ctx, "testbucket", "more-written", options)
+			require.NoError(t, err)
+
+			_, err = upload.Write(data)
+			require.NoError(t, err)
+			_, err = upload.Write([]byte{1})
+			require.True(t, errors.Is(err, uplink.ErrUploadSizeMismatch))
+
+			require.NoError(t, upload.Commit())
+			require.Equal(t, data, download(t, "more-written"))
+		})
+
+		t.Run("MoreCopied", func(t *testing.T) {
+			upload, err := project.UploadObject(ctx, "testbucket", "more-copied", options)
+			require.NoError(t, err)
+
+			source := bytes.NewReader(append(append([]byte{}, data...), 1, 2))
+			n, err := io.Copy(upload, source)
+			require.True(t, errors.Is(err, uplink.ErrUploadSizeMismatch))
+			require.EqualValues(t, len(data), n)
+			// only one byte past the expected size is read from the source.
+			require.Equal(t, 1, source.Len())
+
+			// the extra byte was not uploaded.
+			require.NoError(t, upload.Commit())
+			require.Equal(t, data, download(t, "more-copied"))
+		})
+	})
+}
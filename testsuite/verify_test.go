@@ -0,0 +1,98 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package testsuite_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"common/memory"
+	"common/testcontext"
+	"storx/private/testplanet"
+	"uplink"
+	"uplink/private/testuplink"
+)
+
+func TestVerifyObject(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		access := planet.Uplinks[0].Access[planet.Satellites[0].ID()]
+		project, err := uplink.OpenProject(testuplink.WithMaxSegmentSize(ctx, 20*memory.KiB), access)
+		require.NoError(t, err)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		_, err = project.VerifyObject(ctx, "testbucket", "missing")
+		require.True(t, errors.Is(err, uplink.ErrObjectNotFound))
+
+		t.Run("Inline", func(t *testing.T) {
+			uploadObject(t, ctx, project, "testbucket", "inline", memory.KiB)
+
+			verification, err := project.VerifyObject(ctx, "testbucket", "inline")
+			require.NoError(t, err)
+			require.Equal(t, "inline", verification.Object.Key)
+			require.True(t, verification.Intact())
+			require.Len(t, verification.Segments, 1)
+			require.True(t, verification.Segments[0].Inline)
+			require.Empty(t, verification.Segments[0].Pieces)
+		})
+
+		uploadObject(t, ctx, project, "testbucket", "remote", 50*memory.KiB)
+
+		t.Run("Remote", func(t *testing.T) {
+			verification, err := project.VerifyObject(ctx, "testbucket", "remote")
+			require.NoError(t, err)
+			require.True(t, verification.Intact())
+
+			// 20 KiB, 20 KiB and 10 KiB.
+			require.Len(t, verification.Segments, 3)
+			for i, segment := range verification.Segments {
+				require.EqualValues(t, i, segment.Index)
+				require.False(t, segment.Inline)
+				require.NotZero(t, segment.Stripes)
+				require.Zero(t, segment.UnrecoverableStripes)
+				require.Zero(t, segment.UncorrectableStripes)
+				require.GreaterOrEqual(t, len(segment.Pieces), segment.RedundancyScheme.Required)
+
+				for _, piece := range segment.Pieces {
+					require.True(t, piece.Intact())
+					require.NotEmpty(t, piece.NodeID)
+					require.NotZero(t, piece.Downloaded)
+					// only the stripes are checked.
+					require.False(t, piece.HashSent)
+					require.NoError(t, piece.HashError)
+				}
+			}
+		})
+
+		t.Run("StoppedNode", func(t *testing.T) {
+			require.NoError(t, planet.StopPeer(planet.StorageNodes[0]))
+			stopped := planet.StorageNodes[0].ID().String()
+
+			verification, err := project.VerifyObject(ctx, "testbucket", "remote")
+			require.NoError(t, err)
+			require.True(t, verification.Intact())
+
+			var failed int
+			for _, segment := range verification.Segments {
+				for _, piece := range segment.Pieces {
+					if piece.NodeID != stopped {
+						require.True(t, piece.Intact())
+						continue
+					}
+					require.Error(t, piece.DownloadError)
+					require.False(t, piece.Intact())
+					failed++
+				}
+			}
+			require.NotZero(t, failed)
+		})
+	})
+}
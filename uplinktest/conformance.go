@@ -0,0 +1,422 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package uplinktest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"uplink"
+)
+
+// RunConformance checks that project behaves like uplink.Project.
+//
+// It creates and deletes buckets with the prefix "conformance-", which must
+// not exist. Listings are compared without order, because the order of
+// encrypted keys differs from the order of their plain text.
+func RunConformance(t *testing.T, ctx context.Context, project Project) {
+	t.Run("Buckets", func(t *testing.T) { testBuckets(t, ctx, project) })
+	t.Run("Objects", func(t *testing.T) { testObjects(t, ctx, project) })
+	t.Run("ListObjects", func(t *testing.T) { testListObjects(t, ctx, project) })
+	t.Run("CopyMove", func(t *testing.T) { testCopyMove(t, ctx, project) })
+	t.Run("Multipart", func(t *testing.T) { testMultipart(t, ctx, project) })
+}
+
+func testBuckets(t *testing.T, ctx context.Context, project Project) {
+	const bucket = "conformance-buckets"
+
+	for _, name := range []string{"", "a", "Invalid_!?"} {
+		_, err := project.CreateBucket(ctx, name)
+		require.True(t, errors.Is(err, uplink.ErrBucketNameInvalid), "%q: %v", name, err)
+	}
+
+	_, err := project.StatBucket(ctx, bucket)
+	require.True(t, errors.Is(err, uplink.ErrBucketNotFound))
+
+	created, err := project.CreateBucket(ctx, bucket)
+	require.NoError(t, err)
+	require.Equal(t, bucket, created.Name)
+	require.False(t, created.Created.IsZero())
+
+	existing, err := project.CreateBucket(ctx, bucket)
+	require.True(t, errors.Is(err, uplink.ErrBucketAlreadyExists))
+	require.Equal(t, bucket, existing.Name)
+
+	ensured, err := project.EnsureBucket(ctx, bucket)
+	require.NoError(t, err)
+	require.Equal(t, bucket, ensured.Name)
+
+	stat, err := project.StatBucket(ctx, bucket)
+	require.NoError(t, err)
+	require.Equal(t, bucket, stat.Name)
+
+	var names []string
+	buckets := project.ListBuckets(ctx, nil)
+	for buckets.Next() {
+		names = append(names, buckets.Item().Name)
+	}
+	require.NoError(t, buckets.Err())
+	require.Nil(t, buckets.Item())
+	require.Contains(t, names, bucket)
+
+	upload(t, ctx, project, bucket, "object", []byte("data"), nil)
+
+	_, err = project.DeleteBucket(ctx, bucket)
+	require.True(t, errors.Is(err, uplink.ErrBucketNotEmpty))
+
+	deleted, err := project.DeleteBucketWithObjects(ctx, bucket)
+	require.NoError(t, err)
+	require.Equal(t, bucket, deleted.Name)
+
+	_, err = project.StatBucket(ctx, bucket)
+	require.True(t, errors.Is(err, uplink.ErrBucketNotFound))
+
+	_, err = project.DeleteBucket(ctx, bucket)
+	require.True(t, errors.Is(err, uplink.ErrBucketNotFound))
+}
+
+func testObjects(t *testing.T, ctx context.Context, project Project) {
+	const bucket = "conformance-objects"
+	createBucket(t, ctx, project, bucket)
+
+	data := []byte("0123456789")
+	metadata := uplink.CustomMetadata{"conformance:key": "value"}
+
+	_, err := project.UploadObject(ctx, bucket, "", nil)
+	require.True(t, errors.Is(err, uplink.ErrObjectKeyInvalid))
+	_, err = project.StatObject(ctx, bucket, "")
+	require.True(t, errors.Is(err, uplink.ErrObjectKeyInvalid))
+	_, err = project.DownloadObject(ctx, "", "object", nil)
+	require.True(t, errors.Is(err, uplink.ErrBucketNameInvalid))
+
+	committed := upload(t, ctx, project, bucket, "object", data, metadata)
+	require.Equal(t, "object", committed.Key)
+	require.Equal(t, int64(len(data)), committed.System.ContentLength)
+
+	stat, err := project.StatObject(ctx, bucket, "object")
+	require.NoError(t, err)
+	require.Equal(t, "object", stat.Key)
+	require.Equal(t, int64(len(data)), stat.System.ContentLength)
+	require.False(t, stat.System.Created.IsZero())
+	require.Equal(t, metadata, stat.Custom)
+
+	for _, tc := range []struct {
+		offset, length int64
+		expected       []byte
+	}{
+		{0, -1, data},
+		{2, 3, data[2:5]},
+		{5, -1, data[5:]},
+		{8, 100, data[8:]},
+		{-3, -1, data[7:]},
+	} {
+		require.Equal(t, tc.expected, download(t, ctx, project, bucket, "object", &uplink.DownloadOptions{
+			Offset: tc.offset,
+			Length: tc.length,
+		}), "offset %d, length %d", tc.offset, tc.length)
+	}
+
+	updated := uplink.CustomMetadata{"conformance:other": "other"}
+	require.NoError(t, project.UpdateObjectMetadata(ctx, bucket, "object", updated, nil))
+	stat, err = project.StatObject(ctx, bucket, "object")
+	require.NoError(t, err)
+	require.Equal(t, updated, stat.Custom)
+
+	err = project.UpdateObjectMetadata(ctx, bucket, "missing", updated, nil)
+	require.True(t, errors.Is(err, uplink.ErrObjectNotFound))
+
+	// uploading again replaces the object.
+	upload(t, ctx, project, bucket, "object", data[:4], nil)
+	require.Equal(t, data[:4], download(t, ctx, project, bucket, "object", nil))
+
+	// aborted uploads leave nothing behind.
+	aborted, err := project.UploadObject(ctx, bucket, "aborted", nil)
+	require.NoError(t, err)
+	_, err = aborted.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, aborted.Abort())
+	require.True(t, errors.Is(aborted.Commit(), uplink.ErrUploadDone))
+	_, err = project.StatObject(ctx, bucket, "aborted")
+	require.True(t, errors.Is(err, uplink.ErrObjectNotFound))
+
+	// the bucket is only needed at commit.
+	missing, err := project.UploadObject(ctx, "conformance-missing", "object", nil)
+	require.NoError(t, err)
+	_, err = missing.Write(data)
+	require.NoError(t, err)
+	require.True(t, errors.Is(missing.Commit(), uplink.ErrBucketNotFound))
+
+	deleted, err := project.DeleteObject(ctx, bucket, "object")
+	require.NoError(t, err)
+	require.Equal(t, "object", deleted.Key)
+
+	deleted, err = project.DeleteObject(ctx, bucket, "object")
+	require.NoError(t, err)
+	require.Nil(t, deleted)
+
+	_, err = project.StatObject(ctx, bucket, "object")
+	require.True(t, errors.Is(err, uplink.ErrObjectNotFound))
+	_, err = project.DownloadObject(ctx, bucket, "object", nil)
+	require.True(t, errors.Is(err, uplink.ErrObjectNotFound))
+
+	deleteBucket(t, ctx, project, bucket)
+}
+
+func testListObjects(t *testing.T, ctx context.Context, project Project) {
+	const bucket = "conformance-list"
+	createBucket(t, ctx, project, bucket)
+
+	for _, key := range []string{"a", "b/c", "b/d", "b/e/f"} {
+		upload(t, ctx, project, bucket, key, []byte(key), uplink.CustomMetadata{"key": key})
+	}
+
+	list := func(options *uplink.ListObjectsOptions) map[string]*uplink.Object {
+		objects := project.ListObjects(ctx, bucket, options)
+		require.Nil(t, objects.Item())
+
+		items := make(map[string]*uplink.Object)
+		for objects.Next() {
+			item := objects.Item()
+			items[item.Key] = item
+		}
+		require.NoError(t, objects.Err())
+		require.Nil(t, objects.Item())
+		return items
+	}
+
+	root := list(nil)
+	require.Equal(t, []string{"a", "b/"}, objectKeys(root))
+	require.False(t, root["a"].IsPrefix)
+	require.True(t, root["b/"].IsPrefix)
+	require.Zero(t, root["a"].System.ContentLength)
+	require.Empty(t, root["a"].Custom)
+
+	prefixed := list(&uplink.ListObjectsOptions{Prefix: "b/"})
+	require.Equal(t, []string{"b/c", "b/d", "b/e/"}, objectKeys(prefixed))
+	require.True(t, prefixed["b/e/"].IsPrefix)
+
+	recursive := list(&uplink.ListObjectsOptions{Recursive: true, System: true, Custom: true})
+	require.Equal(t, []string{"a", "b/c", "b/d", "b/e/f"}, objectKeys(recursive))
+	for key, item := range recursive {
+		require.False(t, item.IsPrefix)
+		require.Equal(t, int64(len(key)), item.System.ContentLength)
+		require.Equal(t, uplink.CustomMetadata{"key": key}, item.Custom)
+	}
+
+	objects := project.ListObjects(ctx, bucket, &uplink.ListObjectsOptions{Prefix: "b"})
+	require.False(t, objects.Next())
+	require.Error(t, objects.Err())
+
+	objects = project.ListObjects(ctx, "conformance-missing", nil)
+	require.False(t, objects.Next())
+	require.True(t, errors.Is(objects.Err(), uplink.ErrBucketNotFound))
+
+	deleteBucket(t, ctx, project, bucket)
+}
+
+func testCopyMove(t *testing.T, ctx context.Context, project Project) {
+	const bucket = "conformance-copy"
+	createBucket(t, ctx, project, bucket)
+
+	data := []byte("copy me")
+	upload(t, ctx, project, bucket, "source", data, uplink.CustomMetadata{"key": "value"})
+
+	_, err := project.CopyObject(ctx, bucket, "", bucket, "target", nil)
+	require.True(t, errors.Is(err, uplink.ErrObjectKeyInvalid))
+	_, err = project.CopyObject(ctx, bucket, "source", bucket, "prefix/", nil)
+	require.Error(t, err)
+	_, err = project.CopyObject(ctx, bucket, "missing", bucket, "target", nil)
+	require.True(t, errors.Is(err, uplink.ErrObjectNotFound))
+	_, err = project.CopyObject(ctx, bucket, "source", "conformance-missing", "target", nil)
+	require.True(t, errors.Is(err, uplink.ErrBucketNotFound))
+
+	copied, err := project.CopyObject(ctx, bucket, "source", bucket, "copy", nil)
+	require.NoError(t, err)
+	require.Equal(t, "copy", copied.Key)
+	require.Equal(t, data, download(t, ctx, project, bucket, "source", nil))
+	require.Equal(t, data, download(t, ctx, project, bucket, "copy", nil))
+
+	stat, err := project.StatObject(ctx, bucket, "copy")
+	require.NoError(t, err)
+	require.Equal(t, uplink.CustomMetadata{"key": "value"}, stat.Custom)
+
+	// copying over an existing object replaces it.
+	upload(t, ctx, project, bucket, "existing", []byte("old"), nil)
+	_, err = project.CopyObject(ctx, bucket, "source", bucket, "existing", nil)
+	require.NoError(t, err)
+	require.Equal(t, data, download(t, ctx, project, bucket, "existing", nil))
+
+	require.NoError(t, project.MoveObject(ctx, bucket, "copy", bucket, "moved", nil))
+	_, err = project.StatObject(ctx, bucket, "copy")
+	require.True(t, errors.Is(err, uplink.ErrObjectNotFound))
+	require.Equal(t, data, download(t, ctx, project, bucket, "moved", nil))
+
+	err = project.MoveObject(ctx, bucket, "missing", bucket, "target", nil)
+	require.True(t, errors.Is(err, uplink.ErrObjectNotFound))
+	err = project.MoveObject(ctx, bucket, "moved", "conformance-missing", "target", nil)
+	require.True(t, errors.Is(err, uplink.ErrBucketNotFound))
+
+	// moving over an existing object fails.
+	require.Error(t, project.MoveObject(ctx, bucket, "moved", bucket, "existing", nil))
+
+	deleteBucket(t, ctx, project, bucket)
+}
+
+func testMultipart(t *testing.T, ctx context.Context, project Project) {
+	const bucket = "conformance-multipart"
+
+	_, err := project.BeginUpload(ctx, bucket, "object", nil)
+	require.True(t, errors.Is(err, uplink.ErrBucketNotFound))
+
+	createBucket(t, ctx, project, bucket)
+
+	_, err = project.BeginUpload(ctx, bucket, "", nil)
+	require.True(t, errors.Is(err, uplink.ErrObjectKeyInvalid))
+
+	info, err := project.BeginUpload(ctx, bucket, "dir/object", nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, info.UploadID)
+
+	other, err := project.BeginUpload(ctx, bucket, "other", nil)
+	require.NoError(t, err)
+
+	uploads := func(options *uplink.ListUploadsOptions) map[string]*uplink.UploadInfo {
+		iterator := project.ListUploads(ctx, bucket, options)
+		items := make(map[string]*uplink.UploadInfo)
+		for iterator.Next() {
+			item := iterator.Item()
+			items[item.Key] = item
+		}
+		require.NoError(t, iterator.Err())
+		return items
+	}
+
+	root := uploads(nil)
+	require.Equal(t, []string{"dir/", "other"}, uploadKeys(root))
+	require.True(t, root["dir/"].IsPrefix)
+	require.Equal(t, other.UploadID, root["other"].UploadID)
+
+	recursive := uploads(&uplink.ListUploadsOptions{Recursive: true, System: true})
+	require.Equal(t, []string{"dir/object", "other"}, uploadKeys(recursive))
+	require.Equal(t, info.UploadID, recursive["dir/object"].UploadID)
+	require.False(t, recursive["dir/object"].System.Created.IsZero())
+
+	exact := uploads(&uplink.ListUploadsOptions{Prefix: "other"})
+	require.Equal(t, []string{"other"}, uploadKeys(exact))
+
+	part1, part2 := []byte("first part "), []byte("second part")
+	for _, part := range []struct {
+		number uint32
+		data   []byte
+	}{{2, part2}, {1, part1}} {
+		upload, err := project.UploadPart(ctx, bucket, "dir/object", info.UploadID, part.number)
+		require.NoError(t, err)
+		_, err = upload.Write(part.data)
+		require.NoError(t, err)
+		require.NoError(t, upload.SetETag([]byte{byte(part.number)}))
+		require.NoError(t, upload.Commit())
+		require.Equal(t, part.number, upload.Info().PartNumber)
+		require.True(t, errors.Is(upload.Commit(), uplink.ErrUploadDone))
+	}
+
+	aborted, err := project.UploadPart(ctx, bucket, "dir/object", info.UploadID, 3)
+	require.NoError(t, err)
+	_, err = aborted.Write([]byte("aborted"))
+	require.NoError(t, err)
+	require.NoError(t, aborted.Abort())
+
+	var parts []*uplink.Part
+	iterator := project.ListUploadParts(ctx, bucket, "dir/object", info.UploadID, nil)
+	for iterator.Next() {
+		parts = append(parts, iterator.Item())
+	}
+	require.NoError(t, iterator.Err())
+	sort.Slice(parts, func(i, k int) bool { return parts[i].PartNumber < parts[k].PartNumber })
+	require.Len(t, parts, 2)
+	for i, part := range parts {
+		require.Equal(t, uint32(i+1), part.PartNumber)
+		require.Equal(t, []byte{byte(i + 1)}, part.ETag)
+	}
+	require.Equal(t, int64(len(part1)), parts[0].Size)
+	require.Equal(t, int64(len(part2)), parts[1].Size)
+
+	metadata := uplink.CustomMetadata{"key": "value"}
+	object, err := project.CommitUpload(ctx, bucket, "dir/object", info.UploadID, &uplink.CommitUploadOptions{
+		CustomMetadata: metadata,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "dir/object", object.Key)
+	require.Equal(t, int64(len(part1)+len(part2)), object.System.ContentLength)
+
+	require.Equal(t, append(append([]byte{}, part1...), part2...), download(t, ctx, project, bucket, "dir/object", nil))
+	stat, err := project.StatObject(ctx, bucket, "dir/object")
+	require.NoError(t, err)
+	require.Equal(t, metadata, stat.Custom)
+
+	err = project.AbortUpload(ctx, bucket, "other", "")
+	require.True(t, errors.Is(err, uplink.ErrUploadIDInvalid))
+	require.NoError(t, project.AbortUpload(ctx, bucket, "other", other.UploadID))
+
+	require.Empty(t, uploads(&uplink.ListUploadsOptions{Recursive: true}))
+
+	deleteBucket(t, ctx, project, bucket)
+}
+
+func createBucket(t *testing.T, ctx context.Context, project Project, bucket string) {
+	_, err := project.CreateBucket(ctx, bucket)
+	require.NoError(t, err)
+}
+
+func deleteBucket(t *testing.T, ctx context.Context, project Project, bucket string) {
+	_, err := project.DeleteBucketWithObjects(ctx, bucket)
+	require.NoError(t, err)
+}
+
+func upload(t *testing.T, ctx context.Context, project Project, bucket, key string, data []byte, metadata uplink.CustomMetadata) *uplink.Object {
+	upload, err := project.UploadObject(ctx, bucket, key, nil)
+	require.NoError(t, err)
+
+	_, err = upload.Write(data)
+	require.NoError(t, err)
+	if metadata != nil {
+		require.NoError(t, upload.SetCustomMetadata(ctx, metadata))
+	}
+	require.NoError(t, upload.Commit())
+	return upload.Info()
+}
+
+func download(t *testing.T, ctx context.Context, project Project, bucket, key string, options *uplink.DownloadOptions) []byte {
+	download, err := project.DownloadObject(ctx, bucket, key, options)
+	require.NoError(t, err)
+
+	var data bytes.Buffer
+	_, err = io.Copy(&data, download)
+	require.NoError(t, err)
+	require.NoError(t, download.Close())
+	return data.Bytes()
+}
+
+func objectKeys(objects map[string]*uplink.Object) []string {
+	keys := make([]string, 0, len(objects))
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func uploadKeys(uploads map[string]*uplink.UploadInfo) []string {
+	keys := make([]string, 0, len(uploads))
+	for key := range uploads {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,9 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+// Package uplinktest helps unit testing code that uses uplink.
+//
+// Code accepts a Project instead of *uplink.Project, and tests use the
+// in-memory NewProject instead of a satellite and storage nodes.
+// RunConformance checks that a Project behaves like uplink.Project.
+package uplinktest
@@ -0,0 +1,361 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package uplinktest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zeebo/errs"
+
+	"uplink"
+)
+
+// packageError matches the error class of uplink, so that errors read the
+// same.
+var packageError = errs.Class("uplink")
+
+// fakeProject is an in-memory Project.
+type fakeProject struct {
+	mu      sync.Mutex
+	buckets map[string]*fakeBucket
+}
+
+// fakeBucket is a bucket with its committed objects and uncommitted uploads.
+type fakeBucket struct {
+	created time.Time
+	objects map[string]*fakeObject
+	uploads map[string]*fakeUpload
+}
+
+// fakeObject is a committed object. Its data is never modified.
+type fakeObject struct {
+	data    []byte
+	created time.Time
+	expires time.Time
+	custom  uplink.CustomMetadata
+}
+
+// NewProject returns an in-memory Project.
+//
+// It keeps buckets, objects and uploads in memory and implements the same
+// validation, errors and listing as uplink.Project, without encryption,
+// access restrictions or limits.
+func NewProject() Project {
+	return &fakeProject{
+		buckets: make(map[string]*fakeBucket),
+	}
+}
+
+// Close does nothing.
+func (project *fakeProject) Close() error { return nil }
+
+// StatBucket returns information about a bucket.
+func (project *fakeProject) StatBucket(ctx context.Context, bucket string) (*uplink.Bucket, error) {
+	project.mu.Lock()
+	defer project.mu.Unlock()
+
+	b, err := project.bucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return &uplink.Bucket{Name: bucket, Created: b.created}, nil
+}
+
+// CreateBucket creates a new bucket.
+func (project *fakeProject) CreateBucket(ctx context.Context, bucket string) (*uplink.Bucket, error) {
+	if err := validateBucketName(bucket); err != nil {
+		return nil, err
+	}
+
+	project.mu.Lock()
+	defer project.mu.Unlock()
+
+	if b, ok := project.buckets[bucket]; ok {
+		return &uplink.Bucket{Name: bucket, Created: b.created}, errwrapf("%w (%q)", uplink.ErrBucketAlreadyExists, bucket)
+	}
+
+	b := &fakeBucket{
+		created: time.Now(),
+		objects: make(map[string]*fakeObject),
+		uploads: make(map[string]*fakeUpload),
+	}
+	project.buckets[bucket] = b
+	return &uplink.Bucket{Name: bucket, Created: b.created}, nil
+}
+
+// EnsureBucket ensures that a bucket exists or creates a new one.
+func (project *fakeProject) EnsureBucket(ctx context.Context, bucket string) (*uplink.Bucket, error) {
+	ensured, err := project.CreateBucket(ctx, bucket)
+	if err != nil && !errors.Is(err, uplink.ErrBucketAlreadyExists) {
+		return nil, err
+	}
+	return ensured, nil
+}
+
+// DeleteBucket deletes an empty bucket.
+func (project *fakeProject) DeleteBucket(ctx context.Context, bucket string) (*uplink.Bucket, error) {
+	return project.deleteBucket(bucket, false)
+}
+
+// DeleteBucketWithObjects deletes a bucket and everything in it.
+func (project *fakeProject) DeleteBucketWithObjects(ctx context.Context, bucket string) (*uplink.Bucket, error) {
+	return project.deleteBucket(bucket, true)
+}
+
+func (project *fakeProject) deleteBucket(bucket string, withObjects bool) (*uplink.Bucket, error) {
+	project.mu.Lock()
+	defer project.mu.Unlock()
+
+	b, err := project.bucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !withObjects && (len(b.objects) > 0 || len(b.uploads) > 0) {
+		return nil, errwrapf("%w (%q)", uplink.ErrBucketNotEmpty, bucket)
+	}
+
+	delete(project.buckets, bucket)
+	return &uplink.Bucket{Name: bucket, Created: b.created}, nil
+}
+
+// ListBuckets returns an iterator over the buckets.
+func (project *fakeProject) ListBuckets(ctx context.Context, options *uplink.ListBucketsOptions) BucketIterator {
+	if options == nil {
+		options = &uplink.ListBucketsOptions{}
+	}
+
+	return &bucketIterator{
+		pos: -1,
+		list: func() ([]*uplink.Bucket, error) {
+			project.mu.Lock()
+			defer project.mu.Unlock()
+
+			var buckets []*uplink.Bucket
+			for name, b := range project.buckets {
+				if name > options.Cursor {
+					buckets = append(buckets, &uplink.Bucket{Name: name, Created: b.created})
+				}
+			}
+			sort.Slice(buckets, func(i, k int) bool {
+				return buckets[i].Name < buckets[k].Name
+			})
+			return buckets, nil
+		},
+	}
+}
+
+// StatObject returns information about an object.
+func (project *fakeProject) StatObject(ctx context.Context, bucket, key string) (*uplink.Object, error) {
+	project.mu.Lock()
+	defer project.mu.Unlock()
+
+	obj, err := project.object(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	return obj.info(key), nil
+}
+
+// DeleteObject deletes an object. It returns nil when there is no object.
+func (project *fakeProject) DeleteObject(ctx context.Context, bucket, key string) (*uplink.Object, error) {
+	project.mu.Lock()
+	defer project.mu.Unlock()
+
+	obj, err := project.object(bucket, key)
+	if err != nil {
+		if errors.Is(err, uplink.ErrObjectNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	delete(project.buckets[bucket].objects, key)
+	return obj.info(key), nil
+}
+
+// UpdateObjectMetadata replaces the custom metadata of an object.
+func (project *fakeProject) UpdateObjectMetadata(ctx context.Context, bucket, key string, newMetadata uplink.CustomMetadata, options *uplink.UploadObjectMetadataOptions) error {
+	project.mu.Lock()
+	defer project.mu.Unlock()
+
+	obj, err := project.object(bucket, key)
+	if err != nil {
+		return err
+	}
+
+	// objects are replaced rather than modified, so that downloads and
+	// listings already made don't change.
+	updated := *obj
+	updated.custom = newMetadata.Clone()
+	project.buckets[bucket].objects[key] = &updated
+	return nil
+}
+
+// CopyObject copies an object, replacing any object at the new key.
+func (project *fakeProject) CopyObject(ctx context.Context, oldBucket, oldKey, newBucket, newKey string, options *uplink.CopyObjectOptions) (*uplink.Object, error) {
+	if err := validateMoveCopyInput(oldBucket, oldKey, newBucket, newKey); err != nil {
+		return nil, err
+	}
+
+	project.mu.Lock()
+	defer project.mu.Unlock()
+
+	obj, target, err := project.moveCopySource(oldBucket, oldKey, newBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	copied := &fakeObject{
+		data:    obj.data,
+		created: time.Now(),
+		expires: obj.expires,
+		custom:  obj.custom.Clone(),
+	}
+	target.objects[newKey] = copied
+	return copied.info(newKey), nil
+}
+
+// MoveObject moves an object. It fails when there is an object at the new
+// key.
+func (project *fakeProject) MoveObject(ctx context.Context, oldBucket, oldKey, newBucket, newKey string, options *uplink.MoveObjectOptions) error {
+	if err := validateMoveCopyInput(oldBucket, oldKey, newBucket, newKey); err != nil {
+		return err
+	}
+
+	project.mu.Lock()
+	defer project.mu.Unlock()
+
+	obj, target, err := project.moveCopySource(oldBucket, oldKey, newBucket)
+	if err != nil {
+		return err
+	}
+	if existing, ok := target.objects[newKey]; ok && existing.visible() {
+		return packageError.New("object already exists (%q)", newKey)
+	}
+
+	delete(project.buckets[oldBucket].objects, oldKey)
+	target.objects[newKey] = obj
+	return nil
+}
+
+// moveCopySource returns the object to move or copy and the bucket to move
+// or copy it to.
+func (project *fakeProject) moveCopySource(oldBucket, oldKey, newBucket string) (*fakeObject, *fakeBucket, error) {
+	if _, err := project.bucket(oldBucket); err != nil {
+		return nil, nil, err
+	}
+	target, err := project.bucket(newBucket)
+	if err != nil {
+		return nil, nil, err
+	}
+	obj, err := project.object(oldBucket, oldKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return obj, target, nil
+}
+
+// bucket returns an existing bucket. project.mu must be held.
+func (project *fakeProject) bucket(bucket string) (*fakeBucket, error) {
+	if bucket == "" {
+		return nil, errwrapf("%w (%q)", uplink.ErrBucketNameInvalid, bucket)
+	}
+	b, ok := project.buckets[bucket]
+	if !ok {
+		return nil, errwrapf("%w (%q)", uplink.ErrBucketNotFound, bucket)
+	}
+	return b, nil
+}
+
+// object returns a committed object that has not expired. project.mu must
+// be held.
+//
+// Like uplink.Project, a missing bucket is reported as a missing object.
+func (project *fakeProject) object(bucket, key string) (*fakeObject, error) {
+	switch {
+	case bucket == "":
+		return nil, errwrapf("%w (%q)", uplink.ErrBucketNameInvalid, bucket)
+	case key == "":
+		return nil, errwrapf("%w (%q)", uplink.ErrObjectKeyInvalid, key)
+	}
+
+	b, ok := project.buckets[bucket]
+	if !ok {
+		return nil, errwrapf("%w (%q)", uplink.ErrObjectNotFound, key)
+	}
+	obj, ok := b.objects[key]
+	if !ok || !obj.visible() {
+		return nil, errwrapf("%w (%q)", uplink.ErrObjectNotFound, key)
+	}
+	return obj, nil
+}
+
+// visible returns whether the object has not expired.
+func (obj *fakeObject) visible() bool {
+	return obj.expires.IsZero() || obj.expires.After(time.Now())
+}
+
+// info returns the uplink.Object of obj at key.
+func (obj *fakeObject) info(key string) *uplink.Object {
+	return &uplink.Object{
+		Key: key,
+		System: uplink.SystemMetadata{
+			Created:       obj.created,
+			Expires:       obj.expires,
+			ContentLength: int64(len(obj.data)),
+		},
+		Custom: obj.custom.Clone(),
+	}
+}
+
+// validateBucketName checks the bucket naming rules of the satellite.
+func validateBucketName(bucket string) error {
+	if len(bucket) < 3 || len(bucket) > 63 || net.ParseIP(bucket) != nil {
+		return errwrapf("%w (%q)", uplink.ErrBucketNameInvalid, bucket)
+	}
+
+	for _, label := range strings.Split(bucket, ".") {
+		if label == "" || label[0] == '-' || label[len(label)-1] == '-' {
+			return errwrapf("%w (%q)", uplink.ErrBucketNameInvalid, bucket)
+		}
+		for _, r := range label {
+			if !('a' <= r && r <= 'z' || '0' <= r && r <= '9' || r == '-') {
+				return errwrapf("%w (%q)", uplink.ErrBucketNameInvalid, bucket)
+			}
+		}
+	}
+	return nil
+}
+
+func validateMoveCopyInput(oldBucket, oldKey, newBucket, newKey string) error {
+	switch {
+	case oldBucket == "":
+		return errwrapf("%w (%q)", uplink.ErrBucketNameInvalid, oldBucket)
+	case oldKey == "":
+		return errwrapf("%w (%q)", uplink.ErrObjectKeyInvalid, oldKey)
+	case strings.HasSuffix(oldKey, "/"):
+		return packageError.New("oldkey cannot be a prefix")
+	case newBucket == "":
+		return errwrapf("%w (%q)", uplink.ErrBucketNameInvalid, newBucket)
+	case newKey == "":
+		return errwrapf("%w (%q)", uplink.ErrObjectKeyInvalid, newKey)
+	case strings.HasSuffix(newKey, "/"):
+		return packageError.New("newkey cannot be a prefix")
+	}
+	return nil
+}
+
+func errwrapf(format string, err error, args ...interface{}) error {
+	var all []interface{}
+	all = append(all, err)
+	all = append(all, args...)
+	return packageError.Wrap(fmt.Errorf(format, all...))
+}
@@ -0,0 +1,293 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package uplinktest
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"uplink"
+)
+
+// ListObjects returns an iterator over the objects.
+func (project *fakeProject) ListObjects(ctx context.Context, bucket string, options *uplink.ListObjectsOptions) ObjectIterator {
+	if options == nil {
+		options = &uplink.ListObjectsOptions{}
+	}
+
+	return &objectIterator{
+		pos: -1,
+		list: func() ([]*uplink.Object, error) {
+			if options.Prefix != "" && !strings.HasSuffix(options.Prefix, "/") {
+				return nil, packageError.New("prefix should end with slash")
+			}
+
+			project.mu.Lock()
+			defer project.mu.Unlock()
+
+			b, err := project.bucket(bucket)
+			if err != nil {
+				return nil, err
+			}
+
+			keys := make([]string, 0, len(b.objects))
+			for key, obj := range b.objects {
+				if obj.visible() {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+
+			var objects []*uplink.Object
+			for _, entry := range listEntries(keys, options.Prefix, options.Cursor, options.Recursive) {
+				if entry.isPrefix {
+					objects = append(objects, &uplink.Object{Key: entry.key, IsPrefix: true})
+					continue
+				}
+
+				object := b.objects[entry.key].info(entry.key)
+				if !options.System {
+					object.System = uplink.SystemMetadata{}
+				}
+				if !options.Custom {
+					object.Custom = nil
+				}
+				objects = append(objects, object)
+			}
+			return objects, nil
+		},
+	}
+}
+
+// ListUploads returns an iterator over the uncommitted uploads.
+//
+// Like uplink.Project, a prefix that doesn't end with a slash lists the
+// uploads of that exact key.
+func (project *fakeProject) ListUploads(ctx context.Context, bucket string, options *uplink.ListUploadsOptions) UploadIterator {
+	if options == nil {
+		options = &uplink.ListUploadsOptions{}
+	}
+
+	return &uploadIterator{
+		pos: -1,
+		list: func() ([]*uplink.UploadInfo, error) {
+			project.mu.Lock()
+			defer project.mu.Unlock()
+
+			b, err := project.bucket(bucket)
+			if err != nil {
+				return nil, err
+			}
+
+			pending := make([]*fakeUpload, 0, len(b.uploads))
+			for _, upload := range b.uploads {
+				pending = append(pending, upload)
+			}
+			sort.Slice(pending, func(i, k int) bool {
+				if pending[i].key != pending[k].key {
+					return pending[i].key < pending[k].key
+				}
+				return pending[i].created.Before(pending[k].created)
+			})
+
+			var uploads []*uplink.UploadInfo
+			if options.Prefix != "" && !strings.HasSuffix(options.Prefix, "/") {
+				for _, upload := range pending {
+					if upload.key == options.Prefix {
+						info := upload.info(upload.key, options.System, options.Custom)
+						uploads = append(uploads, &info)
+					}
+				}
+				return uploads, nil
+			}
+
+			keys := make([]string, len(pending))
+			for i, upload := range pending {
+				keys[i] = upload.key
+			}
+
+			for _, entry := range listEntries(keys, options.Prefix, options.Cursor, options.Recursive) {
+				if entry.isPrefix {
+					uploads = append(uploads, &uplink.UploadInfo{Key: entry.key, IsPrefix: true})
+					continue
+				}
+				info := pending[entry.index].info(entry.key, options.System, options.Custom)
+				uploads = append(uploads, &info)
+			}
+			return uploads, nil
+		},
+	}
+}
+
+// listEntry is a key or a collapsed prefix in a listing.
+type listEntry struct {
+	key      string
+	isPrefix bool
+	// index is the position of the key in the listed keys.
+	index int
+}
+
+// listEntries lists the sorted keys under prefix that come after cursor,
+// which is relative to prefix. Unless recursive, keys that continue past
+// the next slash are collapsed into a single prefix entry.
+func listEntries(keys []string, prefix, cursor string, recursive bool) []listEntry {
+	var entries []listEntry
+	for i, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		entry := listEntry{key: key, index: i}
+		relative := key[len(prefix):]
+		if !recursive {
+			if slash := strings.IndexByte(relative, '/'); slash >= 0 {
+				relative = relative[:slash+1]
+				entry = listEntry{key: prefix + relative, isPrefix: true, index: i}
+			}
+		}
+
+		if relative <= cursor {
+			continue
+		}
+		// keys under the same prefix are next to each other.
+		if entry.isPrefix && len(entries) > 0 && entries[len(entries)-1].key == entry.key {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// bucketIterator iterates over buckets listed on the first call to Next.
+type bucketIterator struct {
+	list  func() ([]*uplink.Bucket, error)
+	items []*uplink.Bucket
+	pos   int
+	err   error
+}
+
+// Next prepares the next bucket for reading.
+func (it *bucketIterator) Next() bool {
+	if it.list != nil {
+		it.items, it.err = it.list()
+		it.list = nil
+	}
+	if it.err != nil || it.pos >= len(it.items)-1 {
+		it.pos = len(it.items)
+		return false
+	}
+	it.pos++
+	return true
+}
+
+// Item returns the current bucket.
+func (it *bucketIterator) Item() *uplink.Bucket {
+	if it.pos < 0 || it.pos >= len(it.items) {
+		return nil
+	}
+	return it.items[it.pos]
+}
+
+// Err returns the error, if one happened while listing.
+func (it *bucketIterator) Err() error { return it.err }
+
+// objectIterator iterates over objects listed on the first call to Next.
+type objectIterator struct {
+	list  func() ([]*uplink.Object, error)
+	items []*uplink.Object
+	pos   int
+	err   error
+}
+
+// Next prepares the next object for reading.
+func (it *objectIterator) Next() bool {
+	if it.list != nil {
+		it.items, it.err = it.list()
+		it.list = nil
+	}
+	if it.err != nil || it.pos >= len(it.items)-1 {
+		it.pos = len(it.items)
+		return false
+	}
+	it.pos++
+	return true
+}
+
+// Item returns the current object.
+func (it *objectIterator) Item() *uplink.Object {
+	if it.pos < 0 || it.pos >= len(it.items) {
+		return nil
+	}
+	return it.items[it.pos]
+}
+
+// Err returns the error, if one happened while listing.
+func (it *objectIterator) Err() error { return it.err }
+
+// uploadIterator iterates over uploads listed on the first call to Next.
+type uploadIterator struct {
+	list  func() ([]*uplink.UploadInfo, error)
+	items []*uplink.UploadInfo
+	pos   int
+	err   error
+}
+
+// Next prepares the next upload for reading.
+func (it *uploadIterator) Next() bool {
+	if it.list != nil {
+		it.items, it.err = it.list()
+		it.list = nil
+	}
+	if it.err != nil || it.pos >= len(it.items)-1 {
+		it.pos = len(it.items)
+		return false
+	}
+	it.pos++
+	return true
+}
+
+// Item returns the current upload.
+func (it *uploadIterator) Item() *uplink.UploadInfo {
+	if it.pos < 0 || it.pos >= len(it.items) {
+		return nil
+	}
+	return it.items[it.pos]
+}
+
+// Err returns the error, if one happened while listing.
+func (it *uploadIterator) Err() error { return it.err }
+
+// partIterator iterates over parts listed on the first call to Next.
+type partIterator struct {
+	list  func() ([]*uplink.Part, error)
+	items []*uplink.Part
+	pos   int
+	err   error
+}
+
+// Next prepares the next part for reading.
+func (it *partIterator) Next() bool {
+	if it.list != nil {
+		it.items, it.err = it.list()
+		it.list = nil
+	}
+	if it.err != nil || it.pos >= len(it.items)-1 {
+		it.pos = len(it.items)
+		return false
+	}
+	it.pos++
+	return true
+}
+
+// Item returns the current part.
+func (it *partIterator) Item() *uplink.Part {
+	if it.pos < 0 || it.pos >= len(it.items) {
+		return nil
+	}
+	return it.items[it.pos]
+}
+
+// Err returns the error, if one happened while listing.
+func (it *partIterator) Err() error { return it.err }
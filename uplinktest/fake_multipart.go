@@ -0,0 +1,299 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package uplinktest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	"uplink"
+)
+
+// fakeUploadIDSize is the number of random bytes in an upload ID.
+const fakeUploadIDSize = 16
+
+// fakeUpload is an uncommitted multipart upload.
+type fakeUpload struct {
+	id      string
+	key     string
+	created time.Time
+	expires time.Time
+	custom  uplink.CustomMetadata
+	parts   map[uint32]*uplink.Part
+	data    map[uint32][]byte
+}
+
+// BeginUpload begins a new multipart upload to bucket and key.
+func (project *fakeProject) BeginUpload(ctx context.Context, bucket, key string, options *uplink.UploadOptions) (uplink.UploadInfo, error) {
+	switch {
+	case bucket == "":
+		return uplink.UploadInfo{}, errwrapf("%w (%q)", uplink.ErrBucketNameInvalid, bucket)
+	case key == "":
+		return uplink.UploadInfo{}, errwrapf("%w (%q)", uplink.ErrObjectKeyInvalid, key)
+	}
+	if options == nil {
+		options = &uplink.UploadOptions{}
+	}
+
+	project.mu.Lock()
+	defer project.mu.Unlock()
+
+	b, err := project.bucket(bucket)
+	if err != nil {
+		return uplink.UploadInfo{}, err
+	}
+
+	var id [fakeUploadIDSize]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return uplink.UploadInfo{}, packageError.Wrap(err)
+	}
+
+	upload := &fakeUpload{
+		id:      hex.EncodeToString(id[:]),
+		key:     key,
+		created: time.Now(),
+		expires: options.Expires,
+		parts:   make(map[uint32]*uplink.Part),
+		data:    make(map[uint32][]byte),
+	}
+	b.uploads[upload.id] = upload
+	return upload.info(key, true, true), nil
+}
+
+// UploadPart uploads a part to an upload. The part replaces any part with
+// the same number when it is committed.
+func (project *fakeProject) UploadPart(ctx context.Context, bucket, key, uploadID string, partNumber uint32) (PartUpload, error) {
+	project.mu.Lock()
+	defer project.mu.Unlock()
+
+	if _, err := project.upload(bucket, key, uploadID); err != nil {
+		return nil, err
+	}
+
+	return &fakePartUpload{
+		project:  project,
+		bucket:   bucket,
+		key:      key,
+		uploadID: uploadID,
+		part:     &uplink.Part{PartNumber: partNumber},
+	}, nil
+}
+
+// CommitUpload commits an upload as an object made of its parts in part
+// number order, replacing any object at its key.
+func (project *fakeProject) CommitUpload(ctx context.Context, bucket, key, uploadID string, options *uplink.CommitUploadOptions) (*uplink.Object, error) {
+	if options == nil {
+		options = &uplink.CommitUploadOptions{}
+	}
+
+	project.mu.Lock()
+	defer project.mu.Unlock()
+
+	upload, err := project.upload(bucket, key, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	numbers := make([]uint32, 0, len(upload.data))
+	for number := range upload.data {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, k int) bool { return numbers[i] < numbers[k] })
+
+	var data []byte
+	for _, number := range numbers {
+		data = append(data, upload.data[number]...)
+	}
+
+	b := project.buckets[bucket]
+	delete(b.uploads, uploadID)
+
+	obj := &fakeObject{
+		data:    data,
+		created: time.Now(),
+		expires: upload.expires,
+		custom:  options.CustomMetadata.Clone(),
+	}
+	b.objects[key] = obj
+	return obj.info(key), nil
+}
+
+// AbortUpload aborts an upload and discards its parts.
+func (project *fakeProject) AbortUpload(ctx context.Context, bucket, key, uploadID string) error {
+	project.mu.Lock()
+	defer project.mu.Unlock()
+
+	if _, err := project.upload(bucket, key, uploadID); err != nil {
+		return err
+	}
+	delete(project.buckets[bucket].uploads, uploadID)
+	return nil
+}
+
+// ListUploadParts returns an iterator over the committed parts of an upload.
+func (project *fakeProject) ListUploadParts(ctx context.Context, bucket, key, uploadID string, options *uplink.ListUploadPartsOptions) PartIterator {
+	if options == nil {
+		options = &uplink.ListUploadPartsOptions{}
+	}
+
+	return &partIterator{
+		pos: -1,
+		list: func() ([]*uplink.Part, error) {
+			project.mu.Lock()
+			defer project.mu.Unlock()
+
+			upload, err := project.upload(bucket, key, uploadID)
+			if err != nil {
+				return nil, err
+			}
+
+			var parts []*uplink.Part
+			for number, part := range upload.parts {
+				if number > options.Cursor {
+					part := *part
+					parts = append(parts, &part)
+				}
+			}
+			sort.Slice(parts, func(i, k int) bool {
+				return parts[i].PartNumber < parts[k].PartNumber
+			})
+			return parts, nil
+		},
+	}
+}
+
+// upload returns an uncommitted upload. project.mu must be held.
+func (project *fakeProject) upload(bucket, key, uploadID string) (*fakeUpload, error) {
+	switch {
+	case bucket == "":
+		return nil, errwrapf("%w (%q)", uplink.ErrBucketNameInvalid, bucket)
+	case key == "":
+		return nil, errwrapf("%w (%q)", uplink.ErrObjectKeyInvalid, key)
+	}
+
+	if id, err := hex.DecodeString(uploadID); err != nil || len(id) != fakeUploadIDSize {
+		return nil, packageError.Wrap(uplink.ErrUploadIDInvalid)
+	}
+
+	b, err := project.bucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+	upload, ok := b.uploads[uploadID]
+	if !ok || upload.key != key {
+		return nil, errwrapf("%w (%q)", uplink.ErrObjectNotFound, key)
+	}
+	return upload, nil
+}
+
+// info returns the uplink.UploadInfo of upload listed at key.
+func (upload *fakeUpload) info(key string, system, custom bool) uplink.UploadInfo {
+	info := uplink.UploadInfo{
+		UploadID: upload.id,
+		Key:      key,
+	}
+	if system {
+		info.System = uplink.SystemMetadata{
+			Created: upload.created,
+			Expires: upload.expires,
+		}
+		for _, part := range upload.parts {
+			info.System.ContentLength += part.Size
+		}
+	}
+	if custom {
+		info.Custom = upload.custom.Clone()
+	}
+	return info
+}
+
+// fakePartUpload is an upload of a part of a fakeUpload.
+type fakePartUpload struct {
+	project  *fakeProject
+	bucket   string
+	key      string
+	uploadID string
+
+	mu   sync.Mutex
+	done bool
+	data bytes.Buffer
+	part *uplink.Part
+}
+
+// Info returns the last information about the uploaded part.
+func (upload *fakePartUpload) Info() *uplink.Part {
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	part := *upload.part
+	return &part
+}
+
+// Write buffers p until Commit.
+func (upload *fakePartUpload) Write(p []byte) (int, error) {
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	if upload.done {
+		return 0, packageError.Wrap(uplink.ErrUploadDone)
+	}
+
+	n, _ := upload.data.Write(p)
+	upload.part.Size = int64(upload.data.Len())
+	return n, nil
+}
+
+// SetETag sets the ETag the part is committed with.
+func (upload *fakePartUpload) SetETag(eTag []byte) error {
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	if upload.done {
+		return packageError.Wrap(uplink.ErrUploadDone)
+	}
+	upload.part.ETag = append([]byte(nil), eTag...)
+	return nil
+}
+
+// Commit adds the part to its upload.
+func (upload *fakePartUpload) Commit() error {
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	if upload.done {
+		return packageError.Wrap(uplink.ErrUploadDone)
+	}
+	upload.done = true
+
+	upload.project.mu.Lock()
+	defer upload.project.mu.Unlock()
+
+	parent, err := upload.project.upload(upload.bucket, upload.key, upload.uploadID)
+	if err != nil {
+		return err
+	}
+
+	upload.part.Modified = time.Now()
+	part := *upload.part
+	parent.parts[part.PartNumber] = &part
+	parent.data[part.PartNumber] = upload.data.Bytes()
+	return nil
+}
+
+// Abort discards the part.
+func (upload *fakePartUpload) Abort() error {
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	if upload.done {
+		return packageError.Wrap(uplink.ErrUploadDone)
+	}
+	upload.done = true
+	return nil
+}
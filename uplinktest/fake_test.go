@@ -0,0 +1,21 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package uplinktest_test
+
+import (
+	"testing"
+
+	"common/testcontext"
+	"uplink/uplinktest"
+)
+
+func TestProject_Conformance(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	project := uplinktest.NewProject()
+	defer ctx.Check(project.Close)
+
+	uplinktest.RunConformance(t, ctx, project)
+}
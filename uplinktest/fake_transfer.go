@@ -0,0 +1,187 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package uplinktest
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"uplink"
+)
+
+// UploadObject starts an upload to the specific key. The object is only
+// visible after Commit.
+func (project *fakeProject) UploadObject(ctx context.Context, bucket, key string, options *uplink.UploadOptions) (Upload, error) {
+	switch {
+	case bucket == "":
+		return nil, errwrapf("%w (%q)", uplink.ErrBucketNameInvalid, bucket)
+	case key == "":
+		return nil, errwrapf("%w (%q)", uplink.ErrObjectKeyInvalid, key)
+	}
+
+	if options == nil {
+		options = &uplink.UploadOptions{}
+	}
+
+	return &fakeObjectUpload{
+		project: project,
+		bucket:  bucket,
+		options: *options,
+		object: &uplink.Object{
+			Key: key,
+			System: uplink.SystemMetadata{
+				Expires: options.Expires,
+			},
+		},
+	}, nil
+}
+
+// fakeObjectUpload is an upload of a fakeProject object.
+type fakeObjectUpload struct {
+	project *fakeProject
+	bucket  string
+	options uplink.UploadOptions
+
+	mu     sync.Mutex
+	done   bool
+	data   bytes.Buffer
+	object *uplink.Object
+}
+
+// Info returns the last information about the uploaded object.
+func (upload *fakeObjectUpload) Info() *uplink.Object {
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	info := *upload.object
+	info.Custom = upload.object.Custom.Clone()
+	return &info
+}
+
+// Write buffers p until Commit.
+func (upload *fakeObjectUpload) Write(p []byte) (int, error) {
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	if upload.done {
+		return 0, packageError.Wrap(uplink.ErrUploadDone)
+	}
+	if expected := upload.options.ExpectedSize; expected > 0 && int64(upload.data.Len()+len(p)) > expected {
+		return 0, errwrapf("%w: expected %d bytes, got at least %d", uplink.ErrUploadSizeMismatch, expected, int64(upload.data.Len()+len(p)))
+	}
+
+	n, _ := upload.data.Write(p)
+	upload.object.System.ContentLength = int64(upload.data.Len())
+	return n, nil
+}
+
+// SetCustomMetadata sets the custom metadata the object is committed with.
+func (upload *fakeObjectUpload) SetCustomMetadata(ctx context.Context, custom uplink.CustomMetadata) error {
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	if upload.done {
+		return packageError.Wrap(uplink.ErrUploadDone)
+	}
+	upload.object.Custom = custom.Clone()
+	return nil
+}
+
+// Commit makes the object visible, replacing any object at its key.
+func (upload *fakeObjectUpload) Commit() error {
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	if upload.done {
+		return packageError.Wrap(uplink.ErrUploadDone)
+	}
+	upload.done = true
+
+	if expected := upload.options.ExpectedSize; expected > 0 && int64(upload.data.Len()) != expected {
+		return errwrapf("%w: expected %d bytes, got %d", uplink.ErrUploadSizeMismatch, expected, int64(upload.data.Len()))
+	}
+
+	upload.project.mu.Lock()
+	defer upload.project.mu.Unlock()
+
+	b, err := upload.project.bucket(upload.bucket)
+	if err != nil {
+		return err
+	}
+
+	obj := &fakeObject{
+		data:    upload.data.Bytes(),
+		created: time.Now(),
+		expires: upload.options.Expires,
+		custom:  upload.object.Custom.Clone(),
+	}
+	b.objects[upload.object.Key] = obj
+	upload.object = obj.info(upload.object.Key)
+	return nil
+}
+
+// Abort discards the upload.
+func (upload *fakeObjectUpload) Abort() error {
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	if upload.done {
+		return packageError.Wrap(uplink.ErrUploadDone)
+	}
+	upload.done = true
+	return nil
+}
+
+// DownloadObject starts a download from the specific key.
+func (project *fakeProject) DownloadObject(ctx context.Context, bucket, key string, options *uplink.DownloadOptions) (Download, error) {
+	if options == nil {
+		options = &uplink.DownloadOptions{Offset: 0, Length: -1}
+	}
+
+	project.mu.Lock()
+	defer project.mu.Unlock()
+
+	obj, err := project.object(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	size := int64(len(obj.data))
+	start, end := options.Offset, size
+	switch {
+	case start < 0 && options.Length >= 0:
+		return nil, packageError.New("suffix requires length to be negative, got %d", options.Length)
+	case start < 0:
+		start += size
+		if start < 0 {
+			start = 0
+		}
+	case start > size:
+		return nil, packageError.New("offset %d is beyond the object size %d", start, size)
+	case options.Length >= 0 && start+options.Length < size:
+		end = start + options.Length
+	}
+
+	return &fakeDownload{
+		Reader: bytes.NewReader(obj.data[start:end]),
+		object: obj.info(key),
+	}, nil
+}
+
+// fakeDownload is a download of a fakeProject object. It reads the object as
+// it was when the download started.
+type fakeDownload struct {
+	*bytes.Reader
+	object *uplink.Object
+}
+
+// Info returns the downloaded object.
+func (download *fakeDownload) Info() *uplink.Object {
+	return download.object
+}
+
+// Close does nothing.
+func (download *fakeDownload) Close() error { return nil }
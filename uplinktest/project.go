@@ -0,0 +1,147 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package uplinktest
+
+import (
+	"context"
+	"io"
+
+	"uplink"
+)
+
+// Project is the bucket, object and multipart API of uplink.Project.
+//
+// Code that accepts a Project instead of *uplink.Project can be tested with
+// NewProject and run for real with Wrap.
+type Project interface {
+	StatBucket(ctx context.Context, bucket string) (*uplink.Bucket, error)
+	CreateBucket(ctx context.Context, bucket string) (*uplink.Bucket, error)
+	EnsureBucket(ctx context.Context, bucket string) (*uplink.Bucket, error)
+	DeleteBucket(ctx context.Context, bucket string) (*uplink.Bucket, error)
+	DeleteBucketWithObjects(ctx context.Context, bucket string) (*uplink.Bucket, error)
+	ListBuckets(ctx context.Context, options *uplink.ListBucketsOptions) BucketIterator
+
+	StatObject(ctx context.Context, bucket, key string) (*uplink.Object, error)
+	DeleteObject(ctx context.Context, bucket, key string) (*uplink.Object, error)
+	UpdateObjectMetadata(ctx context.Context, bucket, key string, newMetadata uplink.CustomMetadata, options *uplink.UploadObjectMetadataOptions) error
+	ListObjects(ctx context.Context, bucket string, options *uplink.ListObjectsOptions) ObjectIterator
+	UploadObject(ctx context.Context, bucket, key string, options *uplink.UploadOptions) (Upload, error)
+	DownloadObject(ctx context.Context, bucket, key string, options *uplink.DownloadOptions) (Download, error)
+	CopyObject(ctx context.Context, oldBucket, oldKey, newBucket, newKey string, options *uplink.CopyObjectOptions) (*uplink.Object, error)
+	MoveObject(ctx context.Context, oldBucket, oldKey, newBucket, newKey string, options *uplink.MoveObjectOptions) error
+
+	BeginUpload(ctx context.Context, bucket, key string, options *uplink.UploadOptions) (uplink.UploadInfo, error)
+	UploadPart(ctx context.Context, bucket, key, uploadID string, partNumber uint32) (PartUpload, error)
+	CommitUpload(ctx context.Context, bucket, key, uploadID string, options *uplink.CommitUploadOptions) (*uplink.Object, error)
+	AbortUpload(ctx context.Context, bucket, key, uploadID string) error
+	ListUploadParts(ctx context.Context, bucket, key, uploadID string, options *uplink.ListUploadPartsOptions) PartIterator
+	ListUploads(ctx context.Context, bucket string, options *uplink.ListUploadsOptions) UploadIterator
+
+	Close() error
+}
+
+// Upload is an object upload, see uplink.Upload.
+type Upload interface {
+	io.Writer
+	Info() *uplink.Object
+	SetCustomMetadata(ctx context.Context, custom uplink.CustomMetadata) error
+	Commit() error
+	Abort() error
+}
+
+// Download is an object download, see uplink.Download.
+type Download interface {
+	io.ReadCloser
+	Info() *uplink.Object
+}
+
+// PartUpload is a part upload, see uplink.PartUpload.
+type PartUpload interface {
+	io.Writer
+	Info() *uplink.Part
+	SetETag(eTag []byte) error
+	Commit() error
+	Abort() error
+}
+
+// BucketIterator is an iterator over buckets, see uplink.BucketIterator.
+type BucketIterator interface {
+	Next() bool
+	Item() *uplink.Bucket
+	Err() error
+}
+
+// ObjectIterator is an iterator over objects, see uplink.ObjectIterator.
+type ObjectIterator interface {
+	Next() bool
+	Item() *uplink.Object
+	Err() error
+}
+
+// UploadIterator is an iterator over uncommitted uploads, see
+// uplink.UploadIterator.
+type UploadIterator interface {
+	Next() bool
+	Item() *uplink.UploadInfo
+	Err() error
+}
+
+// PartIterator is an iterator over the parts of an upload, see
+// uplink.PartIterator.
+type PartIterator interface {
+	Next() bool
+	Item() *uplink.Part
+	Err() error
+}
+
+// Wrap returns project as a Project.
+func Wrap(project *uplink.Project) Project {
+	return &wrappedProject{project}
+}
+
+// wrappedProject adapts the concrete types returned by uplink.Project to
+// the interfaces of Project.
+type wrappedProject struct {
+	*uplink.Project
+}
+
+func (project *wrappedProject) ListBuckets(ctx context.Context, options *uplink.ListBucketsOptions) BucketIterator {
+	return project.Project.ListBuckets(ctx, options)
+}
+
+func (project *wrappedProject) ListObjects(ctx context.Context, bucket string, options *uplink.ListObjectsOptions) ObjectIterator {
+	return project.Project.ListObjects(ctx, bucket, options)
+}
+
+func (project *wrappedProject) UploadObject(ctx context.Context, bucket, key string, options *uplink.UploadOptions) (Upload, error) {
+	upload, err := project.Project.UploadObject(ctx, bucket, key, options)
+	if err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+func (project *wrappedProject) DownloadObject(ctx context.Context, bucket, key string, options *uplink.DownloadOptions) (Download, error) {
+	download, err := project.Project.DownloadObject(ctx, bucket, key, options)
+	if err != nil {
+		return nil, err
+	}
+	return download, nil
+}
+
+func (project *wrappedProject) UploadPart(ctx context.Context, bucket, key, uploadID string, partNumber uint32) (PartUpload, error) {
+	upload, err := project.Project.UploadPart(ctx, bucket, key, uploadID, partNumber)
+	if err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+func (project *wrappedProject) ListUploadParts(ctx context.Context, bucket, key, uploadID string, options *uplink.ListUploadPartsOptions) PartIterator {
+	return project.Project.ListUploadParts(ctx, bucket, key, uploadID, options)
+}
+
+func (project *wrappedProject) ListUploads(ctx context.Context, bucket string, options *uplink.ListUploadsOptions) UploadIterator {
+	return project.Project.ListUploads(ctx, bucket, options)
+}
@@ -23,10 +23,37 @@ import (
 // ErrUploadDone is returned when either Abort or Commit has already been called.
 var ErrUploadDone = errors.New("upload done")
 
+// ErrUploadSizeMismatch is returned when an upload with an expected size
+// has more or fewer bytes written to it.
+var ErrUploadSizeMismatch = errors.New("upload size does not match expected size")
+
 // UploadOptions contains additional options for uploading.
 type UploadOptions struct {
 	// When Expires is zero, there is no expiration.
 	Expires time.Time
+
+	// MaximumBytesPerSecond limits the rate at which this upload sends bytes
+	// to storage nodes, including the erasure coding expansion. When positive
+	// it replaces Config.MaximumUploadBytesPerSecond for this upload, so it
+	// can also be higher, and the upload isn't counted against that limit.
+	MaximumBytesPerSecond int64
+
+	// BufferSpillThreshold is the number of bytes of a segment kept in memory
+	// before it is moved into a temporary file. When positive it replaces
+	// Config.BufferSpillThreshold for this upload.
+	BufferSpillThreshold int64
+
+	// Background marks the upload as background work. When uploads of a
+	// project compete for Config.MaximumConcurrentPieceUploads, uploads that
+	// are not background are served first.
+	Background bool
+
+	// ExpectedSize, when positive, is the exact number of bytes that will
+	// be written to the upload. Knowing it lets the upload decide between
+	// inline and remote segments and size them without waiting for the
+	// data. Writing more bytes fails the write, and committing after
+	// fewer bytes fails the commit, with ErrUploadSizeMismatch.
+	ExpectedSize int64
 }
 
 // UploadObject starts an upload to the specific key.
@@ -58,6 +85,8 @@ func (project *Project) UploadObject(ctx context.Context, bucket, key string, op
 		options = &UploadOptions{}
 	}
 
+	ctx = project.withUploadLimiter(ctx, options.MaximumBytesPerSecond)
+
 	// N.B. we always call dbCleanup which closes the db because
 	// closing it earlier has the benefit of returning a connection to
 	// the pool, so we try to do that as early as possible.
@@ -102,10 +131,25 @@ func (project *Project) UploadObject(ctx context.Context, bucket, key string, op
 	}
 	upload.streams = streams
 
-	if project.concurrentSegmentUploadConfig == nil {
+	if options.BufferSpillThreshold > 0 {
+		streams.NewBackend = project.newBufferBackend(options.BufferSpillThreshold)
+	}
+	if options.ExpectedSize > 0 {
+		upload.expected = options.ExpectedSize
+		streams.ExpectedSize = options.ExpectedSize
+	}
+
+	// N.B. only the concurrent segment upload codepath buffers segments and
+	// plans them ahead of the data, so it is used for uploads that spill
+	// them to disk or know their size.
+	if project.concurrentSegmentUploadConfig == nil && options.BufferSpillThreshold <= 0 && options.ExpectedSize <= 0 {
 		upload.upload = stream.NewUpload(ctx, mutableStream, streams)
 	} else {
-		sched := scheduler.New(project.concurrentSegmentUploadConfig.SchedulerOptions)
+		prio := scheduler.PriorityInteractive
+		if options.Background {
+			prio = scheduler.PriorityBackground
+		}
+		sched := project.newScheduler(prio)
 		u, err := streams.UploadObject(ctx, mutableStream.BucketName(), mutableStream.Path(), mutableStream, mutableStream.Expires(), sched)
 		if err != nil {
 			return nil, convertKnownErrors(err, bucket, key)
@@ -126,6 +170,7 @@ func (dyn dynamicMetadata) Metadata() ([]byte, error) {
 
 type streamUpload interface {
 	io.Writer
+	io.ReaderFrom
 	Commit() error
 	Abort() error
 	Meta() *streams.Meta
@@ -142,6 +187,9 @@ type Upload struct {
 	object  *Object
 	streams *streams.Store
 
+	// expected is the number of bytes that will be written, if positive.
+	expected int64
+
 	stats operationStats
 	task  func(*error)
 }
@@ -161,6 +209,13 @@ func (upload *Upload) Info() *Object {
 // and any error encountered that caused the write to stop early.
 func (upload *Upload) Write(p []byte) (n int, err error) {
 	track := upload.stats.trackWorking()
+	if err := upload.checkSize(int64(len(p))); err != nil {
+		upload.mu.Lock()
+		upload.stats.flagFailure(err)
+		track()
+		upload.mu.Unlock()
+		return 0, err
+	}
 	n, err = upload.upload.Write(p)
 	upload.mu.Lock()
 	upload.stats.bytes += int64(n)
@@ -170,6 +225,37 @@ func (upload *Upload) Write(p []byte) (n int, err error) {
 	return n, convertKnownErrors(err, upload.bucket, upload.object.Key)
 }
 
+// ReadFrom uploads data read from r to the object's data stream until EOF.
+// It is used by io.Copy, and hands r to the upload to read from, instead of
+// copying the data through a buffer of its own.
+func (upload *Upload) ReadFrom(r io.Reader) (n int64, err error) {
+	track := upload.stats.trackWorking()
+	if upload.expected > 0 {
+		// hand over no more than the expected size, so that extra data is
+		// never uploaded, and then check whether r has more.
+		upload.mu.Lock()
+		remaining := upload.expected - upload.stats.bytes
+		upload.mu.Unlock()
+
+		limited := &io.LimitedReader{R: r, N: remaining}
+		n, err = upload.upload.ReadFrom(limited)
+		if err == nil && limited.N == 0 {
+			var probe [1]byte
+			if m, _ := io.ReadFull(r, probe[:]); m > 0 {
+				err = errwrapf("%w: more than %d bytes read", ErrUploadSizeMismatch, upload.expected)
+			}
+		}
+	} else {
+		n, err = upload.upload.ReadFrom(r)
+	}
+	upload.mu.Lock()
+	upload.stats.bytes += n
+	upload.stats.flagFailure(err)
+	track()
+	upload.mu.Unlock()
+	return n, convertKnownErrors(err, upload.bucket, upload.object.Key)
+}
+
 // Commit commits data to the store.
 //
 // Returns ErrUploadDone when either Abort or Commit has already been called.
@@ -186,6 +272,21 @@ func (upload *Upload) Commit() error {
 		return errwrapf("%w: already committed", ErrUploadDone)
 	}
 
+	if upload.expected > 0 && upload.stats.bytes != upload.expected {
+		// don't commit an object that is not what the caller promised.
+		upload.aborted = true
+		upload.cancel()
+
+		err := errwrapf("%w: %d bytes written, expected %d", ErrUploadSizeMismatch, upload.stats.bytes, upload.expected)
+		_ = upload.upload.Abort()
+		err = errs.Combine(err, upload.streams.Close())
+		upload.stats.flagFailure(err)
+		track()
+		upload.emitEvent(true)
+
+		return err
+	}
+
 	upload.closed = true
 
 	err := errs.Combine(
@@ -199,6 +300,23 @@ func (upload *Upload) Commit() error {
 	return convertKnownErrors(err, upload.bucket, upload.object.Key)
 }
 
+// checkSize returns an error if writing n more bytes would go past the
+// expected size of the upload.
+func (upload *Upload) checkSize(n int64) error {
+	if upload.expected <= 0 {
+		return nil
+	}
+
+	upload.mu.Lock()
+	written := upload.stats.bytes
+	upload.mu.Unlock()
+
+	if written+n > upload.expected {
+		return errwrapf("%w: writing %d bytes after %d exceeds %d bytes", ErrUploadSizeMismatch, n, written, upload.expected)
+	}
+	return nil
+}
+
 // Abort aborts the upload.
 //
 // Returns ErrUploadDone when either Abort or Commit has already been called.
@@ -0,0 +1,169 @@
+// Copyright (C) 2023 Storx Labs, Inc.
+// See LICENSE for copying information.
+
+package uplink
+
+import (
+	"context"
+
+	"uplink/private/eestream"
+	"uplink/private/metaclient"
+)
+
+// ObjectVerification is the result of verifying every piece of an object.
+// It does not contain any of the object's data.
+type ObjectVerification struct {
+	Object   *Object
+	Segments []SegmentVerification
+}
+
+// SegmentVerification is the result of verifying every piece of a segment.
+type SegmentVerification struct {
+	PartNumber uint32
+	Index      uint32
+
+	// Inline is true when the segment is stored with the object metadata
+	// on the satellite. Inline segments have no pieces to verify.
+	Inline bool
+
+	// RedundancyScheme is the redundancy scheme of a remote segment.
+	RedundancyScheme RedundancyScheme
+
+	// Pieces are the results for the pieces the satellite reports.
+	Pieces []PieceVerification
+
+	// Stripes is the number of stripes in the segment.
+	Stripes int
+	// UncheckedStripes is the number of stripes that could be reconstructed,
+	// but had no spare shares to detect corruption with.
+	UncheckedStripes int
+	// UncorrectableStripes is the number of stripes in which corruption was
+	// detected, but that had too few spare shares to tell which pieces are
+	// corrupt, so they may not be reconstructed correctly.
+	UncorrectableStripes int
+	// UnrecoverableStripes is the number of stripes that could not be
+	// reconstructed because of missing pieces.
+	UnrecoverableStripes int
+}
+
+// PieceVerification is the result of verifying a single piece.
+type PieceVerification struct {
+	Number      int
+	NodeID      string
+	NodeAddress string
+
+	// Downloaded is the number of bytes of the piece that were downloaded.
+	Downloaded int64
+	// DownloadError is set when the piece could not be downloaded completely.
+	DownloadError error
+
+	// HashSent is true when the node sent the signed hash of the piece, and
+	// it was checked. Nodes only send it with repair order limits, which the
+	// satellite does not hand out to uplinks, so it is false for nodes that
+	// follow the protocol.
+	HashSent bool
+	// HashError is set when the hash did not match the piece or it was not
+	// signed by the uplink that uploaded the piece.
+	HashError error
+
+	// CorruptStripes is the number of stripes in which the piece disagreed
+	// with the other pieces.
+	CorruptStripes int
+}
+
+// Intact returns whether the piece was downloaded and found no problems.
+func (piece PieceVerification) Intact() bool {
+	return piece.DownloadError == nil && piece.HashError == nil && piece.CorruptStripes == 0
+}
+
+// Intact returns whether every stripe of the segment can be reconstructed
+// correctly.
+func (segment SegmentVerification) Intact() bool {
+	return segment.UnrecoverableStripes == 0 && segment.UncorrectableStripes == 0
+}
+
+// Intact returns whether every segment of the object can be reconstructed.
+func (verification ObjectVerification) Intact() bool {
+	for _, segment := range verification.Segments {
+		if !segment.Intact() {
+			return false
+		}
+	}
+	return true
+}
+
+// VerifyObject downloads every available piece of every segment of the
+// object at the specific key and runs Reed-Solomon error detection across
+// all erasure shares, stripe by stripe. The pieces are discarded after they
+// are checked and nothing is decrypted.
+//
+// The pieces are only checked against each other. Nodes don't send the
+// signed piece hashes for the downloads of uplinks, so PieceVerification.HashSent
+// is false for nodes that follow the protocol, and a stripe with exactly the
+// required number of shares can't be checked at all.
+//
+// It downloads the object once for every piece that is stored, so it costs
+// much more bandwidth than downloading the object.
+func (project *Project) VerifyObject(ctx context.Context, bucket, key string) (_ *ObjectVerification, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	verification := &ObjectVerification{}
+	verification.Object, err = project.forEachSegment(ctx, bucket, key, func(ctx context.Context, response metaclient.DownloadSegmentWithRSResponse) error {
+		segment, err := project.verifySegment(ctx, response)
+		if err != nil {
+			return err
+		}
+		verification.Segments = append(verification.Segments, segment)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return verification, nil
+}
+
+func (project *Project) verifySegment(ctx context.Context, response metaclient.DownloadSegmentWithRSResponse) (_ SegmentVerification, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	info := response.Info
+	segment := SegmentVerification{
+		PartNumber: uint32(info.Position.PartNumber),
+		Index:      uint32(info.Position.Index),
+	}
+
+	if isInlineSegment(response) {
+		segment.Inline = true
+		return segment, nil
+	}
+
+	redundancy, err := eestream.NewRedundancyStrategyFromStorx(info.RedundancyScheme)
+	if err != nil {
+		return SegmentVerification{}, err
+	}
+	segment.RedundancyScheme = convertRedundancyStrategy(redundancy)
+
+	result, err := project.ec.Verify(ctx, response.Limits, info.PiecePrivateKey, redundancy, info.EncryptedSize)
+	if err != nil {
+		return SegmentVerification{}, err
+	}
+
+	segment.Stripes = result.Stripes
+	segment.UncheckedStripes = result.UncheckedStripes
+	segment.UncorrectableStripes = result.UncorrectableStripes
+	segment.UnrecoverableStripes = result.UnrecoverableStripes
+	for _, piece := range result.Pieces {
+		segment.Pieces = append(segment.Pieces, PieceVerification{
+			Number:         piece.Num,
+			NodeID:         piece.NodeID.String(),
+			NodeAddress:    piece.Address,
+			Downloaded:     piece.Downloaded,
+			DownloadError:  piece.Err,
+			HashSent:       piece.HashSent,
+			HashError:      piece.HashErr,
+			CorruptStripes: piece.CorruptStripes,
+		})
+	}
+
+	return segment, nil
+}